@@ -0,0 +1,122 @@
+package mdbx
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// arenaSlabSize is the size of the slabs an Arena copies values into.
+// Values larger than a quarter of it get an allocation of their own.
+const arenaSlabSize = 64 << 10
+
+var arenaSlabs = sync.Pool{
+	New: func() interface{} {
+		slab := make([]byte, arenaSlabSize)
+		return &slab
+	},
+}
+
+// Arena copies values into large pooled slabs instead of allocating a slice
+// per value. The copies are valid until Release, which returns the slabs to
+// the pool for the next Arena. The Arena of a transaction, see Tx.Arena, is
+// released when the transaction ends. The zero value is an empty Arena owned
+// by the caller, which keeps its copies across transactions, so a handler
+// can retain the values it reads until it is done with them:
+//
+//	var arena Arena
+//	defer arena.Release()
+//	err := store.View(func(tx *Tx) error {
+//		...
+//		value = arena.Copy(&data)
+//		...
+//	})
+//
+// An Arena is not safe for concurrent use.
+type Arena struct {
+	slab  []byte
+	slabs []*[]byte
+}
+
+// Alloc returns n bytes of arena memory. Their content is undefined.
+func (a *Arena) Alloc(n int) []byte {
+	if n == 0 {
+		return []byte{}
+	}
+	if n > arenaSlabSize/4 {
+		return make([]byte, n)
+	}
+	if len(a.slab) < n {
+		slab := arenaSlabs.Get().(*[]byte)
+		a.slabs = append(a.slabs, slab)
+		a.slab = *slab
+	}
+	b := a.slab[:n:n]
+	a.slab = a.slab[n:]
+	return b
+}
+
+// Copy returns a copy of v in arena memory.
+func (a *Arena) Copy(v *Val) []byte {
+	b := a.Alloc(int(v.Len))
+	if v.Len > 0 {
+		copy(b, unsafe.Slice(v.Base, int(v.Len)))
+	}
+	return b
+}
+
+// Release returns the slabs of the arena to the pool. Slices returned by the
+// arena must not be used afterwards.
+func (a *Arena) Release() {
+	for i, slab := range a.slabs {
+		arenaSlabs.Put(slab)
+		a.slabs[i] = nil
+	}
+	a.slabs = a.slabs[:0]
+	a.slab = nil
+}
+
+// Arena returns the arena of the transaction, which is released when the
+// transaction is committed, aborted or reset. Values copied into it stay
+// valid when the database is modified or cursors move, until the end of the
+// transaction. Use an Arena of your own or GetInto to retain values beyond
+// it.
+func (tx *Tx) Arena() *Arena {
+	if tx.arena == nil {
+		tx.arena = &Arena{}
+	}
+	return tx.arena
+}
+
+// GetInto copies the value of key in dbi into *data, reusing its capacity,
+// so that a buffer retained across transactions is only grown when needed.
+func (tx *Tx) GetInto(dbi DBI, key []byte, data *[]byte) error {
+	k, v := Bytes(&key), Val{}
+	if err := tx.Get(dbi, &k, &v); err != nil {
+		return err
+	}
+	*data = v.Copy(*data)
+	return nil
+}
+
+// GetInto is Get copying the key and value of the record the cursor moves
+// to into *key and *data, reusing their capacity. *key and *data are the
+// input of operations like CursorSet and CursorGetBoth.
+func (cur *Cursor) GetInto(key, data *[]byte, op CursorOp) error {
+	k, v := Bytes(key), Bytes(data)
+	if err := cur.Get(&k, &v, op); err != nil {
+		return err
+	}
+	*key = k.Copy(*key)
+	*data = v.Copy(*data)
+	return nil
+}
+
+// ended runs when the transaction was committed, aborted or reset.
+func (tx *Tx) ended() {
+	if tx.arena != nil {
+		tx.arena.Release()
+	}
+	if debugViews {
+		debugEnd(unsafe.Pointer(tx.txn))
+	}
+}
@@ -0,0 +1,150 @@
+package mdbx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestArena(t *testing.T) {
+	var a Arena
+	empty := Val{}
+	if b := a.Copy(&empty); b == nil || len(b) != 0 {
+		t.Fatalf("empty copy of a fresh arena: %v", b)
+	}
+	small := strings.Repeat("x", 1000)
+	var copies [][]byte
+	for i := 0; i < 200; i++ {
+		v := StringConst(small)
+		copies = append(copies, a.Copy(&v))
+	}
+	if len(a.slabs) < 2 {
+		t.Fatalf("expected several slabs, got %d", len(a.slabs))
+	}
+	for _, b := range copies {
+		if string(b) != small || cap(b) != len(b) {
+			t.Fatal("copy changed or has spare capacity")
+		}
+	}
+	large := strings.Repeat("y", arenaSlabSize)
+	v := StringConst(large)
+	if b := a.Copy(&v); string(b) != large {
+		t.Fatal("large copy changed")
+	}
+	if b := a.Copy(&empty); b == nil || len(b) != 0 {
+		t.Fatalf("empty copy: %v", b)
+	}
+	a.Release()
+	if len(a.slabs) != 0 || a.slab != nil {
+		t.Fatal("slabs kept after Release")
+	}
+}
+
+func TestTx_Arena(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "arena", DBDefaults)
+	var arena *Arena
+	if err := store.Update(func(tx *Tx) error {
+		key, data := StringConst("key"), StringConst("value")
+		if err := tx.Put(dbi, &key, &data, 0); err != nil {
+			return err
+		}
+		data = Val{}
+		if err := tx.Get(dbi, &key, &data); err != nil {
+			return err
+		}
+		arena = tx.Arena()
+		value := arena.Copy(&data)
+		data = StringConst("VALUE")
+		if err := tx.Put(dbi, &key, &data, 0); err != nil {
+			return err
+		}
+		if string(value) != "value" {
+			t.Fatalf("got %q, want value", value)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(arena.slabs) != 0 {
+		t.Fatal("arena not released at the end of the transaction")
+	}
+
+	// An arena of the caller keeps its copies after the transaction.
+	var own Arena
+	defer own.Release()
+	var value []byte
+	if err := store.View(func(tx *Tx) error {
+		key, data := StringConst("key"), Val{}
+		if err := tx.Get(dbi, &key, &data); err != nil {
+			return err
+		}
+		value = own.Copy(&data)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "VALUE" {
+		t.Fatalf("got %q, want VALUE", value)
+	}
+}
+
+func TestTx_GetInto(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "getinto", DBDefaults)
+	if err := store.Update(func(tx *Tx) error {
+		for _, k := range []string{"a", "b", "c"} {
+			key, data := StringConst(k), StringConst(strings.Repeat(k, 10))
+			if err := tx.Put(dbi, &key, &data, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 0, 64)
+	if err := store.View(func(tx *Tx) error {
+		if err := tx.GetInto(dbi, []byte("b"), &buf); err != nil {
+			return err
+		}
+		if err := tx.GetInto(dbi, []byte("z"), &buf); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "bbbbbbbbbb" || cap(buf) != 64 {
+		t.Fatalf("got %q with capacity %d", buf, cap(buf))
+	}
+
+	var keys, values []string
+	if err := store.View(func(tx *Tx) error {
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		key, data := []byte("b"), buf
+		if err := cursor.GetInto(&key, &data, CursorSet); err != nil {
+			return err
+		}
+		if !bytes.Equal(data, []byte("bbbbbbbbbb")) || &data[0] != &buf[0] {
+			t.Fatalf("CursorSet: got %q", data)
+		}
+		for err = cursor.GetInto(&key, &data, CursorFirst); err == nil; err = cursor.GetInto(&key, &data, CursorNext) {
+			keys, values = append(keys, string(key)), append(values, string(data))
+		}
+		if err != ErrNotFound {
+			return err
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(keys, " ") + " " + strings.Join(values, " "); got != "a b c aaaaaaaaaa bbbbbbbbbb cccccccccc" {
+		t.Fatalf("got %q", got)
+	}
+}
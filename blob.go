@@ -0,0 +1,421 @@
+package mdbx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var (
+	ErrBlobOffset  = errors.New("mdbx: blob offset out of range")
+	ErrCorruptBlob = errors.New("mdbx: corrupt blob metadata")
+)
+
+// blobChunkOverhead is subtracted from half a page for the default chunk size,
+// covering the page header, node header and key, so that two chunks fit on a
+// leaf page and none spills to overflow pages.
+const blobChunkOverhead = 64
+
+// Blobs stores large objects in a database, split into chunks small enough
+// to stay on leaf pages. Values larger than about half a page go to overflow
+// pages, which are allocated, copied and spilled as a whole.
+//
+// Each blob is identified by a uint64 ID. Its metadata, the content length
+// and chunk size, is stored under the 8 byte big-endian ID, and chunk n under
+// the ID followed by n as a 4 byte big-endian number, so the metadata of a
+// blob sorts right before its chunks. Chunks that were never written are
+// holes and read as zeros.
+//
+// The database should not be used for anything else.
+type Blobs struct {
+	DBI DBI
+	// ChunkSize is the chunk size of blobs created from now on. Existing
+	// blobs keep the chunk size they were created with.
+	ChunkSize int
+}
+
+// OpenBlobs opens the named database for blobs, creating it if necessary. A
+// chunkSize of zero selects the largest size that stays on leaf pages.
+func OpenBlobs(tx *Tx, name string, chunkSize int) (Blobs, error) {
+	dbi, err := tx.OpenDBI(name, DBCreate)
+	if err != nil {
+		return Blobs{}, err
+	}
+	if chunkSize <= 0 {
+		var stat Stats
+		if err = tx.DBIStat(dbi, &stat); err != nil {
+			return Blobs{}, err
+		}
+		chunkSize = int(stat.PageSize)/2 - blobChunkOverhead
+	}
+	if int64(chunkSize) > math.MaxUint32 {
+		return Blobs{}, opError("blob_open", ErrBadValSize)
+	}
+	return Blobs{DBI: dbi, ChunkSize: chunkSize}, nil
+}
+
+// Create creates the empty blob id and returns it. It returns ErrKeyExist if
+// the blob exists already. Chunks left over from a deleted blob with the same
+// ID are removed.
+func (b Blobs) Create(tx *Tx, id uint64) (*Blob, error) {
+	if b.ChunkSize <= 0 || int64(b.ChunkSize) > math.MaxUint32 {
+		return nil, opError("blob_create", ErrBadValSize)
+	}
+	blob := &Blob{tx: tx, dbi: b.DBI, id: id, chunkSize: int64(b.ChunkSize)}
+	key, meta := blobMetaKey(id), blob.meta()
+	k, v := sliceVal(key[:]), sliceVal(meta[:])
+	if err := tx.Put(b.DBI, &k, &v, PutNoOverwrite); err != nil {
+		return nil, err
+	}
+	keys, err := b.orphans(tx, id, 0)
+	if err != nil {
+		return nil, err
+	}
+	return blob, b.deleteChunks(tx, keys)
+}
+
+// Open returns the blob id for access in tx, or ErrNotFound.
+func (b Blobs) Open(tx *Tx, id uint64) (*Blob, error) {
+	key := blobMetaKey(id)
+	k, v := sliceVal(key[:]), Val{}
+	if err := tx.Get(b.DBI, &k, &v); err != nil {
+		return nil, err
+	}
+	meta := v.UnsafeBytes()
+	if len(meta) != 12 || binary.BigEndian.Uint32(meta[8:]) == 0 {
+		return nil, fmt.Errorf("%w: blob %d", ErrCorruptBlob, id)
+	}
+	return &Blob{
+		tx:        tx,
+		dbi:       b.DBI,
+		id:        id,
+		size:      int64(binary.BigEndian.Uint64(meta)),
+		chunkSize: int64(binary.BigEndian.Uint32(meta[8:])),
+	}, nil
+}
+
+// Delete deletes the blob id, or returns ErrNotFound. Only the metadata is
+// removed, so that deleting a large blob does not dirty many pages at once.
+// Its chunks become orphans and are removed by GC.
+func (b Blobs) Delete(tx *Tx, id uint64) error {
+	key := blobMetaKey(id)
+	k := sliceVal(key[:])
+	return tx.Delete(b.DBI, &k, nil)
+}
+
+// GC removes up to limit orphaned chunks, chunks of deleted blobs or past the
+// end of a blob, and returns how many it removed. A limit of zero or less
+// removes all of them. Call it repeatedly in separate transactions until it
+// returns less than limit to bound the size of each transaction.
+//
+// GC skips over the chunks of existing blobs, its cost is proportional to
+// the number of blobs and orphans.
+func (b Blobs) GC(tx *Tx, limit int) (int, error) {
+	keys, err := b.orphans(tx, math.MaxUint64, limit)
+	if err != nil {
+		return 0, err
+	}
+	return len(keys), b.deleteChunks(tx, keys)
+}
+
+// orphans returns the keys of up to limit orphaned chunks. If only is not
+// math.MaxUint64, only chunks of blob only are returned, whether orphaned or
+// not.
+func (b Blobs) orphans(tx *Tx, only uint64, limit int) ([][]byte, error) {
+	cursor, err := tx.OpenCursor(b.DBI)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	var keys [][]byte
+	var seek [12]byte
+	op, key, data := CursorFirst, Val{}, Val{}
+	if only != math.MaxUint64 {
+		seek = blobChunkKey(only, 0)
+		op, key = CursorSetRange, sliceVal(seek[:])
+	}
+	for limit <= 0 || len(keys) < limit {
+		if err = cursor.Get(&key, &data, op); err != nil {
+			if err == ErrNotFound {
+				err = nil
+			}
+			return keys, err
+		}
+		op = CursorNext
+		k := key.UnsafeBytes()
+		switch {
+		case len(k) == 12 && (only == math.MaxUint64 || binary.BigEndian.Uint64(k) == only):
+			keys = append(keys, key.Bytes())
+		case only != math.MaxUint64:
+			return keys, nil
+		case len(k) == 8:
+			// Skip the chunks of an existing blob, landing on the first one
+			// past its end or on the next blob.
+			id, meta := binary.BigEndian.Uint64(k), data.UnsafeBytes()
+			if len(meta) != 12 || binary.BigEndian.Uint32(meta[8:]) == 0 {
+				return nil, fmt.Errorf("%w: blob %d", ErrCorruptBlob, id)
+			}
+			size, chunkSize := binary.BigEndian.Uint64(meta), uint64(binary.BigEndian.Uint32(meta[8:]))
+			if chunks := (size + chunkSize - 1) / chunkSize; chunks <= math.MaxUint32 {
+				seek = blobChunkKey(id, uint32(chunks))
+				op, key = CursorSetRange, sliceVal(seek[:])
+			} else if id == math.MaxUint64 {
+				return keys, nil
+			} else {
+				next := blobMetaKey(id + 1)
+				op, key = CursorSetRange, sliceVal(next[:])
+			}
+		default:
+			return nil, fmt.Errorf("%w: key %x", ErrCorruptBlob, k)
+		}
+	}
+	return keys, nil
+}
+
+func (b Blobs) deleteChunks(tx *Tx, keys [][]byte) error {
+	for _, key := range keys {
+		k := sliceVal(key)
+		if err := tx.Delete(b.DBI, &k, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func blobMetaKey(id uint64) (key [8]byte) {
+	binary.BigEndian.PutUint64(key[:], id)
+	return key
+}
+
+func blobChunkKey(id uint64, n uint32) (key [12]byte) {
+	binary.BigEndian.PutUint64(key[:], id)
+	binary.BigEndian.PutUint32(key[8:], n)
+	return key
+}
+
+// Blob gives access to a blob inside a transaction. It implements
+// io.ReadSeeker, io.ReaderAt, io.Writer and io.WriterAt. Read and Write
+// start at the offset set by Seek, ReadAt and WriteAt ignore it. Writes
+// past the end extend the blob, leaving holes that read as zeros.
+//
+// A Blob must not be used after the transaction ends, or concurrently with
+// another Blob of the same ID.
+type Blob struct {
+	tx        *Tx
+	dbi       DBI
+	id        uint64
+	size      int64
+	chunkSize int64
+	offset    int64
+	buf       []byte
+}
+
+// ID returns the ID of the blob.
+func (b *Blob) ID() uint64 {
+	return b.id
+}
+
+// Size returns the content length of the blob.
+func (b *Blob) Size() int64 {
+	return b.size
+}
+
+// Read reads from the blob at the current offset.
+func (b *Blob) Read(p []byte) (int, error) {
+	n, err := b.ReadAt(p, b.offset)
+	b.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from the blob at off. It returns io.EOF if the
+// blob ends before.
+func (b *Blob) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrBlobOffset, off)
+	}
+	n := 0
+	for n < len(p) && off < b.size {
+		chunk, within := off/b.chunkSize, off%b.chunkSize
+		data, err := b.chunk(chunk)
+		if err != nil {
+			return n, err
+		}
+		m := b.chunkSize - within
+		if rest := b.size - off; rest < m {
+			m = rest
+		}
+		if rest := int64(len(p) - n); rest < m {
+			m = rest
+		}
+		dst := p[n : n+int(m)]
+		copied := 0
+		if within < int64(len(data)) {
+			copied = copy(dst, data[within:])
+		}
+		for i := copied; i < len(dst); i++ {
+			dst[i] = 0
+		}
+		n += int(m)
+		off += m
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek sets the offset for the next Read or Write.
+func (b *Blob) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	default:
+		return 0, opError("blob_seek", ErrEINVAL)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrBlobOffset, offset)
+	}
+	b.offset = offset
+	return offset, nil
+}
+
+// Write writes p to the blob at the current offset.
+func (b *Blob) Write(p []byte) (int, error) {
+	n, err := b.WriteAt(p, b.offset)
+	b.offset += int64(n)
+	return n, err
+}
+
+// WriteAt writes p to the blob at off, extending it if necessary.
+func (b *Blob) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || !b.validEnd(off+int64(len(p))) {
+		return 0, fmt.Errorf("%w: %d", ErrBlobOffset, off)
+	}
+	n := 0
+	for n < len(p) {
+		chunk, within := off/b.chunkSize, off%b.chunkSize
+		m := b.chunkSize - within
+		if rest := int64(len(p) - n); rest < m {
+			m = rest
+		}
+		src := p[n : n+int(m)]
+		key := blobChunkKey(b.id, uint32(chunk))
+		k := sliceVal(key[:])
+		if within == 0 && m == b.chunkSize {
+			v := sliceVal(src)
+			if err := b.tx.Put(b.dbi, &k, &v, 0); err != nil {
+				return n, err
+			}
+		} else {
+			// Merge with the existing chunk through b.buf, the chunk itself
+			// may be moved by the Put.
+			data, err := b.chunk(chunk)
+			if err != nil {
+				return n, err
+			}
+			length := within + m
+			if int64(len(data)) > length {
+				length = int64(len(data))
+			}
+			if int64(cap(b.buf)) < length {
+				b.buf = make([]byte, length, b.chunkSize)
+			}
+			buf := b.buf[:length]
+			copied := copy(buf, data)
+			for i := int64(copied); i < within; i++ {
+				buf[i] = 0
+			}
+			copy(buf[within:], src)
+			v := sliceVal(buf)
+			if err = b.tx.Put(b.dbi, &k, &v, 0); err != nil {
+				return n, err
+			}
+		}
+		n += int(m)
+		off += m
+	}
+	if off > b.size {
+		b.size = off
+		return n, b.writeMeta()
+	}
+	return n, nil
+}
+
+// Truncate changes the content length of the blob to size. Chunks past the
+// new end are removed, growing the blob leaves a hole.
+func (b *Blob) Truncate(size int64) error {
+	if !b.validEnd(size) {
+		return fmt.Errorf("%w: %d", ErrBlobOffset, size)
+	}
+	if size < b.size {
+		blobs := Blobs{DBI: b.dbi}
+		keys, err := blobs.orphans(b.tx, b.id, 0)
+		if err != nil {
+			return err
+		}
+		first := (size + b.chunkSize - 1) / b.chunkSize
+		for len(keys) > 0 && int64(binary.BigEndian.Uint32(keys[0][8:])) < first {
+			keys = keys[1:]
+		}
+		if err = blobs.deleteChunks(b.tx, keys); err != nil {
+			return err
+		}
+		if within := size % b.chunkSize; within != 0 {
+			data, err := b.chunk(size / b.chunkSize)
+			if err != nil {
+				return err
+			}
+			if int64(len(data)) > within {
+				key := blobChunkKey(b.id, uint32(size/b.chunkSize))
+				b.buf = append(b.buf[:0], data[:within]...)
+				k, v := sliceVal(key[:]), sliceVal(b.buf)
+				if err = b.tx.Put(b.dbi, &k, &v, 0); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	b.size = size
+	return b.writeMeta()
+}
+
+// validEnd reports whether a blob can extend to end with chunk numbers that
+// fit the keys. A negative end is an overflowed offset.
+func (b *Blob) validEnd(end int64) bool {
+	return end >= 0 && end/b.chunkSize <= math.MaxUint32
+}
+
+// chunk returns chunk n, or nil for a hole. It is valid until the next
+// modification.
+func (b *Blob) chunk(n int64) ([]byte, error) {
+	key := blobChunkKey(b.id, uint32(n))
+	k, v := sliceVal(key[:]), Val{}
+	if err := b.tx.Get(b.dbi, &k, &v); err != nil {
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return v.UnsafeBytes(), nil
+}
+
+func (b *Blob) meta() (meta [12]byte) {
+	binary.BigEndian.PutUint64(meta[:], uint64(b.size))
+	binary.BigEndian.PutUint32(meta[8:], uint32(b.chunkSize))
+	return meta
+}
+
+func (b *Blob) writeMeta() error {
+	key, meta := blobMetaKey(b.id), b.meta()
+	k, v := sliceVal(key[:]), sliceVal(meta[:])
+	return b.tx.Put(b.dbi, &k, &v, 0)
+}
@@ -0,0 +1,153 @@
+package mdbx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestBlobs(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	content := make([]byte, 100_000)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	var blobs Blobs
+	if err := store.Update(func(tx *Tx) error {
+		var err error
+		if blobs, err = OpenBlobs(tx, "blobs", 0); err != nil {
+			return err
+		}
+		blob, err := blobs.Create(tx, 1)
+		if err != nil {
+			return err
+		}
+		if n, err := io.Copy(blob, bytes.NewReader(content)); err != nil || n != int64(len(content)) {
+			t.Fatalf("io.Copy: %d, %v", n, err)
+		}
+		if _, err = blobs.Create(tx, 1); err != ErrKeyExist {
+			t.Fatalf("got %v, want ErrKeyExist", err)
+		}
+
+		// A small chunk size, with a hole between two writes.
+		blobs.ChunkSize = 10
+		if blob, err = blobs.Create(tx, 2); err != nil {
+			return err
+		}
+		if _, err = blob.WriteAt([]byte("abc"), 4); err != nil {
+			return err
+		}
+		_, err = blob.WriteAt([]byte("0123456789xyz"), 25)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		blob, err := blobs.Open(tx, 1)
+		if err != nil {
+			return err
+		}
+		if blob.Size() != int64(len(content)) {
+			t.Fatalf("size %d", blob.Size())
+		}
+		got, err := io.ReadAll(blob)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatal("content differs")
+		}
+		if _, err = blob.Seek(-1000, io.SeekEnd); err != nil {
+			return err
+		}
+		buf := make([]byte, 2000)
+		if n, err := blob.Read(buf); n != 1000 || err != nil || !bytes.Equal(buf[:n], content[len(content)-1000:]) {
+			t.Fatalf("read at end: %d, %v", n, err)
+		}
+		if _, err = blob.Read(buf); err != io.EOF {
+			t.Fatalf("got %v, want io.EOF", err)
+		}
+		if _, err = blob.Seek(-1, io.SeekStart); !errors.Is(err, ErrBlobOffset) {
+			t.Fatalf("got %v, want ErrBlobOffset", err)
+		}
+
+		var stat Stats
+		if err = tx.DBIStat(blobs.DBI, &stat); err != nil {
+			return err
+		}
+		if stat.OverflowPages != 0 {
+			t.Fatalf("%d overflow pages", stat.OverflowPages)
+		}
+
+		if blob, err = blobs.Open(tx, 2); err != nil {
+			return err
+		}
+		if got, err = io.ReadAll(blob); err != nil {
+			return err
+		}
+		if want := "\x00\x00\x00\x00abc" + string(make([]byte, 18)) + "0123456789xyz"; string(got) != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+		if _, err = blobs.Open(tx, 3); err != ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Update(func(tx *Tx) error {
+		blob, err := blobs.Open(tx, 2)
+		if err != nil {
+			return err
+		}
+		if err = blob.Truncate(6); err != nil {
+			return err
+		}
+		if err = blob.Truncate(12); err != nil {
+			return err
+		}
+		got := make([]byte, 12)
+		if _, err = blob.ReadAt(got, 0); err != nil {
+			return err
+		}
+		if string(got) != "\x00\x00\x00\x00ab\x00\x00\x00\x00\x00\x00" {
+			t.Fatalf("got %q after truncation", got)
+		}
+		return blobs.Delete(tx, 1)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Blob 1 was deleted, its chunks are collected in batches.
+	var collected int
+	for {
+		var n int
+		if err := store.Update(func(tx *Tx) error {
+			var err error
+			n, err = blobs.GC(tx, 16)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		collected += n
+		if n < 16 {
+			break
+		}
+	}
+	if err := store.View(func(tx *Tx) error {
+		var stat Stats
+		if err := tx.DBIStat(blobs.DBI, &stat); err != nil {
+			return err
+		}
+		// The metadata and chunk 0 of blob 2 remain.
+		if collected == 0 || stat.Entries != 2 {
+			t.Fatalf("collected %d, %d entries left", collected, stat.Entries)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
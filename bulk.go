@@ -0,0 +1,303 @@
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrBulkOrder is returned by BulkLoader.Load for records that are not in
+// database order.
+var ErrBulkOrder = errors.New("mdbx: bulk load records out of order")
+
+// BulkSource returns the records of a bulk load in database order and io.EOF
+// after the last one. The returned slices are only used until the next call.
+type BulkSource func() (key, value []byte, err error)
+
+// BulkStats reports the progress of a bulk load.
+type BulkStats struct {
+	Records uint64        // Records written
+	Bytes   uint64        // Bytes of keys and values written
+	Txns    int           // Transactions committed
+	Elapsed time.Duration // Time since the load started
+}
+
+// RecordsPerSecond returns the average number of records written per second.
+func (s BulkStats) RecordsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Records) / s.Elapsed.Seconds()
+}
+
+// BytesPerSecond returns the average number of bytes written per second.
+func (s BulkStats) BytesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// BulkLoader loads records sorted in database order into a database much
+// faster than Tx.Put, writing them with PutAppend and PutAppendDup so that
+// pages are filled completely without searching the tree. Values of a key in
+// a DBDupFixed database are written together with PutMultiple.
+//
+// The load is split into transactions, each committed once TxnBytes were
+// written, so that a transaction does not exceed OptTxnDpLimit. A failed load
+// leaves the transactions committed before the failure in place.
+//
+// Appending requires every record to sort after the last record of the
+// database, Load returns ErrBulkOrder for records out of order and
+// ErrEKeyMismatch for records before the existing ones. Records that are not
+// sorted are loaded with Unsorted, which sorts them first with an external
+// merge sort in the order of the database, following DBIntegerKey,
+// DBReverseKey and the corresponding flags of the values. Duplicate records
+// still fail with ErrBulkOrder.
+type BulkLoader struct {
+	Store *Store
+	DBI   DBI
+
+	// TxnBytes bounds the bytes of keys and values written per transaction.
+	// Defaults to half the size of OptTxnDpLimit dirty pages.
+	TxnBytes int
+
+	// Progress is called after each commit, if set.
+	Progress func(BulkStats)
+
+	// Unsorted sorts the records before loading them. Sorted runs of up to
+	// SortBytes, 64MB by default, are spilled to temporary files in TempDir,
+	// os.TempDir by default, and merged.
+	Unsorted  bool
+	SortBytes int
+	TempDir   string
+}
+
+// bulkNodeOverhead approximates the bytes of a leaf node besides the key and
+// value.
+const bulkNodeOverhead = 16
+
+// bulkRecord locates a record in the batch of a bulk load. sameKey is set
+// for the values of a DBDupSort database after the first of their key,
+// including the first record of a batch continuing the key of the previous
+// one.
+type bulkRecord struct {
+	sortRecord
+	sameKey bool
+}
+
+// Load writes the records from src and returns the statistics of the load.
+//
+// The records of a transaction are read into a batch before it starts, so
+// that Store.Update can run it again under a MapGrowth or RetryPolicy
+// without reading from src twice.
+func (l *BulkLoader) Load(src BulkSource) (BulkStats, error) {
+	var (
+		stats    BulkStats
+		start    = time.Now()
+		flags    DBFlags
+		txnBytes = l.TxnBytes
+	)
+	if err := l.Store.View(func(tx *Tx) error {
+		var err error
+		if flags, _, err = tx.DBIFlags(l.DBI); err != nil {
+			return err
+		}
+		if txnBytes <= 0 {
+			txnBytes, err = l.defaultTxnBytes(tx)
+		}
+		return err
+	}); err != nil {
+		return stats, err
+	}
+
+	if l.Unsorted {
+		sorter := newExternalSort(flags, l.SortBytes, l.TempDir)
+		defer sorter.close()
+		for {
+			key, value, err := src()
+			if err == io.EOF {
+				break
+			}
+			if err == nil {
+				err = sorter.add(key, value)
+			}
+			if err != nil {
+				stats.Elapsed = time.Since(start)
+				return stats, err
+			}
+		}
+		var err error
+		if src, err = sorter.source(); err != nil {
+			stats.Elapsed = time.Since(start)
+			return stats, err
+		}
+	}
+
+	var (
+		cmpKey  = KeyComparator(flags)
+		cmpData = DataComparator(flags)
+		prevKey []byte
+		prevVal []byte
+		hasPrev bool
+		batch   []byte
+		records []bulkRecord
+		pending []byte
+		// keyValues counts the values of the last key written by the
+		// committed transactions, nextKeyValues by the current one.
+		keyValues     int
+		nextKeyValues int
+	)
+	put := PutAppend
+	if flags&DBDupSort != 0 {
+		put |= PutAppendDup
+	}
+
+	for done := false; !done; {
+		// Read the batch of the next transaction and check the order.
+		batch, records = batch[:0], records[:0]
+		var bytes uint64
+		for size := 0; size < txnBytes; {
+			key, value, err := src()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				stats.Elapsed = time.Since(start)
+				return stats, err
+			}
+
+			sameKey := false
+			if hasPrev {
+				c := cmpKey(prevKey, key)
+				if c == 0 && flags&DBDupSort != 0 {
+					sameKey, c = true, cmpData(prevVal, value)
+				}
+				if c >= 0 {
+					stats.Elapsed = time.Since(start)
+					return stats, fmt.Errorf("%w: record %d with key %q", ErrBulkOrder, stats.Records+uint64(len(records)), key)
+				}
+			}
+			records = append(records, bulkRecord{
+				sortRecord: sortRecord{offset: len(batch), keyLen: len(key), valLen: len(value)},
+				sameKey:    sameKey,
+			})
+			batch = append(append(batch, key...), value...)
+
+			prevKey = append(prevKey[:0], key...)
+			if flags&DBDupSort != 0 {
+				prevVal = append(prevVal[:0], value...)
+			}
+			hasPrev = true
+			bytes += uint64(len(key) + len(value))
+			size += len(key) + len(value) + bulkNodeOverhead
+		}
+		if len(records) == 0 {
+			break
+		}
+
+		// The transaction only reads the batch and keyValues, so that it can
+		// be run again.
+		err := l.Store.Update(func(tx *Tx) error {
+			// pending holds the values of the current key in a DBDupFixed
+			// database not written yet, multiSize is their size and kv
+			// counts the values of the key written.
+			pending = pending[:0]
+			multiSize, kv := -1, keyValues
+			var pendingKey []byte
+			flush := func() error {
+				k, rest := sliceVal(pendingKey), pending
+				for len(rest) > 0 {
+					n := len(rest) / multiSize
+					if n < 2 || kv < 2 {
+						// mdbx fails PutMultiple for keys with less than two
+						// values, and PutMultiple takes at least two.
+						v := sliceVal(rest[:multiSize])
+						if err := tx.Put(l.DBI, &k, &v, put); err != nil {
+							return err
+						}
+						n = 1
+					} else {
+						// PutMultiple takes the size of one value and the
+						// count, and reports the number of values written in
+						// the second Val.
+						data := [2]Val{{Base: &rest[0], Len: uint64(multiSize)}, {Len: uint64(n)}}
+						if err := tx.Put(l.DBI, &k, &data[0], put|PutMultiple); err != nil {
+							return err
+						}
+						if n = int(data[1].Len); n == 0 {
+							return dbiError("bulk_put", tx.env, l.DBI, &k, ErrEINVAL)
+						}
+					}
+					rest = rest[n*multiSize:]
+					kv += n
+				}
+				pending = pending[:0]
+				return nil
+			}
+
+			for _, r := range records {
+				key, value := batch[r.offset:r.offset+r.keyLen], batch[r.offset+r.keyLen:r.offset+r.keyLen+r.valLen]
+				if flags&DBDupFixed == 0 {
+					k, v := sliceVal(key), sliceVal(value)
+					if err := tx.Put(l.DBI, &k, &v, put); err != nil {
+						return err
+					}
+					continue
+				}
+				if !r.sameKey || len(value) != multiSize {
+					if err := flush(); err != nil {
+						return err
+					}
+					if !r.sameKey {
+						kv = 0
+					}
+					multiSize, pendingKey = len(value), key
+				}
+				if multiSize == 0 {
+					k, v := sliceVal(key), Val{}
+					if err := tx.Put(l.DBI, &k, &v, put); err != nil {
+						return err
+					}
+				} else {
+					pending = append(pending, value...)
+				}
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			nextKeyValues = kv
+			return nil
+		})
+		if err != nil {
+			stats.Elapsed = time.Since(start)
+			return stats, err
+		}
+		keyValues = nextKeyValues
+		stats.Records += uint64(len(records))
+		stats.Bytes += bytes
+		stats.Txns++
+		stats.Elapsed = time.Since(start)
+		if l.Progress != nil {
+			l.Progress(stats)
+		}
+	}
+	return stats, nil
+}
+
+func (l *BulkLoader) defaultTxnBytes(tx *Tx) (int, error) {
+	limit, err := l.Store.Env().GetTxDPLimit()
+	if err != nil {
+		return 0, err
+	}
+	var stat Stats
+	if err = tx.DBIStat(l.DBI, &stat); err != nil {
+		return 0, err
+	}
+	if n := limit * uint64(stat.PageSize) / 2; n < 1<<30 {
+		return int(n), nil
+	}
+	return 1 << 30, nil
+}
@@ -0,0 +1,280 @@
+package mdbx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestBulkLoader(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	plain := openTestDBI(t, store, "plain", DBDefaults)
+	fixed := openTestDBI(t, store, "fixed", DBDupSort|DBDupFixed)
+
+	const n = 10000
+	i := 0
+	var progress []BulkStats
+	loader := BulkLoader{Store: store, DBI: plain, TxnBytes: 64 << 10, Progress: func(s BulkStats) {
+		progress = append(progress, s)
+	}}
+	stats, err := loader.Load(func() ([]byte, []byte, error) {
+		if i == n {
+			return nil, nil, io.EOF
+		}
+		i++
+		return []byte(fmt.Sprintf("key%06d", i)), []byte(fmt.Sprintf("value%d", i)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != n || stats.Txns < 2 || len(progress) != stats.Txns || progress[len(progress)-1] != stats {
+		t.Fatalf("stats %+v, %d progress reports", stats, len(progress))
+	}
+	if stats.RecordsPerSecond() <= 0 || stats.BytesPerSecond() <= 0 {
+		t.Fatalf("no throughput in %+v", stats)
+	}
+
+	// Three keys with 1000 values each, written with PutMultiple.
+	i = 0
+	var value [8]byte
+	loader = BulkLoader{Store: store, DBI: fixed, TxnBytes: 10 << 10}
+	if stats, err = loader.Load(func() ([]byte, []byte, error) {
+		if i == 3000 {
+			return nil, nil, io.EOF
+		}
+		binary.BigEndian.PutUint64(value[:], uint64(i%1000))
+		i++
+		return []byte{byte('a' + (i-1)/1000)}, value[:], nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != 3000 {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		var stat Stats
+		if err := tx.DBIStat(plain, &stat); err != nil {
+			return err
+		}
+		if stat.Entries != n {
+			t.Fatalf("%d entries, want %d", stat.Entries, n)
+		}
+		key, data := StringConst("key000042"), Val{}
+		if err := tx.Get(plain, &key, &data); err != nil || data.String() != "value42" {
+			t.Fatalf("got %q, %v", data.String(), err)
+		}
+
+		cursor, err := tx.OpenCursor(fixed)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		keys := ""
+		for err = cursor.Get(&key, &data, CursorFirst); err == nil; err = cursor.Get(&key, &data, CursorNextNoDup) {
+			count, err := cursor.Count()
+			if err != nil {
+				return err
+			}
+			keys += fmt.Sprintf("%s:%d ", key.String(), count)
+			if err = cursor.Get(&key, &data, CursorLastDup); err != nil {
+				return err
+			}
+			if binary.BigEndian.Uint64(data.UnsafeBytes()) != 999 {
+				t.Fatalf("last value of %s is %x", key.String(), data.UnsafeBytes())
+			}
+		}
+		if err != ErrNotFound {
+			return err
+		}
+		if keys != "a:1000 b:1000 c:1000 " {
+			t.Fatalf("got %q", keys)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []string{"x", "z", "y"}
+	loader = BulkLoader{Store: store, DBI: plain}
+	_, err = loader.Load(func() ([]byte, []byte, error) {
+		if len(records) == 0 {
+			return nil, nil, io.EOF
+		}
+		key := records[0]
+		records = records[1:]
+		return []byte(key), nil, nil
+	})
+	if !errors.Is(err, ErrBulkOrder) {
+		t.Fatalf("got %v, want ErrBulkOrder", err)
+	}
+
+	records = []string{"a"}
+	_, err = loader.Load(func() ([]byte, []byte, error) {
+		if len(records) == 0 {
+			return nil, nil, io.EOF
+		}
+		key := records[0]
+		records = records[1:]
+		return []byte(key), nil, nil
+	})
+	if !errors.Is(err, ErrEKeyMismatch) {
+		t.Fatalf("got %v, want ErrEKeyMismatch", err)
+	}
+}
+
+func TestBulkLoader_Unsorted(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "ints", DBIntegerKey)
+
+	perm := rand.New(rand.NewSource(1)).Perm(5000)
+	var key [8]byte
+	loader := BulkLoader{Store: store, DBI: dbi, Unsorted: true, SortBytes: 4096, TempDir: t.TempDir()}
+	stats, err := loader.Load(func() ([]byte, []byte, error) {
+		if len(perm) == 0 {
+			return nil, nil, io.EOF
+		}
+		binary.LittleEndian.PutUint64(key[:], uint64(perm[0]))
+		perm = perm[1:]
+		return key[:], key[:1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != 5000 {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		var k, v Val
+		next := uint64(0)
+		for err = cursor.Get(&k, &v, CursorFirst); err == nil; err = cursor.Get(&k, &v, CursorNext) {
+			if n := binary.LittleEndian.Uint64(k.UnsafeBytes()); n != next {
+				t.Fatalf("got key %d, want %d", n, next)
+			}
+			next++
+		}
+		if err != ErrNotFound {
+			return err
+		}
+		if next != 5000 {
+			t.Fatalf("got %d keys", next)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkLoader_MapGrowth(t *testing.T) {
+	store := openSmallStore(t)
+	fixed := openTestDBI(t, store, "fixed", DBDupSort|DBDupFixed)
+	plain := openTestDBI(t, store, "plain", DBDefaults)
+	grown := 0
+	if err := store.SetMapGrowth(&MapGrowth{
+		Ceiling: 64 << 20,
+		OnGrow: func(MapGrowthEvent) {
+			grown++
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Transactions failing with a full map are run again with the records
+	// read for them, none may be lost.
+	i := 0
+	var value [8]byte
+	loader := BulkLoader{Store: store, DBI: fixed, TxnBytes: 256 << 10}
+	stats, err := loader.Load(func() ([]byte, []byte, error) {
+		if i == 200000 {
+			return nil, nil, io.EOF
+		}
+		binary.BigEndian.PutUint64(value[:], uint64(i%100000))
+		i++
+		return []byte{byte('a' + (i-1)/100000)}, value[:], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != 200000 {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	i = 0
+	large := make([]byte, 512)
+	loader = BulkLoader{Store: store, DBI: plain, TxnBytes: 512 << 10}
+	if stats, err = loader.Load(func() ([]byte, []byte, error) {
+		if i == 10000 {
+			return nil, nil, io.EOF
+		}
+		i++
+		binary.BigEndian.PutUint64(large, uint64(i))
+		return []byte(fmt.Sprintf("key%06d", i)), large, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != 10000 {
+		t.Fatalf("stats %+v", stats)
+	}
+	if grown == 0 {
+		t.Fatal("the map did not grow")
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		var stat Stats
+		if err := tx.DBIStat(plain, &stat); err != nil {
+			return err
+		}
+		if stat.Entries != 10000 {
+			t.Fatalf("%d entries, want 10000", stat.Entries)
+		}
+		cursor, err := tx.OpenCursor(plain)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		var key, data Val
+		n := uint64(0)
+		for err = cursor.Get(&key, &data, CursorFirst); err == nil; err = cursor.Get(&key, &data, CursorNext) {
+			n++
+			if got := binary.BigEndian.Uint64(data.UnsafeBytes()); got != n {
+				t.Fatalf("record %d has value %d", n, got)
+			}
+		}
+		if err != ErrNotFound {
+			return err
+		}
+
+		fc, err := tx.OpenCursor(fixed)
+		if err != nil {
+			return err
+		}
+		defer fc.Close()
+		for _, k := range []string{"a", "b"} {
+			key = StringConst(k)
+			if err = fc.Get(&key, &data, CursorSet); err != nil {
+				return err
+			}
+			if count, err := fc.Count(); err != nil || count != 100000 {
+				t.Fatalf("%s has %d values, %v", k, count, err)
+			}
+			if err = fc.Get(&key, &data, CursorLastDup); err != nil {
+				return err
+			}
+			if last := binary.BigEndian.Uint64(data.UnsafeBytes()); last != 99999 {
+				t.Fatalf("last value of %s is %d", k, last)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,115 @@
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Table is a database registered in the Store catalog. KeyCmp and DataCmp are
+// optional custom comparators, which every process opening the database must
+// use consistently.
+type Table struct {
+	Name    string
+	Flags   DBFlags
+	KeyCmp  *Cmp
+	DataCmp *Cmp
+	DBI     DBI // Set by Register
+}
+
+var (
+	ErrTableNotFound = errors.New("mdbx: table does not exist")
+	ErrTableMismatch = errors.New("mdbx: table flags do not match the database")
+)
+
+// Register opens the tables, creating the missing ones unless the environment
+// is read-only, and adds them to the Store catalog so their handles can be
+// retrieved with DBI. Existing databases must have been created with the
+// declared flags. No table is registered if any of them fails.
+func (s *Store) Register(tables ...Table) error {
+	envFlags, e := s.env.GetFlags()
+	if e != nil {
+		return e
+	}
+	readOnly := envFlags&EnvReadOnly != 0
+	for _, t := range tables {
+		if msg := validateDBIFlags(t.Flags); msg != "" {
+			return fmt.Errorf("%w: table %q: %s", ErrInvalidOptions, t.Name, msg)
+		}
+	}
+
+	opened := make([]Table, 0, len(tables))
+	open := func(tx *Tx) error {
+		for _, t := range tables {
+			dbi, err := tx.OpenDBIEx(t.Name, DBAccede, t.KeyCmp, t.DataCmp)
+			switch {
+			case err == ErrNotFound && readOnly:
+				return fmt.Errorf("%w: %q", ErrTableNotFound, t.Name)
+			case err == ErrNotFound:
+				if dbi, err = tx.OpenDBIEx(t.Name, t.Flags|DBCreate, t.KeyCmp, t.DataCmp); err != nil {
+					return fmt.Errorf("mdbx: create table %q: %w", t.Name, err)
+				}
+			case err != nil:
+				return fmt.Errorf("mdbx: open table %q: %w", t.Name, err)
+			}
+			flags, _, err := tx.DBIFlags(dbi)
+			if err != nil {
+				return fmt.Errorf("mdbx: open table %q: %w", t.Name, err)
+			}
+			if existing := flags & dbiFlagsMask; existing != t.Flags {
+				return fmt.Errorf("%w: table %q was created with %v but is declared with %v",
+					ErrTableMismatch, t.Name, existing, t.Flags)
+			}
+			t.DBI = dbi
+			opened = append(opened, t)
+		}
+		return nil
+	}
+	var err error
+	if readOnly {
+		err = s.View(open)
+	} else {
+		err = s.Update(open)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.catalogMu.Lock()
+	if s.catalog == nil {
+		s.catalog = make(map[string]Table, len(opened))
+	}
+	for _, t := range opened {
+		s.catalog[t.Name] = t
+	}
+	s.catalogMu.Unlock()
+	return nil
+}
+
+// DBI returns the handle of a registered table.
+func (s *Store) DBI(name string) (DBI, bool) {
+	s.catalogMu.RLock()
+	t, ok := s.catalog[name]
+	s.catalogMu.RUnlock()
+	return t.DBI, ok
+}
+
+// Table returns a registered table.
+func (s *Store) Table(name string) (Table, bool) {
+	s.catalogMu.RLock()
+	t, ok := s.catalog[name]
+	s.catalogMu.RUnlock()
+	return t, ok
+}
+
+// Tables returns the registered tables ordered by name.
+func (s *Store) Tables() []Table {
+	s.catalogMu.RLock()
+	tables := make([]Table, 0, len(s.catalog))
+	for _, t := range s.catalog {
+		tables = append(tables, t)
+	}
+	s.catalogMu.RUnlock()
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	return tables
+}
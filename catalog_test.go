@@ -0,0 +1,77 @@
+package mdbx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStore_Register(t *testing.T) {
+	path := t.TempDir()
+	store := openTestStore(t, path, EnvSafeNoSync)
+	if err := store.Register(
+		Table{Name: "users"},
+		Table{Name: "events", Flags: DBDupSort | DBDupFixed},
+		Table{Name: "by_id", KeyCmp: CmpU64},
+	); err != nil {
+		t.Fatal(err)
+	}
+	dbi, ok := store.DBI("events")
+	if !ok {
+		t.Fatal("events not registered")
+	}
+	if err := store.View(func(tx *Tx) error {
+		flags, _, err := tx.DBIFlags(dbi)
+		if err != nil {
+			return err
+		}
+		if flags&dbiFlagsMask != DBDupSort|DBDupFixed {
+			t.Fatalf("events flags %v", flags)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = store.DBI("missing"); ok {
+		t.Fatal("unexpected table")
+	}
+	if tables := store.Tables(); len(tables) != 3 || tables[0].Name != "by_id" {
+		t.Fatalf("tables %+v", tables)
+	}
+
+	// Registering the same declaration again is fine, a different one is not.
+	if err := store.Register(Table{Name: "events", Flags: DBDupSort | DBDupFixed}); err != nil {
+		t.Fatal(err)
+	}
+	err := store.Register(Table{Name: "users", Flags: DBDupSort}, Table{Name: "other"})
+	if !errors.Is(err, ErrTableMismatch) || !strings.Contains(err.Error(), `"users" was created with defaults but is declared with dupsort`) {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+	if _, ok = store.DBI("other"); ok {
+		t.Fatal("table registered despite failure")
+	}
+	if err = store.Register(Table{Name: "bad", Flags: DBIntegerGroup}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected invalid flags, got %v", err)
+	}
+}
+
+func TestOpenWithOptions_Catalog(t *testing.T) {
+	opts := DefaultOptions(t.TempDir())
+	opts.Flags = EnvSafeNoSync
+	opts.DBIs = []DBIOptions{{Name: "counters", Flags: DBIntegerKey}}
+	store, err := OpenWithOptions(opts, func(store *Store, create bool) error {
+		if _, ok := store.DBI("counters"); !ok {
+			t.Fatal("declared dbi not registered before init")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	opts.DBIs[0].Flags = 0
+	if _, err = OpenWithOptions(opts, nil); !errors.Is(err, ErrTableMismatch) {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,686 @@
+package mdbx
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+var (
+	ErrChangeFeedDisabled = errors.New("mdbx: change feed is not enabled")
+	ErrCorruptChangeSet   = errors.New("mdbx: corrupt change set")
+)
+
+// ChangeOp is the kind of modification recorded in a Change.
+type ChangeOp uint8
+
+const (
+	// ChangePut a key/value pair was inserted or overwritten.
+	ChangePut ChangeOp = iota + 1
+
+	// ChangeDelete a key/value pair was removed (tombstone).
+	ChangeDelete
+
+	// ChangeDrop a whole database was emptied.
+	ChangeDrop
+
+	// ChangeDeleteDBI a whole database was deleted and its handle closed.
+	ChangeDeleteDBI
+)
+
+func (op ChangeOp) String() string {
+	switch op {
+	case ChangePut:
+		return "put"
+	case ChangeDelete:
+		return "delete"
+	case ChangeDrop:
+		return "drop"
+	case ChangeDeleteDBI:
+		return "delete-dbi"
+	}
+	return "unknown"
+}
+
+// Change is a single modification made by a committed write transaction.
+//
+// Old holds the previous value of the key, or nil if the key did not exist.
+// For DBDupSort databases a put adds a duplicate rather than replacing one, so
+// Old is always nil, and a delete without data removes every duplicate of the
+// key and is recorded with a nil Old. New is nil for deletes and drops.
+type Change struct {
+	Op  ChangeOp
+	DBI DBI
+	Key []byte
+	Old []byte
+	New []byte
+}
+
+// ChangeDBI describes a database touched by a ChangeSet. DBI handles are
+// local to an environment, so consumers applying changes elsewhere should
+// resolve databases by Name.
+type ChangeDBI struct {
+	DBI   DBI
+	Name  string
+	Flags DBFlags
+}
+
+// ChangeSet is the ordered list of changes committed by a single transaction.
+type ChangeSet struct {
+	TxID    uint64
+	DBIs    []ChangeDBI
+	Changes []Change
+}
+
+// LookupDBI returns the description of dbi or false if no change touched it.
+func (cs *ChangeSet) LookupDBI(dbi DBI) (ChangeDBI, bool) {
+	for _, d := range cs.DBIs {
+		if d.DBI == dbi {
+			return d, true
+		}
+	}
+	return ChangeDBI{}, false
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+// Recording
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// changeLog accumulates the changes of a write transaction. It is attached to
+// a Tx by Store.UpdateLock when the change feed is enabled and is written to
+// the feed DBI right before the commit.
+type changeLog struct {
+	feed     *changeFeed
+	dbis     []ChangeDBI
+	changes  []Change
+	reserved []int
+}
+
+// touch records the name and flags of dbi the first time it is modified.
+func (l *changeLog) touch(tx *Tx, dbi DBI) ChangeDBI {
+	for _, d := range l.dbis {
+		if d.DBI == dbi {
+			return d
+		}
+	}
+	d := ChangeDBI{DBI: dbi}
+	d.Flags, _, _ = tx.DBIFlags(dbi)
+	d.Name, _ = tx.env.DBIName(dbi)
+	l.dbis = append(l.dbis, d)
+	return d
+}
+
+func (l *changeLog) dbiFlags(tx *Tx, dbi DBI) DBFlags {
+	return l.touch(tx, dbi).Flags
+}
+
+// current returns a copy of the current value of key or nil if not present.
+func (l *changeLog) current(tx *Tx, dbi DBI, key *Val) []byte {
+	k := *key
+	var v Val
+	if tx.Get(dbi, &k, &v) != nil {
+		return nil
+	}
+	return v.Bytes()
+}
+
+// changeTxs maps the C transactions of Store.Update with a change feed to
+// their Tx, so that writes through a cursor like Cursor.PutMulti can be
+// recorded.
+var changeTxs sync.Map
+
+// changeTx returns the Tx recording the changes of txn, nil if there is none.
+func changeTx(txn unsafe.Pointer) *Tx {
+	if tx, ok := changeTxs.Load(txn); ok {
+		return tx.(*Tx)
+	}
+	return nil
+}
+
+func (l *changeLog) put(tx *Tx, dbi DBI, key *Val, data *Val, flags PutFlags) Error {
+	var old []byte
+	if l.dbiFlags(tx, dbi)&DBDupSort == 0 {
+		old = l.current(tx, dbi, key)
+	}
+	// PutMultiple advances the first Val over the values it writes.
+	first := data.Base
+	if err := tx.put(dbi, key, data, flags); err != ErrSuccess {
+		return err
+	}
+	k := key.Bytes()
+	switch {
+	case flags&PutMultiple != 0:
+		// data points to a pair of Val: the first element and the count written.
+		multi := (*[2]Val)(unsafe.Pointer(data))
+		size := multi[0].Len
+		all := unsafe.Slice(first, size*multi[1].Len)
+		for i := uint64(0); i < multi[1].Len; i++ {
+			v := make([]byte, size)
+			copy(v, all[i*size:(i+1)*size])
+			l.changes = append(l.changes, Change{Op: ChangePut, DBI: dbi, Key: k, New: v})
+		}
+		return ErrSuccess
+	case flags&PutReserve != 0:
+		// The value is filled in by the caller after the put returns.
+		l.reserved = append(l.reserved, len(l.changes))
+		l.changes = append(l.changes, Change{Op: ChangePut, DBI: dbi, Key: k, Old: old})
+	default:
+		l.changes = append(l.changes, Change{Op: ChangePut, DBI: dbi, Key: k, Old: old, New: data.Bytes()})
+	}
+	return ErrSuccess
+}
+
+func (l *changeLog) replace(tx *Tx, dbi DBI, key *Val, data *Val, oldData *Val, flags PutFlags) Error {
+	l.touch(tx, dbi)
+	if err := tx.replace(dbi, key, data, oldData, flags); err != ErrSuccess {
+		return err
+	}
+	c := Change{Op: ChangePut, DBI: dbi, Key: key.Bytes()}
+	if oldData != nil && oldData.Base != nil {
+		c.Old = oldData.Bytes()
+	}
+	if data == nil {
+		c.Op = ChangeDelete
+	} else {
+		c.New = data.Bytes()
+	}
+	l.changes = append(l.changes, c)
+	return ErrSuccess
+}
+
+func (l *changeLog) delete(tx *Tx, dbi DBI, key *Val, data *Val) Error {
+	var old []byte
+	flags := l.dbiFlags(tx, dbi)
+	if data != nil {
+		old = data.Bytes()
+	} else if flags&DBDupSort == 0 {
+		old = l.current(tx, dbi, key)
+	}
+	if err := tx.delete(dbi, key, data); err != ErrSuccess {
+		return err
+	}
+	l.changes = append(l.changes, Change{Op: ChangeDelete, DBI: dbi, Key: key.Bytes(), Old: old})
+	return ErrSuccess
+}
+
+func (l *changeLog) drop(tx *Tx, dbi DBI, del bool) Error {
+	l.touch(tx, dbi)
+	if err := tx.drop(dbi, del); err != ErrSuccess {
+		return err
+	}
+	op := ChangeDrop
+	if del {
+		op = ChangeDeleteDBI
+	}
+	l.changes = append(l.changes, Change{Op: op, DBI: dbi})
+	return ErrSuccess
+}
+
+// flush writes the accumulated changes to the feed DBI keyed by the
+// transaction ID. It must be called inside the transaction before commit.
+func (l *changeLog) flush(tx *Tx) Error {
+	if len(l.changes) == 0 {
+		return ErrSuccess
+	}
+	for _, i := range l.reserved {
+		c := &l.changes[i]
+		k := sliceVal(c.Key)
+		c.New = l.current(tx, c.DBI, &k)
+	}
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], tx.ID())
+	buf := appendChanges(nil, l.dbis, l.changes)
+	key := sliceVal(id[:])
+	data := sliceVal(buf)
+	err := tx.put(l.feed.dbi, &key, &data, PutUpsert)
+	l.dbis = l.dbis[:0]
+	l.changes = l.changes[:0]
+	l.reserved = l.reserved[:0]
+	return err
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+// Encoding
+//////////////////////////////////////////////////////////////////////////////////////////
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(b, tmp[:n]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+// appendOptional encodes a possibly nil slice as len+1 followed by the bytes,
+// reserving zero for nil.
+func appendOptional(b []byte, v []byte) []byte {
+	if v == nil {
+		return appendUvarint(b, 0)
+	}
+	b = appendUvarint(b, uint64(len(v))+1)
+	return append(b, v...)
+}
+
+func appendChanges(b []byte, dbis []ChangeDBI, changes []Change) []byte {
+	b = appendUvarint(b, uint64(len(dbis)))
+	for _, d := range dbis {
+		b = appendUvarint(b, uint64(d.DBI))
+		b = appendUvarint(b, uint64(d.Flags))
+		b = appendUvarint(b, uint64(len(d.Name)))
+		b = append(b, d.Name...)
+	}
+	b = appendUvarint(b, uint64(len(changes)))
+	for _, c := range changes {
+		b = append(b, byte(c.Op))
+		b = appendUvarint(b, uint64(c.DBI))
+		b = appendUvarint(b, uint64(len(c.Key)))
+		b = append(b, c.Key...)
+		b = appendOptional(b, c.Old)
+		b = appendOptional(b, c.New)
+	}
+	return b
+}
+
+type changeDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *changeDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = ErrCorruptChangeSet
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *changeDecoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.b)) {
+		d.err = ErrCorruptChangeSet
+		return nil
+	}
+	v := make([]byte, n)
+	copy(v, d.b[:n])
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *changeDecoder) optional() []byte {
+	n := d.uvarint()
+	if n == 0 {
+		return nil
+	}
+	return d.bytes(n - 1)
+}
+
+// decodeChanges decodes a buffer produced by appendChanges. The returned
+// change set does not reference b.
+func decodeChanges(txID uint64, b []byte) (ChangeSet, error) {
+	d := changeDecoder{b: b}
+	count := d.uvarint()
+	if count > uint64(len(b)) {
+		return ChangeSet{}, ErrCorruptChangeSet
+	}
+	dbis := make([]ChangeDBI, 0, count)
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		dbi := ChangeDBI{DBI: DBI(d.uvarint()), Flags: DBFlags(d.uvarint())}
+		dbi.Name = string(d.bytes(d.uvarint()))
+		dbis = append(dbis, dbi)
+	}
+	count = d.uvarint()
+	if count > uint64(len(b)) {
+		return ChangeSet{}, ErrCorruptChangeSet
+	}
+	changes := make([]Change, 0, count)
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		if len(d.b) == 0 {
+			return ChangeSet{}, ErrCorruptChangeSet
+		}
+		c := Change{Op: ChangeOp(d.b[0])}
+		d.b = d.b[1:]
+		c.DBI = DBI(d.uvarint())
+		c.Key = d.bytes(d.uvarint())
+		c.Old = d.optional()
+		c.New = d.optional()
+		changes = append(changes, c)
+	}
+	if d.err != nil {
+		return ChangeSet{}, d.err
+	}
+	return ChangeSet{TxID: txID, DBIs: dbis, Changes: changes}, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+// Feed
+//////////////////////////////////////////////////////////////////////////////////////////
+
+type changeFeed struct {
+	store  *Store
+	dbi    DBI
+	ch     chan struct{}
+	closed bool
+	mu     sync.Mutex
+	rw     sync.RWMutex
+}
+
+func newChangeFeed(store *Store, dbi DBI) *changeFeed {
+	return &changeFeed{
+		store: store,
+		dbi:   dbi,
+		ch:    make(chan struct{}),
+	}
+}
+
+// notify wakes up every subscriber waiting for new commits.
+func (f *changeFeed) notify() {
+	f.mu.Lock()
+	close(f.ch)
+	f.ch = make(chan struct{})
+	f.mu.Unlock()
+}
+
+func (f *changeFeed) wait() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ch
+}
+
+// close waits for in-flight reads to finish so the environment can be closed.
+func (f *changeFeed) close() {
+	f.rw.Lock()
+	f.closed = true
+	f.rw.Unlock()
+	f.notify()
+}
+
+// feedMeta is stored in the feed DBI under transaction ID zero, which is never
+// assigned to a write transaction.
+type feedMeta struct {
+	// Epoch is a random identity assigned when the feed is first enabled.
+	Epoch uint64
+	// Truncated is the lowest transaction ID still present in the feed.
+	Truncated uint64
+}
+
+var feedMetaKey [8]byte
+
+func readFeedMeta(tx *Tx, dbi DBI) (feedMeta, error) {
+	key := sliceVal(feedMetaKey[:])
+	data := Val{}
+	if err := tx.Get(dbi, &key, &data); err != nil {
+		return feedMeta{}, err
+	}
+	if data.Len != 16 {
+		return feedMeta{}, ErrCorrupted
+	}
+	b := data.UnsafeBytes()
+	return feedMeta{
+		Epoch:     binary.BigEndian.Uint64(b),
+		Truncated: binary.BigEndian.Uint64(b[8:]),
+	}, nil
+}
+
+func writeFeedMeta(tx *Tx, dbi DBI, meta feedMeta) error {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:], meta.Epoch)
+	binary.BigEndian.PutUint64(b[8:], meta.Truncated)
+	key := sliceVal(feedMetaKey[:])
+	data := sliceVal(b[:])
+	return dbiError("put", tx.env, dbi, &key, tx.put(dbi, &key, &data, PutUpsert))
+}
+
+func (f *changeFeed) meta() (meta feedMeta, err error) {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	if f.closed {
+		return meta, os.ErrClosed
+	}
+	err = f.store.View(func(tx *Tx) error {
+		var e error
+		if meta, e = readFeedMeta(tx, f.dbi); e != nil {
+			return e
+		}
+		return nil
+	})
+	return
+}
+
+// read appends up to limit change sets with a transaction ID >= from.
+func (f *changeFeed) read(from uint64, batch []ChangeSet, limit int) ([]ChangeSet, error) {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	if f.closed {
+		return batch, os.ErrClosed
+	}
+	err := f.store.View(func(tx *Tx) error {
+		cursor, err := tx.OpenCursor(f.dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+
+		if from == 0 {
+			from = 1
+		}
+		var id [8]byte
+		binary.BigEndian.PutUint64(id[:], from)
+		key := sliceVal(id[:])
+		data := Val{}
+		op := CursorSetRange
+		for len(batch) < limit {
+			if err = cursor.Get(&key, &data, op); err != nil {
+				if err == ErrNotFound {
+					return nil
+				}
+				return err
+			}
+			op = CursorNext
+			if key.Len != 8 {
+				return ErrCorruptChangeSet
+			}
+			cs, e := decodeChanges(binary.BigEndian.Uint64(key.UnsafeBytes()), data.UnsafeBytes())
+			if e != nil {
+				return e
+			}
+			batch = append(batch, cs)
+		}
+		return nil
+	})
+	return batch, err
+}
+
+// EnableChangeFeed turns on change data capture for every write transaction
+// of the store. Changes are recorded in the named DBI, which is created if
+// necessary, in the same transaction as the writes themselves. Call it right
+// after Open, before concurrent writers start.
+//
+// Only writes made through Tx.Put, Tx.Replace, Tx.Delete, Tx.Drop and
+// Cursor.PutMulti are captured; other writes made through a Cursor are not.
+func (s *Store) EnableChangeFeed(name string) error {
+	var dbi DBI
+	if err := s.Update(func(tx *Tx) error {
+		var err error
+		dbi, err = tx.OpenDBI(name, DBCreate)
+		if err != nil {
+			return err
+		}
+		if _, err = readFeedMeta(tx, dbi); err != ErrNotFound {
+			return err
+		}
+		var epoch [8]byte
+		if _, e := rand.Read(epoch[:]); e != nil {
+			return e
+		}
+		return writeFeedMeta(tx, dbi, feedMeta{
+			Epoch:     binary.BigEndian.Uint64(epoch[:]) | 1,
+			Truncated: 1,
+		})
+	}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.feed == nil {
+		s.feed = newChangeFeed(s, dbi)
+	}
+	return nil
+}
+
+func (s *Store) changeFeed() *changeFeed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.feed
+}
+
+// TruncateChangeFeed removes recorded change sets with a transaction ID lower
+// than before and returns how many were removed.
+func (s *Store) TruncateChangeFeed(before uint64) (int, error) {
+	feed := s.changeFeed()
+	if feed == nil {
+		return 0, ErrChangeFeedDisabled
+	}
+	count := 0
+	err := s.Update(func(tx *Tx) error {
+		meta, err := readFeedMeta(tx, feed.dbi)
+		if err != nil {
+			return err
+		}
+		if before <= meta.Truncated {
+			return nil
+		}
+		cursor, err := tx.OpenCursor(feed.dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+
+		var id [8]byte
+		binary.BigEndian.PutUint64(id[:], 1)
+		key, data := sliceVal(id[:]), Val{}
+		for {
+			if err = cursor.Get(&key, &data, CursorSetRange); err != nil && err != ErrNotFound {
+				return err
+			}
+			if err == ErrNotFound || key.Len != 8 || binary.BigEndian.Uint64(key.UnsafeBytes()) >= before {
+				break
+			}
+			if err = cursor.Delete(0); err != nil {
+				return err
+			}
+			key = sliceVal(id[:])
+			count++
+		}
+		meta.Truncated = before
+		return writeFeedMeta(tx, feed.dbi, meta)
+	})
+	return count, err
+}
+
+// Subscription tails the change feed of a Store. Committed change sets are
+// delivered on C in transaction order. C is closed when the subscription or
+// the store is closed, after which Err reports the reason.
+type Subscription struct {
+	C    <-chan ChangeSet
+	c    chan ChangeSet
+	next uint64
+	done chan struct{}
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+}
+
+// Subscribe returns a Subscription delivering every recorded change set with
+// a transaction ID >= fromTxID, followed by new commits as they happen.
+func (s *Store) Subscribe(fromTxID uint64) (*Subscription, error) {
+	feed := s.changeFeed()
+	if feed == nil {
+		return nil, ErrChangeFeedDisabled
+	}
+	sub := &Subscription{
+		c:    make(chan ChangeSet, 64),
+		next: fromTxID,
+		done: make(chan struct{}),
+	}
+	sub.C = sub.c
+	go sub.run(feed)
+	return sub, nil
+}
+
+func (sub *Subscription) run(feed *changeFeed) {
+	defer close(sub.c)
+
+	var (
+		batch []ChangeSet
+		err   error
+	)
+	for {
+		wait := feed.wait()
+		batch, err = feed.read(sub.next, batch[:0], 256)
+		if err != nil {
+			sub.setErr(err)
+			return
+		}
+		for _, cs := range batch {
+			select {
+			case sub.c <- cs:
+				sub.next = cs.TxID + 1
+			case <-sub.done:
+				return
+			}
+		}
+		if len(batch) > 0 {
+			continue
+		}
+		select {
+		case <-wait:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+func (sub *Subscription) setErr(err error) {
+	sub.mu.Lock()
+	sub.err = err
+	sub.mu.Unlock()
+}
+
+// Err returns the error that terminated the subscription, if any.
+// It returns os.ErrClosed if the store was closed.
+func (sub *Subscription) Err() error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.err
+}
+
+// Close stops the subscription. C is closed shortly after.
+func (sub *Subscription) Close() error {
+	sub.once.Do(func() {
+		close(sub.done)
+	})
+	return nil
+}
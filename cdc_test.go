@@ -0,0 +1,143 @@
+package mdbx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestChangeFeed_Subscribe(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+	if err := store.EnableChangeFeed("cdc"); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := store.Subscribe(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	put := func(key, value string) {
+		if err := store.Update(func(tx *Tx) error {
+			k, v := StringConst(key), StringConst(value)
+			return tx.Put(dbi, &k, &v, 0)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a", "1")
+	put("a", "2")
+	if err = store.Update(func(tx *Tx) error {
+		k := StringConst("a")
+		return tx.Delete(dbi, &k, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Change{
+		{Op: ChangePut, DBI: dbi, Key: []byte("a"), New: []byte("1")},
+		{Op: ChangePut, DBI: dbi, Key: []byte("a"), Old: []byte("1"), New: []byte("2")},
+		{Op: ChangeDelete, DBI: dbi, Key: []byte("a"), Old: []byte("2")},
+	}
+	var lastTxID uint64
+	for i, want := range expected {
+		var cs ChangeSet
+		select {
+		case cs = <-sub.C:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for change set %d", i)
+		}
+		if cs.TxID <= lastTxID {
+			t.Fatalf("tx ids out of order: %d after %d", cs.TxID, lastTxID)
+		}
+		lastTxID = cs.TxID
+		if len(cs.Changes) != 1 {
+			t.Fatalf("expected 1 change, got %d", len(cs.Changes))
+		}
+		got := cs.Changes[0]
+		if got.Op != want.Op || got.DBI != want.DBI || !bytes.Equal(got.Key, want.Key) ||
+			!bytes.Equal(got.Old, want.Old) || !bytes.Equal(got.New, want.New) ||
+			(got.Old == nil) != (want.Old == nil) {
+			t.Fatalf("change %d: got %+v want %+v", i, got, want)
+		}
+	}
+
+	removed, err := store.TruncateChangeFeed(lastTxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Fatalf("expected 2 truncated change sets, got %d", removed)
+	}
+}
+
+func TestChangeFeed_AbortNotRecorded(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+	if err := store.EnableChangeFeed("cdc"); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Update(func(tx *Tx) error {
+		k, v := StringConst("a"), StringConst("1")
+		if err := tx.Put(dbi, &k, &v, 0); err != nil {
+			return err
+		}
+		return ErrNotFound
+	})
+
+	sub, err := store.Subscribe(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for cs := range sub.C {
+		t.Fatalf("unexpected change set %+v", cs)
+	}
+}
+
+func TestChangeFeed_PutMultiple(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "fixed", DBDupSort|DBDupFixed)
+	if err := store.EnableChangeFeed("cdc"); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := store.Subscribe(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	if err = store.Update(func(tx *Tx) error {
+		k := StringConst("k")
+		// mdbx only takes PutMultiple for keys with two values already.
+		for _, v := range []string{"0", "1"} {
+			data := StringConst(v)
+			if err := tx.Put(dbi, &k, &data, 0); err != nil {
+				return err
+			}
+		}
+		values := []byte("23456")
+		data := [2]Val{{Base: &values[0], Len: 1}, {Len: 5}}
+		return tx.Put(dbi, &k, &data[0], PutMultiple)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var cs ChangeSet
+	select {
+	case cs = <-sub.C:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for change set")
+	}
+	var values []byte
+	for _, c := range cs.Changes {
+		values = append(values, c.New...)
+	}
+	if string(values) != "0123456" {
+		t.Fatalf("got values %q", values)
+	}
+}
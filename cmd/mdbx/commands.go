@@ -0,0 +1,546 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moontrade/mdbx-go"
+)
+
+func runChk(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "-json" && args[0] != "--json") {
+		mdbx.ChkMain(args...)
+		return 0
+	}
+	result, output, err := mdbx.Chk(args[1:]...)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	code := writeJSON(stdout, struct {
+		Result int32  `json:"result"`
+		OK     bool   `json:"ok"`
+		Output string `json:"output"`
+	}{result, result == 0, string(output)})
+	if result != 0 {
+		return 1
+	}
+	return code
+}
+
+func runDump(args []string, stdout, stderr io.Writer) int {
+	return mdbx.DumpCommand(args, stdout, stderr)
+}
+
+func runLoad(args []string, stdout, stderr io.Writer) int {
+	return mdbx.LoadCommand(args, stdin, stderr)
+}
+
+type geometryInfo struct {
+	Lower   uint64 `json:"lower"`
+	Current uint64 `json:"current"`
+	Upper   uint64 `json:"upper"`
+	Shrink  uint64 `json:"shrink"`
+	Grow    uint64 `json:"grow"`
+}
+
+type dbiStat struct {
+	Name          string   `json:"name"`
+	Flags         []string `json:"flags,omitempty"`
+	Depth         uint32   `json:"depth"`
+	BranchPages   uint64   `json:"branch_pages"`
+	LeafPages     uint64   `json:"leaf_pages"`
+	OverflowPages uint64   `json:"overflow_pages"`
+	Entries       uint64   `json:"entries"`
+	ModTxnID      uint64   `json:"mod_txn_id"`
+}
+
+type envStat struct {
+	Path        string       `json:"path"`
+	PageSize    uint32       `json:"page_size"`
+	Geometry    geometryInfo `json:"geometry"`
+	MapSize     uint64       `json:"map_size"`
+	LastPage    uint64       `json:"last_page"`
+	RecentTxnID uint64       `json:"recent_txn_id"`
+	MaxReaders  uint32       `json:"max_readers"`
+	NumReaders  uint32       `json:"num_readers"`
+	DBIs        []dbiStat    `json:"databases"`
+}
+
+var dbFlagNames = []struct {
+	flag mdbx.DBFlags
+	name string
+}{
+	{mdbx.DBReverseKey, "reversekey"},
+	{mdbx.DBDupSort, "dupsort"},
+	{mdbx.DBIntegerKey, "integerkey"},
+	{mdbx.DBDupFixed, "dupfixed"},
+	{mdbx.DBIntegerGroup, "integerdup"},
+	{mdbx.DBReverseDup, "reversedup"},
+}
+
+func flagNames(flags mdbx.DBFlags) []string {
+	var names []string
+	for _, f := range dbFlagNames {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+func geometryOf(info *mdbx.EnvInfo) geometryInfo {
+	return geometryInfo{
+		Lower:   info.Geo.Lower,
+		Current: info.Geo.Current,
+		Upper:   info.Geo.Upper,
+		Shrink:  info.Geo.Shrink,
+		Grow:    info.Geo.Grow,
+	}
+}
+
+func runStat(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("stat", stderr)
+	asJSON := fs.Bool("json", false, "write JSON")
+	all := fs.Bool("a", false, "print stat of main DB and all subDBs")
+	sub := fs.String("s", "", "print stat of only the specified named subDB")
+	if !parse(fs, args, 1) {
+		return 2
+	}
+	path := fs.Arg(0)
+
+	var stat envStat
+	err := view(path, func(env *mdbx.Env, tx *mdbx.Tx) error {
+		var info mdbx.EnvInfo
+		if err := tx.EnvInfo(&info); err != nil {
+			return err
+		}
+		stat = envStat{
+			Path:        path,
+			PageSize:    info.DXBPageSize,
+			Geometry:    geometryOf(&info),
+			MapSize:     info.MapSize,
+			LastPage:    info.LastPageNumber,
+			RecentTxnID: info.RecentTxnID,
+			MaxReaders:  info.MaxReaders,
+			NumReaders:  info.NumReaders,
+		}
+		names := []string{*sub}
+		if *all {
+			list, err := tx.DBINames()
+			if err != nil {
+				return err
+			}
+			names = append([]string{""}, list...)
+		}
+		for _, name := range names {
+			dbi, err := openDBI(tx, name)
+			if err != nil {
+				return err
+			}
+			var s mdbx.Stats
+			if err := tx.DBIStat(dbi, &s); err != nil {
+				return err
+			}
+			flags, _, e := tx.DBIFlags(dbi)
+			if e != nil {
+				return e
+			}
+			stat.DBIs = append(stat.DBIs, dbiStat{
+				Name:          name,
+				Flags:         flagNames(flags),
+				Depth:         s.Depth,
+				BranchPages:   s.BranchPages,
+				LeafPages:     s.LeafPages,
+				OverflowPages: s.OverflowPages,
+				Entries:       s.Entries,
+				ModTxnID:      s.ModTxnID,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if *asJSON {
+		return writeJSON(stdout, stat)
+	}
+
+	fmt.Fprintf(stdout, "Environment Info\n")
+	fmt.Fprintf(stdout, "  Pagesize: %d\n", stat.PageSize)
+	fmt.Fprintf(stdout, "  Dynamic datafile: %d..%d bytes (+%d/-%d), current %d\n",
+		stat.Geometry.Lower, stat.Geometry.Upper, stat.Geometry.Grow, stat.Geometry.Shrink, stat.Geometry.Current)
+	fmt.Fprintf(stdout, "  Current mapsize: %d bytes\n", stat.MapSize)
+	fmt.Fprintf(stdout, "  Number of pages used: %d\n", stat.LastPage+1)
+	fmt.Fprintf(stdout, "  Last transaction ID: %d\n", stat.RecentTxnID)
+	fmt.Fprintf(stdout, "  Max readers: %d\n", stat.MaxReaders)
+	fmt.Fprintf(stdout, "  Number of reader slots uses: %d\n", stat.NumReaders)
+	for _, d := range stat.DBIs {
+		name := d.Name
+		if name == "" {
+			name = "@MAIN"
+		}
+		fmt.Fprintf(stdout, "Status of %s\n", name)
+		if len(d.Flags) > 0 {
+			fmt.Fprintf(stdout, "  Flags: %s\n", strings.Join(d.Flags, ", "))
+		}
+		fmt.Fprintf(stdout, "  Tree depth: %d\n", d.Depth)
+		fmt.Fprintf(stdout, "  Branch pages: %d\n", d.BranchPages)
+		fmt.Fprintf(stdout, "  Leaf pages: %d\n", d.LeafPages)
+		fmt.Fprintf(stdout, "  Overflow pages: %d\n", d.OverflowPages)
+		fmt.Fprintf(stdout, "  Entries: %d\n", d.Entries)
+	}
+	return 0
+}
+
+func runCopy(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("copy", stderr)
+	compact := fs.Bool("compact", false, "omit free space and renumber pages")
+	dynamic := fs.Bool("force-dynamic", false, "force a dynamic size for the copy")
+	if !parse(fs, args, 2) {
+		return 2
+	}
+	env, err := openEnv(fs.Arg(0), true)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer env.Close(true)
+	flags := mdbx.CopyDefaults
+	if *compact {
+		flags |= mdbx.CopyCompact
+	}
+	if *dynamic {
+		flags |= mdbx.CopyForceDynamicSize
+	}
+	if err := env.Copy(fs.Arg(1), flags); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+type readerInfo struct {
+	Slot          int    `json:"slot"`
+	PID           int    `json:"pid"`
+	Thread        uint64 `json:"thread"`
+	TxnID         uint64 `json:"txn_id"`
+	Lag           uint64 `json:"lag"`
+	BytesUsed     uint64 `json:"bytes_used"`
+	BytesRetained uint64 `json:"bytes_retained"`
+}
+
+func runReaders(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("readers", stderr)
+	asJSON := fs.Bool("json", false, "write JSON")
+	check := fs.Bool("check", false, "clear stale entries first")
+	if !parse(fs, args, 1) {
+		return 2
+	}
+	env, err := openEnv(fs.Arg(0), !*check)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer env.Close(true)
+
+	cleared := 0
+	if *check {
+		if cleared, err = env.ReaderCheck(); err != nil {
+			return fail(stderr, err)
+		}
+	}
+	list, err := env.ReaderList()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	readers := make([]readerInfo, 0, len(list))
+	for _, r := range list {
+		readers = append(readers, readerInfo(r))
+	}
+	if *asJSON {
+		return writeJSON(stdout, struct {
+			Cleared int          `json:"cleared"`
+			Readers []readerInfo `json:"readers"`
+		}{cleared, readers})
+	}
+	if *check {
+		fmt.Fprintf(stdout, "%d stale readers cleared\n", cleared)
+	}
+	if len(readers) == 0 {
+		fmt.Fprintf(stdout, "(no active readers)\n")
+		return 0
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "slot\tpid\tthread\ttxnid\tlag\tused\tretained\t\n")
+	for _, r := range readers {
+		fmt.Fprintf(tw, "%d\t%d\t%#x\t%d\t%d\t%d\t%d\t\n",
+			r.Slot, r.PID, r.Thread, r.TxnID, r.Lag, r.BytesUsed, r.BytesRetained)
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+type record struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func runGet(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("get", stderr)
+	asJSON := fs.Bool("json", false, "write JSON")
+	isHex := fs.Bool("hex", false, "key is given and values are written in hex")
+	sub := fs.String("s", "", "named database, main by default")
+	if !parse(fs, args, 2) {
+		return 2
+	}
+	key, err := decodeArg(fs.Arg(1), *isHex)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	var values [][]byte
+	err = view(fs.Arg(0), func(env *mdbx.Env, tx *mdbx.Tx) error {
+		dbi, err := openDBI(tx, *sub)
+		if err != nil {
+			return err
+		}
+		cursor, e := tx.OpenCursor(dbi)
+		if e != nil {
+			return e
+		}
+		defer cursor.Close()
+		k, v := mdbx.Val{}, mdbx.Val{}
+		if len(key) > 0 {
+			k = mdbx.Bytes(&key)
+		}
+		flags, _, e := tx.DBIFlags(dbi)
+		if e != nil {
+			return e
+		}
+		// Duplicates are all returned for dupsort databases.
+		op := mdbx.CursorSetKey
+		for {
+			if e = cursor.Get(&k, &v, op); e != nil {
+				if e == mdbx.ErrNotFound {
+					if op == mdbx.CursorSetKey {
+						return fmt.Errorf("key %q not found", fs.Arg(1))
+					}
+					return nil
+				}
+				return e
+			}
+			values = append(values, append([]byte(nil), v.UnsafeBytes()...))
+			if flags&mdbx.DBDupSort == 0 {
+				return nil
+			}
+			op = mdbx.CursorNextDup
+		}
+	})
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if *asJSON {
+		records := make([]record, 0, len(values))
+		for _, v := range values {
+			records = append(records, record{formatBytes(key, *isHex), formatBytes(v, *isHex)})
+		}
+		return writeJSON(stdout, records)
+	}
+	for _, v := range values {
+		fmt.Fprintln(stdout, formatBytes(v, *isHex))
+	}
+	return 0
+}
+
+func runScan(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("scan", stderr)
+	asJSON := fs.Bool("json", false, "write JSON")
+	isHex := fs.Bool("hex", false, "prefix is given and records are written in hex")
+	sub := fs.String("s", "", "named database, main by default")
+	prefixArg := fs.String("prefix", "", "only keys starting with prefix")
+	limit := fs.Int("limit", 0, "maximum number of records, 0 for all")
+	if !parse(fs, args, 1) {
+		return 2
+	}
+	prefix, err := decodeArg(*prefixArg, *isHex)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	var records []record
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	err = view(fs.Arg(0), func(env *mdbx.Env, tx *mdbx.Tx) error {
+		dbi, err := openDBI(tx, *sub)
+		if err != nil {
+			return err
+		}
+		// Records of the main database that name databases are skipped.
+		var skip map[string]bool
+		if *sub == "" {
+			names, e := tx.DBINames()
+			if e != nil {
+				return e
+			}
+			skip = make(map[string]bool, len(names))
+			for _, name := range names {
+				skip[name] = true
+			}
+		}
+		cursor, e := tx.OpenCursor(dbi)
+		if e != nil {
+			return e
+		}
+		defer cursor.Close()
+
+		k, v := mdbx.Val{}, mdbx.Val{}
+		op := mdbx.CursorFirst
+		if len(prefix) > 0 {
+			k = mdbx.Bytes(&prefix)
+			op = mdbx.CursorSetRange
+		}
+		for n := 0; *limit == 0 || n < *limit; op = mdbx.CursorNext {
+			if e = cursor.Get(&k, &v, op); e != nil {
+				if e == mdbx.ErrNotFound {
+					return nil
+				}
+				return e
+			}
+			key := k.UnsafeBytes()
+			if !bytes.HasPrefix(key, prefix) {
+				return nil
+			}
+			if skip[string(key)] {
+				continue
+			}
+			r := record{formatBytes(key, *isHex), formatBytes(v.UnsafeBytes(), *isHex)}
+			if *asJSON {
+				records = append(records, r)
+			} else {
+				fmt.Fprintf(tw, "%s\t%s\n", r.Key, r.Value)
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if *asJSON {
+		if records == nil {
+			records = []record{}
+		}
+		return writeJSON(stdout, records)
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+func runDrop(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("drop", stderr)
+	del := fs.Bool("delete", false, "delete the database instead of emptying it")
+	sub := fs.String("s", "", "named database")
+	if !parse(fs, args, 1) {
+		return 2
+	}
+	if *sub == "" {
+		fmt.Fprintf(stderr, "mdbx drop: -s name is required\n")
+		return 2
+	}
+	err := update(fs.Arg(0), func(env *mdbx.Env, tx *mdbx.Tx) error {
+		dbi, err := openDBI(tx, *sub)
+		if err != nil {
+			return err
+		}
+		if e := tx.Drop(dbi, *del); e != nil {
+			return e
+		}
+		return nil
+	})
+	if err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+type sizeFlag struct {
+	value int64
+	set   bool
+}
+
+func (f *sizeFlag) String() string {
+	if !f.set {
+		return ""
+	}
+	return fmt.Sprint(f.value)
+}
+
+func (f *sizeFlag) Set(s string) error {
+	v, err := mdbx.ParseSize(s)
+	if err != nil {
+		return err
+	}
+	f.value, f.set = int64(v), true
+	return nil
+}
+
+// geometry returns the flag value or -1 to keep the current setting.
+func (f *sizeFlag) geometry() uintptr {
+	if !f.set {
+		return ^uintptr(0)
+	}
+	return uintptr(f.value)
+}
+
+func runGeometry(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("geometry", stderr)
+	asJSON := fs.Bool("json", false, "write JSON")
+	var lower, now, upper, growth, shrink sizeFlag
+	fs.Var(&lower, "lower", "lower bound of the datafile size")
+	fs.Var(&now, "now", "current datafile size")
+	fs.Var(&upper, "upper", "upper bound of the datafile size")
+	fs.Var(&growth, "growth", "growth step")
+	fs.Var(&shrink, "shrink", "shrink threshold")
+	if !parse(fs, args, 1) {
+		return 2
+	}
+	change := lower.set || now.set || upper.set || growth.set || shrink.set
+
+	env, err := openEnv(fs.Arg(0), !change)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	defer env.Close(false)
+	if change {
+		if err := env.SetGeometry(mdbx.Geometry{
+			SizeLower:       lower.geometry(),
+			SizeNow:         now.geometry(),
+			SizeUpper:       upper.geometry(),
+			GrowthStep:      growth.geometry(),
+			ShrinkThreshold: shrink.geometry(),
+			PageSize:        ^uintptr(0),
+		}); err != nil {
+			return fail(stderr, err)
+		}
+	}
+
+	var info mdbx.EnvInfo
+	tx := &mdbx.Tx{}
+	if err := env.Begin(tx, mdbx.TxReadOnly); err != nil {
+		return fail(stderr, err)
+	}
+	e := tx.EnvInfo(&info)
+	_ = tx.Abort()
+	if e != nil {
+		return fail(stderr, e)
+	}
+	geo := geometryOf(&info)
+	if *asJSON {
+		return writeJSON(stdout, struct {
+			geometryInfo
+			PageSize uint32 `json:"page_size"`
+		}{geo, info.DXBPageSize})
+	}
+	fmt.Fprintf(stdout, "lower=%d current=%d upper=%d growth=%d shrink=%d pagesize=%d\n",
+		geo.Lower, geo.Current, geo.Upper, geo.Grow, geo.Shrink, info.DXBPageSize)
+	return 0
+}
@@ -0,0 +1,232 @@
+// Command mdbx inspects and maintains MDBX databases using the mdbx-go package.
+//
+// usage: mdbx <command> [flags] dbpath [args]
+//
+//	chk        check database integrity (embedded mdbx_chk)
+//	stat       print environment and database statistics
+//	copy       copy an environment, optionally compacting it
+//	dump       export in mdbx_dump format (mdbx_dump flags)
+//	load       import mdbx_dump format (mdbx_load flags)
+//	readers    list the reader lock table
+//	get        print the value of a key
+//	scan       print records, optionally limited to a key prefix
+//	drop       empty or delete a named database
+//	geometry   print or change the database geometry
+//
+// Most commands accept -json to write machine readable output.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"unicode/utf8"
+
+	"github.com/moontrade/mdbx-go"
+)
+
+// maxDBs is the number of named databases a command can open.
+const maxDBs = 4096
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands []command
+
+// stdin is the input of the load command.
+var stdin io.Reader = os.Stdin
+
+func init() {
+	// The commands begin their transactions on the Env rather than with
+	// Store.View, keep main on one thread for them.
+	runtime.LockOSThread()
+
+	commands = []command{
+		{"chk", "[-json] [mdbx_chk flags] dbpath", runChk},
+		{"stat", "[-json] [-a|-s name] dbpath", runStat},
+		{"copy", "[-compact] [-force-dynamic] dbpath dest", runCopy},
+		{"dump", "[mdbx_dump flags] dbpath", runDump},
+		{"load", "[mdbx_load flags] dbpath", runLoad},
+		{"readers", "[-json] [-check] dbpath", runReaders},
+		{"get", "[-json] [-hex] [-s name] dbpath key", runGet},
+		{"scan", "[-json] [-hex] [-s name] [-prefix p] [-limit n] dbpath", runScan},
+		{"drop", "[-delete] -s name dbpath", runDrop},
+		{"geometry", "[-json] [-lower n] [-now n] [-upper n] [-growth n] [-shrink n] dbpath", runGeometry},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	if args[0] != "help" && args[0] != "-h" && args[0] != "-help" {
+		fmt.Fprintf(stderr, "mdbx: unknown command %q\n", args[0])
+	}
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: mdbx <command> [flags] dbpath [args]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.usage)
+	}
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("mdbx "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parse parses args and checks the number of positional arguments.
+func parse(fs *flag.FlagSet, args []string, nargs int) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return false
+	}
+	return true
+}
+
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "mdbx: %v\n", err)
+	return 1
+}
+
+func writeJSON(w io.Writer, v interface{}) int {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// envFlags returns EnvNoSubDir if path is a data file rather than a directory.
+func envFlags(path string) mdbx.EnvFlags {
+	if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+		return mdbx.EnvNoSubDir
+	}
+	return 0
+}
+
+// openEnv opens an existing environment.
+func openEnv(path string, readOnly bool) (*mdbx.Env, error) {
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err = env.SetMaxDBS(maxDBs); err != nil {
+		_ = env.Close(true)
+		return nil, err
+	}
+	flags := envFlags(path) | mdbx.EnvAccede
+	if readOnly {
+		flags |= mdbx.EnvReadOnly
+	}
+	if err = env.Open(path, flags, 0); err != nil {
+		_ = env.Close(true)
+		return nil, err
+	}
+	return env, nil
+}
+
+// view runs fn in a read transaction on a read-only environment.
+func view(path string, fn func(env *mdbx.Env, tx *mdbx.Tx) error) error {
+	env, err := openEnv(path, true)
+	if err != nil {
+		return err
+	}
+	defer env.Close(true)
+	tx := &mdbx.Tx{}
+	if err := env.Begin(tx, mdbx.TxReadOnly); err != nil {
+		return err
+	}
+	defer tx.Abort()
+	return fn(env, tx)
+}
+
+// update runs fn in a write transaction.
+func update(path string, fn func(env *mdbx.Env, tx *mdbx.Tx) error) error {
+	env, err := openEnv(path, false)
+	if err != nil {
+		return err
+	}
+	defer env.Close(false)
+	tx := &mdbx.Tx{}
+	if err := env.Begin(tx, mdbx.TxReadWrite); err != nil {
+		return err
+	}
+	if err := fn(env, tx); err != nil {
+		_ = tx.Abort()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func openDBI(tx *mdbx.Tx, name string) (mdbx.DBI, error) {
+	flags := mdbx.DBAccede
+	if name == "" {
+		flags = 0
+	}
+	dbi, err := tx.OpenDBI(name, flags)
+	if err != nil {
+		if err == mdbx.ErrNotFound {
+			return 0, fmt.Errorf("database %q not found", name)
+		}
+		return 0, err
+	}
+	return dbi, nil
+}
+
+// decodeArg decodes a key given on the command line.
+func decodeArg(s string, isHex bool) ([]byte, error) {
+	if isHex {
+		return hex.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// formatBytes returns b as text if it is printable UTF-8 and as hex otherwise
+// or when hex output was requested.
+func formatBytes(b []byte, isHex bool) string {
+	if isHex || !printable(b) {
+		return hex.EncodeToString(b)
+	}
+	return string(b)
+}
+
+func printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moontrade/mdbx-go"
+)
+
+func createTestDB(t *testing.T) string {
+	t.Helper()
+	path := t.TempDir()
+	store, err := mdbx.Open(path, mdbx.EnvSafeNoSync, 0664, func(env *mdbx.Env, create bool) error {
+		return env.SetMaxDBS(4)
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err = store.Update(func(tx *mdbx.Tx) error {
+		dbi, err := tx.OpenDBI("users", mdbx.DBCreate)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 10; i++ {
+			k, v := mdbx.StringConst(fmt.Sprintf("user/%d", i)), mdbx.StringConst(fmt.Sprintf("name %d", i))
+			if err = tx.Put(dbi, &k, &v, 0); err != nil {
+				return err
+			}
+		}
+		k, v := mdbx.StringConst("zzz"), mdbx.StringConst("\x00\x01")
+		if err = tx.Put(dbi, &k, &v, 0); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runTest(t *testing.T, want int, args ...string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != want {
+		t.Fatalf("mdbx %s: exit %d, want %d: %s", strings.Join(args, " "), code, want, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestCommands(t *testing.T) {
+	path := createTestDB(t)
+
+	var stat envStat
+	if err := json.Unmarshal([]byte(runTest(t, 0, "stat", "-json", "-a", path)), &stat); err != nil {
+		t.Fatal(err)
+	}
+	if len(stat.DBIs) != 2 || stat.DBIs[1].Name != "users" || stat.DBIs[1].Entries != 11 {
+		t.Fatalf("unexpected stat: %+v", stat)
+	}
+
+	if out := runTest(t, 0, "get", "-s", "users", path, "user/3"); out != "name 3\n" {
+		t.Fatalf("get: %q", out)
+	}
+	if out := runTest(t, 0, "get", "-s", "users", path, "zzz"); out != "0001\n" {
+		t.Fatalf("get binary: %q", out)
+	}
+	runTest(t, 1, "get", "-s", "users", path, "missing")
+
+	var records []record
+	if err := json.Unmarshal([]byte(runTest(t, 0, "scan", "-json", "-s", "users", "-prefix", "user/", "-limit", "3", path)), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0].Key != "user/0" || records[2].Value != "name 2" {
+		t.Fatalf("scan: %+v", records)
+	}
+	if out := runTest(t, 0, "scan", path); strings.Contains(out, "users") {
+		t.Fatalf("scan of main lists named databases: %q", out)
+	}
+
+	var readers struct {
+		Readers []readerInfo `json:"readers"`
+	}
+	if err := json.Unmarshal([]byte(runTest(t, 0, "readers", "-json", "-check", path)), &readers); err != nil {
+		t.Fatal(err)
+	}
+
+	var geo geometryInfo
+	if err := json.Unmarshal([]byte(runTest(t, 0, "geometry", "-json", "-upper", "64M", path)), &geo); err != nil {
+		t.Fatal(err)
+	}
+	if geo.Upper != 64<<20 {
+		t.Fatalf("geometry upper %d", geo.Upper)
+	}
+
+	copyPath := filepath.Join(t.TempDir(), "copy.dat")
+	runTest(t, 0, "copy", "-compact", path, copyPath)
+	if out := runTest(t, 0, "get", "-s", "users", copyPath, "user/9"); out != "name 9\n" {
+		t.Fatalf("get from copy: %q", out)
+	}
+
+	dump := runTest(t, 0, "dump", "-s", "users", path)
+	if !strings.Contains(dump, "database=users") {
+		t.Fatalf("dump: %q", dump)
+	}
+	loadPath := t.TempDir()
+	stdin = strings.NewReader(dump)
+	defer func() { stdin = os.Stdin }()
+	runTest(t, 0, "load", loadPath)
+	if out := runTest(t, 0, "get", "-s", "users", loadPath, "zzz"); out != "0001\n" {
+		t.Fatalf("get from load: %q", out)
+	}
+	runTest(t, 2, "dump", path, "extra")
+
+	runTest(t, 0, "drop", "-s", "users", path)
+	if out := runTest(t, 0, "scan", "-s", "users", path); out != "" {
+		t.Fatalf("scan after drop: %q", out)
+	}
+	runTest(t, 2, "nope")
+}
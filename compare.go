@@ -0,0 +1,82 @@
+package mdbx
+
+import (
+	"bytes"
+	"unsafe"
+)
+
+// KeyComparator returns the function mdbx orders the keys of a database
+// opened with flags by: bytes compared from the end for DBReverseKey, native
+// byte order integers for DBIntegerKey and bytes.Compare otherwise.
+func KeyComparator(flags DBFlags) func(a, b []byte) int {
+	switch {
+	case flags&DBReverseKey != 0:
+		return compareReverse
+	case flags&DBIntegerKey != 0:
+		return compareInteger
+	}
+	return bytes.Compare
+}
+
+// DataComparator returns the function mdbx orders the values of a key in a
+// DBDupSort database opened with flags by. Values of other databases are
+// unordered and compared with bytes.Compare.
+func DataComparator(flags DBFlags) func(a, b []byte) int {
+	switch {
+	case flags&DBDupSort == 0:
+	case flags&DBIntegerGroup != 0:
+		return compareInteger
+	case flags&DBReverseDup != 0:
+		return compareReverse
+	}
+	return bytes.Compare
+}
+
+// compareReverse compares a and b from their last bytes on. A value ordered
+// before another is a suffix of it.
+func compareReverse(a, b []byte) int {
+	i, j := len(a)-1, len(b)-1
+	for ; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if a[i] != b[j] {
+			if a[i] < b[j] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case i >= 0:
+		return 1
+	case j >= 0:
+		return -1
+	}
+	return 0
+}
+
+// compareInteger compares 4 and 8 byte unsigned integers in native byte
+// order. mdbx rejects integer keys of other sizes, they are ordered by length
+// and then by bytes.Compare.
+func compareInteger(a, b []byte) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	var x, y uint64
+	switch len(a) {
+	case 4:
+		x, y = uint64(*(*uint32)(unsafe.Pointer(&a[0]))), uint64(*(*uint32)(unsafe.Pointer(&b[0])))
+	case 8:
+		x, y = *(*uint64)(unsafe.Pointer(&a[0])), *(*uint64)(unsafe.Pointer(&b[0]))
+	default:
+		return bytes.Compare(a, b)
+	}
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
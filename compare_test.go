@@ -0,0 +1,77 @@
+package mdbx
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+func TestKeyComparator(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	rng := rand.New(rand.NewSource(1))
+	random := func(flags DBFlags, integer DBFlags) []byte {
+		if flags&integer != 0 {
+			b := make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, rng.Uint64()>>uint(rng.Intn(64)))
+			return b
+		}
+		b := make([]byte, rng.Intn(6))
+		for i := range b {
+			b[i] = byte('a' + rng.Intn(3))
+		}
+		return b
+	}
+
+	for _, flags := range []DBFlags{
+		DBDefaults,
+		DBReverseKey,
+		DBIntegerKey,
+		DBDupSort,
+		DBDupSort | DBReverseDup,
+		DBDupSort | DBDupFixed | DBIntegerGroup | DBIntegerKey,
+		DBDupSort | DBReverseKey | DBReverseDup,
+	} {
+		dbi := openTestDBI(t, store, "cmp"+flags.String(), flags)
+		keyCmp, dataCmp := KeyComparator(flags), DataComparator(flags)
+		if err := store.Update(func(tx *Tx) error {
+			for i := 0; i < 200; i++ {
+				k, v := sliceVal(random(flags, DBIntegerKey)), sliceVal(random(flags, DBIntegerGroup))
+				if err := tx.Put(dbi, &k, &v, 0); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.View(func(tx *Tx) error {
+			cursor, err := tx.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close()
+			var prevKey, prevData []byte
+			k, v := Val{}, Val{}
+			for err = cursor.Get(&k, &v, CursorFirst); err == nil; err = cursor.Get(&k, &v, CursorNext) {
+				key, data := k.Bytes(), v.Bytes()
+				if prevKey != nil {
+					c := keyCmp(prevKey, key)
+					if c == 0 && flags&DBDupSort != 0 {
+						c = dataCmp(prevData, data)
+					}
+					if c >= 0 {
+						t.Errorf("flags %v: %x=%x ordered before %x=%x", flags, prevKey, prevData, key, data)
+					}
+				}
+				prevKey, prevData = key, data
+			}
+			if err != ErrNotFound {
+				return err
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
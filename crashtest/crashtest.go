@@ -0,0 +1,443 @@
+// Package crashtest kills a writer process at random points of its write
+// transactions and checks the database it leaves behind.
+//
+// The writer is the test binary itself, started again with an environment
+// variable that makes Main run the writer instead of the tests:
+//
+//	func TestMain(m *testing.M) {
+//		crashtest.Main()
+//		os.Exit(m.Run())
+//	}
+//
+//	func TestCrash(t *testing.T) {
+//		reports, err := crashtest.Run(crashtest.Config{Dir: t.TempDir()})
+//		...
+//	}
+//
+// Every write transaction of the writer appends Batch records with
+// consecutive sequence numbers and stores the next sequence number in the
+// Canary, then reports it to the parent once Update returned. After the
+// writer was killed the parent checks the datafile with mdbx_chk, which runs
+// in another child process as it can only run once per process, reopens the
+// database and verifies that the records match the Canary and that no reported
+// transaction was lost.
+//
+// Killing a process only models a process crash: pages written by the
+// writer remain in the page cache of the kernel and reach the disk later.
+// On Linux every sync mode comes out without lost or inconsistent
+// transactions. EnvSyncDurable leaves a steady database. EnvSafeNoSync and
+// EnvUtterlyNoSync may leave the last transactions unsteady: a read-only open
+// fails with ErrWannaRecovery until the database was opened read-write once,
+// which Report.Recovered counts. The modes differ further in what survives a
+// system crash or power loss, which this package can not simulate:
+// EnvSyncDurable loses nothing, EnvSafeNoSync may lose the most recent
+// transactions but keeps the database consistent, and EnvUtterlyNoSync may
+// leave it corrupted.
+package crashtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/moontrade/mdbx-go"
+)
+
+// EnvVar holds the configuration of the writer in the child process.
+const EnvVar = "MDBX_CRASHTEST"
+
+// DBIName is the database the writer appends records to.
+const DBIName = "crashtest"
+
+// canaryMagic marks the Canary written by the writer.
+const canaryMagic = 0x6372617368746573
+
+// Config configures Run.
+type Config struct {
+	// Dir receives one database per sync mode. Required.
+	Dir string
+
+	// Modes are the sync modes to test. Defaults to EnvSyncDurable,
+	// EnvSafeNoSync and EnvUtterlyNoSync.
+	Modes []mdbx.EnvFlags
+
+	// Rounds is the number of times the writer is killed per mode.
+	// Defaults to 10.
+	Rounds int
+
+	// MinDelay and MaxDelay bound how long the writer runs before it is
+	// killed. Default to 5ms and 50ms.
+	MinDelay, MaxDelay time.Duration
+
+	// Batch is the number of records written per transaction. Defaults to 16.
+	Batch int
+
+	// ValueSize is the size of every record. Defaults to 256.
+	ValueSize int
+
+	// Seed seeds the kill delays. Zero uses the current time.
+	Seed int64
+}
+
+func (c *Config) setDefaults() {
+	if len(c.Modes) == 0 {
+		c.Modes = []mdbx.EnvFlags{mdbx.EnvSyncDurable, mdbx.EnvSafeNoSync, mdbx.EnvUtterlyNoSync}
+	}
+	if c.Rounds == 0 {
+		c.Rounds = 10
+	}
+	if c.MinDelay == 0 {
+		c.MinDelay = 5 * time.Millisecond
+	}
+	if c.MaxDelay < c.MinDelay {
+		c.MaxDelay = c.MinDelay + 45*time.Millisecond
+	}
+	if c.Batch == 0 {
+		c.Batch = 16
+	}
+	if c.ValueSize < 8 {
+		c.ValueSize = 256
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+}
+
+// Report summarizes the rounds run for one sync mode.
+type Report struct {
+	Flags        mdbx.EnvFlags
+	Rounds       int
+	Acked        uint64   // Sequence number last reported by the writer
+	Committed    uint64   // Sequence number found in the Canary
+	Recovered    int      // Rounds after which mdbx_chk passed only in read-write mode
+	Corrupted    int      // Rounds after which mdbx_chk or reopening failed
+	Inconsistent int      // Rounds after which the records did not match the Canary
+	Lost         int      // Rounds after which reported transactions were missing
+	Failures     []string // Description of every failed round
+}
+
+// OK reports whether every round left an intact database.
+func (r *Report) OK() bool {
+	return r.Corrupted == 0 && r.Inconsistent == 0 && r.Lost == 0
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("%s: %d rounds, committed %d, acked %d, recovered %d, corrupted %d, inconsistent %d, lost %d",
+		ModeName(r.Flags), r.Rounds, r.Committed, r.Acked, r.Recovered, r.Corrupted, r.Inconsistent, r.Lost)
+}
+
+// ModeName returns a short name for the sync mode of flags.
+func ModeName(flags mdbx.EnvFlags) string {
+	switch {
+	case flags&mdbx.EnvUtterlyNoSync == mdbx.EnvUtterlyNoSync:
+		return "utterly_nosync"
+	case flags&mdbx.EnvSafeNoSync != 0:
+		return "safe_nosync"
+	case flags&mdbx.EnvNoMetaSync != 0:
+		return "nometasync"
+	}
+	return "durable"
+}
+
+type childConfig struct {
+	Path      string        `json:"path"`
+	Flags     mdbx.EnvFlags `json:"flags"`
+	Batch     int           `json:"batch"`
+	ValueSize int           `json:"value_size"`
+	Check     []string      `json:"check,omitempty"`
+}
+
+// Main runs the writer or mdbx_chk and exits if the process was started by
+// Run. It returns at once otherwise. Call it first thing in TestMain.
+func Main() {
+	config := os.Getenv(EnvVar)
+	if config == "" {
+		return
+	}
+	var cfg childConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "crashtest:", err)
+		os.Exit(2)
+	}
+	if cfg.Check != nil {
+		mdbx.ChkMain(append(cfg.Check, filepath.Join(cfg.Path, mdbx.DataFileName))...)
+	}
+	if err := write(cfg, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "crashtest:", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Run kills a writer cfg.Rounds times for each sync mode and returns a
+// report per mode. The error is only set if the harness itself failed.
+func Run(cfg Config) ([]Report, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("crashtest: Dir is required")
+	}
+	cfg.setDefaults()
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	reports := make([]Report, 0, len(cfg.Modes))
+	for _, flags := range cfg.Modes {
+		report := Report{Flags: flags}
+		child := childConfig{
+			Path:      filepath.Join(cfg.Dir, ModeName(flags)),
+			Flags:     flags,
+			Batch:     cfg.Batch,
+			ValueSize: cfg.ValueSize,
+		}
+		if err = os.MkdirAll(child.Path, 0755); err != nil {
+			return reports, err
+		}
+		for round := 1; round <= cfg.Rounds; round++ {
+			delay := cfg.MinDelay + time.Duration(rnd.Int63n(int64(cfg.MaxDelay-cfg.MinDelay)+1))
+			acked, err := kill(exe, child, delay)
+			if err != nil {
+				return reports, fmt.Errorf("crashtest: %s round %d: %w", ModeName(flags), round, err)
+			}
+			report.Rounds++
+			report.Acked = acked
+			report.check(exe, round, child, acked)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// kill starts a writer, kills it delay after it opened the database and
+// returns the last sequence number it reported.
+func kill(exe string, cfg childConfig, delay time.Duration) (uint64, error) {
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		return 0, err
+	}
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), EnvVar+"="+string(config))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err = cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	acks := make(chan uint64, 1)
+	done := make(chan error, 1)
+	go func() {
+		var b [8]byte
+		for {
+			if _, err := io.ReadFull(out, b[:]); err != nil {
+				done <- err
+				return
+			}
+			select {
+			case <-acks:
+			default:
+			}
+			acks <- binary.BigEndian.Uint64(b[:])
+		}
+	}()
+
+	// The first report is sent once the database is open.
+	var acked uint64
+	select {
+	case acked = <-acks:
+	case err = <-done:
+		_ = cmd.Wait()
+		return 0, fmt.Errorf("writer exited: %v: %s", err, stderr.Bytes())
+	}
+	time.Sleep(delay)
+	if err = cmd.Process.Kill(); err != nil {
+		return 0, err
+	}
+	<-done
+	if err = cmd.Wait(); err == nil || len(stderr.Bytes()) > 0 {
+		return 0, fmt.Errorf("writer exited before it was killed: %v: %s", err, stderr.Bytes())
+	}
+	select {
+	case acked = <-acks:
+	default:
+	}
+	return acked, nil
+}
+
+// check verifies the database left behind by a killed writer.
+func (r *Report) check(exe string, round int, cfg childConfig, acked uint64) {
+	fail := func(count *int, format string, args ...interface{}) {
+		*count++
+		r.Failures = append(r.Failures, fmt.Sprintf("round %d: "+format, append([]interface{}{round}, args...)...))
+	}
+
+	// A database left unsteady needs a read-write open to be recovered.
+	result, output, err := chk(exe, cfg, "-v")
+	if err == nil && result != 0 {
+		if result, output, err = chk(exe, cfg, "-v", "-w"); err == nil && result == 0 {
+			r.Recovered++
+		}
+	}
+	if err != nil || result != 0 {
+		fail(&r.Corrupted, "chk returned %d, %v: %s", result, err, output)
+		return
+	}
+	store, err := open(cfg)
+	if err != nil {
+		fail(&r.Corrupted, "reopen: %v", err)
+		return
+	}
+	defer store.Close()
+
+	var committed, count uint64
+	err = store.View(func(tx *mdbx.Tx) error {
+		var canary mdbx.Canary
+		if err := tx.GetCanary(&canary); err != nil {
+			return err
+		}
+		if canary.X != canaryMagic && (canary.X != 0 || canary.Y != 0) {
+			return fmt.Errorf("unexpected canary %+v", canary)
+		}
+		committed = canary.Y
+		dbi, err := tx.OpenDBI(DBIName, 0)
+		if err != nil {
+			return err
+		}
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		key, data := mdbx.Val{}, mdbx.Val{}
+		for err = cursor.Get(&key, &data, mdbx.CursorFirst); err == nil; err = cursor.Get(&key, &data, mdbx.CursorNext) {
+			if key.Len != 8 || binary.BigEndian.Uint64(key.UnsafeBytes()) != count {
+				return fmt.Errorf("record %d has key %x", count, key.UnsafeBytes())
+			}
+			if !bytes.Equal(data.UnsafeBytes(), value(count, cfg.ValueSize)) {
+				return fmt.Errorf("record %d has a wrong value", count)
+			}
+			count++
+		}
+		if err != mdbx.ErrNotFound {
+			return err
+		}
+		return nil
+	})
+	switch {
+	case err != nil:
+		fail(&r.Inconsistent, "%v", err)
+	case count != committed:
+		fail(&r.Inconsistent, "%d records but canary at %d", count, committed)
+	case committed < acked:
+		fail(&r.Lost, "canary at %d but writer reported %d", committed, acked)
+	case committed > acked+uint64(cfg.Batch):
+		fail(&r.Inconsistent, "canary at %d beyond the next transaction after %d", committed, acked)
+	}
+	r.Committed = committed
+}
+
+// chk runs mdbx_chk with args on the database of cfg in a child process
+// and returns its exit code and output.
+func chk(exe string, cfg childConfig, args ...string) (int, []byte, error) {
+	cfg.Check = args
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		return 0, nil, err
+	}
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), EnvVar+"="+string(config))
+	output, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), output, nil
+	}
+	return 0, output, err
+}
+
+func open(cfg childConfig) (*mdbx.Store, error) {
+	return mdbx.Open(cfg.Path, cfg.Flags, 0664, func(env *mdbx.Env, create bool) error {
+		if err := env.SetGeometry(mdbx.Geometry{
+			SizeLower:       1 << 20,
+			SizeNow:         1 << 20,
+			SizeUpper:       1 << 30,
+			GrowthStep:      4 << 20,
+			ShrinkThreshold: ^uintptr(0),
+			PageSize:        ^uintptr(0),
+		}); err != nil {
+			return err
+		}
+		return env.SetMaxDBS(2)
+	}, func(store *mdbx.Store, create bool) error {
+		return store.Update(func(tx *mdbx.Tx) error {
+			_, err := tx.OpenDBI(DBIName, mdbx.DBCreate)
+			return err
+		})
+	})
+}
+
+// value returns the deterministic value of record seq.
+func value(seq uint64, size int) []byte {
+	b := make([]byte, size)
+	for i := 0; i+8 <= size; i += 8 {
+		binary.BigEndian.PutUint64(b[i:], seq*uint64(i+1))
+	}
+	return b
+}
+
+// write appends records until the process is killed. The sequence number
+// is reported after every commit.
+func write(cfg childConfig, w io.Writer) error {
+	store, err := open(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var dbi mdbx.DBI
+	var next uint64
+	if err = store.View(func(tx *mdbx.Tx) error {
+		var canary mdbx.Canary
+		if err := tx.GetCanary(&canary); err != nil {
+			return err
+		}
+		next = canary.Y
+		dbi, err = tx.OpenDBI(DBIName, 0)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	var ack [8]byte
+	for {
+		binary.BigEndian.PutUint64(ack[:], next)
+		if _, err = w.Write(ack[:]); err != nil {
+			return err
+		}
+		if err = store.Update(func(tx *mdbx.Tx) error {
+			seq := make([]byte, 8)
+			for i := uint64(0); i < uint64(cfg.Batch); i++ {
+				binary.BigEndian.PutUint64(seq, next+i)
+				data := value(next+i, cfg.ValueSize)
+				k, v := mdbx.Bytes(&seq), mdbx.Bytes(&data)
+				if err := tx.Put(dbi, &k, &v, mdbx.PutAppend); err != nil {
+					return err
+				}
+			}
+			return tx.PutCanary(&mdbx.Canary{X: canaryMagic, Y: next + uint64(cfg.Batch)})
+		}); err != nil {
+			return err
+		}
+		next += uint64(cfg.Batch)
+	}
+}
@@ -0,0 +1,33 @@
+package crashtest
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	Main()
+	os.Exit(m.Run())
+}
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("kills child processes")
+	}
+	reports, err := Run(Config{Dir: t.TempDir(), Rounds: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("got %d reports", len(reports))
+	}
+	for _, r := range reports {
+		t.Log(r.String())
+		if !r.OK() {
+			t.Errorf("%s failed: %v", ModeName(r.Flags), r.Failures)
+		}
+		if r.Committed == 0 {
+			t.Errorf("%s: the writer committed nothing", ModeName(r.Flags))
+		}
+	}
+}
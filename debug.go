@@ -0,0 +1,250 @@
+//go:build mdbxdebug && (linux || darwin)
+
+package mdbx
+
+import (
+	"fmt"
+	"hash/crc32"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// debugViews guards the unsafe view checks. Without the mdbxdebug build tag
+// it is false and the checks are compiled out.
+const debugViews = true
+
+const (
+	viewChunkSize  = 64 << 10
+	viewQuarantine = 1024
+)
+
+// ViewError reports an unsafe view that was written to. It is only available
+// with the mdbxdebug build tag.
+//
+// With the tag, Val.UnsafeBytes and Val.UnsafeString of a value handed out
+// by a get or a cursor return a copy of it in memory owned by the
+// transaction. When the transaction is committed, aborted or reset the
+// copies are checked and made inaccessible, so that using a view of an ended
+// transaction faults at the offending access instead of reading whatever
+// the memory map holds by then. The runtime reports the fault as
+// "unexpected fault address" with the stack of the access, or panics with a
+// runtime.Error if debug.SetPanicOnFault is set, and ViewOrigin returns
+// where the view was handed out. A view that was written to is reported by
+// panicking with a *ViewError when its transaction ends.
+//
+// Values returned for PutReserve are not copied, the caller is meant to
+// write to them. Views of ended transactions stay inaccessible until
+// viewQuarantine more chunks of views were released.
+type ViewError struct {
+	Addr  uintptr
+	Len   int
+	Stack string
+}
+
+func (e *ViewError) Error() string {
+	return fmt.Sprintf("mdbx: unsafe view %#x of %d bytes was written to, it was returned by:\n%s", e.Addr, e.Len, e.Stack)
+}
+
+// ViewOrigin returns the stack of the UnsafeBytes or UnsafeString call that
+// returned the view containing addr, e.g. the address of a fault reported by
+// the runtime.
+func ViewOrigin(addr uintptr) (stack string, ok bool) {
+	views.mu.Lock()
+	defer views.mu.Unlock()
+	find := func(chunks []*viewChunk) *view {
+		for _, c := range chunks {
+			base := uintptr(unsafe.Pointer(&c.mem[0]))
+			if addr < base || addr >= base+uintptr(len(c.mem)) {
+				continue
+			}
+			for _, v := range c.views {
+				start := uintptr(unsafe.Pointer(&v.b[0]))
+				if addr >= start && addr < start+uintptr(len(v.b)) {
+					return v
+				}
+			}
+		}
+		return nil
+	}
+	for _, vt := range views.txns {
+		if v := find(vt.chunks); v != nil {
+			return v.stack(), true
+		}
+	}
+	if v := find(views.quarantine); v != nil {
+		return v.stack(), true
+	}
+	return "", false
+}
+
+type view struct {
+	b   []byte
+	sum uint32
+	pcs []uintptr
+}
+
+func (v *view) stack() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(v.pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// viewChunk is anonymous memory views are copied to. Released chunks are
+// protected and kept in quarantine before they are reused.
+type viewChunk struct {
+	mem   []byte
+	used  int
+	views []*view
+}
+
+// viewTxn holds the values a transaction handed out and the views of them.
+type viewTxn struct {
+	issued []uintptr
+	chunks []*viewChunk
+}
+
+var views struct {
+	issued     map[uintptr]uintptr
+	txns       map[uintptr]*viewTxn
+	quarantine []*viewChunk
+	free       []*viewChunk
+	mu         sync.Mutex
+}
+
+// debugIssue records that v was handed out by txn. prev is the base of v
+// before the call, v is not recorded if the call left it unchanged.
+func debugIssue(txn unsafe.Pointer, v *Val, prev *byte) {
+	if v == nil || v.Base == nil || v.Base == prev || v.Len == 0 {
+		return
+	}
+	views.mu.Lock()
+	defer views.mu.Unlock()
+	if views.issued == nil {
+		views.issued = make(map[uintptr]uintptr)
+		views.txns = make(map[uintptr]*viewTxn)
+	}
+	vt := views.txns[uintptr(txn)]
+	if vt == nil {
+		vt = &viewTxn{}
+		views.txns[uintptr(txn)] = vt
+	}
+	base := uintptr(unsafe.Pointer(v.Base))
+	views.issued[base] = uintptr(txn)
+	vt.issued = append(vt.issued, base)
+}
+
+// debugWritable forgets v, a value the caller is meant to write to.
+func debugWritable(v *Val) {
+	if v == nil || v.Base == nil {
+		return
+	}
+	views.mu.Lock()
+	delete(views.issued, uintptr(unsafe.Pointer(v.Base)))
+	views.mu.Unlock()
+}
+
+// debugView returns a view of v if it was handed out by a transaction.
+func debugView(v *Val) ([]byte, bool) {
+	if v.Base == nil || v.Len == 0 {
+		return nil, false
+	}
+	views.mu.Lock()
+	defer views.mu.Unlock()
+	txn, ok := views.issued[uintptr(unsafe.Pointer(v.Base))]
+	if !ok {
+		return nil, false
+	}
+	vt := views.txns[txn]
+	n := int(v.Len)
+	var chunk *viewChunk
+	if len(vt.chunks) > 0 {
+		chunk = vt.chunks[len(vt.chunks)-1]
+	}
+	if chunk == nil || len(chunk.mem)-chunk.used < n {
+		chunk = allocViewChunk(n)
+		vt.chunks = append(vt.chunks, chunk)
+	}
+	b := chunk.mem[chunk.used : chunk.used+n : chunk.used+n]
+	chunk.used += (n + 7) &^ 7
+	if chunk.used > len(chunk.mem) {
+		chunk.used = len(chunk.mem)
+	}
+	copy(b, unsafe.Slice(v.Base, n))
+	pcs := make([]uintptr, 32)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	chunk.views = append(chunk.views, &view{b: b, sum: crc32.ChecksumIEEE(b), pcs: pcs})
+	return b, true
+}
+
+func allocViewChunk(n int) *viewChunk {
+	if n <= viewChunkSize && len(views.free) > 0 {
+		chunk := views.free[len(views.free)-1]
+		views.free = views.free[:len(views.free)-1]
+		return chunk
+	}
+	size := viewChunkSize
+	if n > size {
+		page := syscall.Getpagesize()
+		size = (n + page - 1) / page * page
+	}
+	mem, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		panic(fmt.Sprintf("mdbx: mapping %d bytes for unsafe views: %v", size, err))
+	}
+	return &viewChunk{mem: mem}
+}
+
+// debugEnd checks and protects the views of txn, which ended.
+func debugEnd(txn unsafe.Pointer) {
+	views.mu.Lock()
+	vt := views.txns[uintptr(txn)]
+	if vt == nil {
+		views.mu.Unlock()
+		return
+	}
+	delete(views.txns, uintptr(txn))
+	for _, base := range vt.issued {
+		if views.issued[base] == uintptr(txn) {
+			delete(views.issued, base)
+		}
+	}
+	var written *ViewError
+	for _, chunk := range vt.chunks {
+		for _, v := range chunk.views {
+			if written == nil && crc32.ChecksumIEEE(v.b) != v.sum {
+				written = &ViewError{Addr: uintptr(unsafe.Pointer(&v.b[0])), Len: len(v.b), Stack: v.stack()}
+			}
+		}
+		_ = syscall.Madvise(chunk.mem, syscall.MADV_DONTNEED)
+		if err := syscall.Mprotect(chunk.mem, syscall.PROT_NONE); err != nil {
+			panic(fmt.Sprintf("mdbx: protecting unsafe views: %v", err))
+		}
+		views.quarantine = append(views.quarantine, chunk)
+	}
+	for len(views.quarantine) > viewQuarantine {
+		chunk := views.quarantine[0]
+		views.quarantine = views.quarantine[1:]
+		if len(chunk.mem) != viewChunkSize {
+			_ = syscall.Munmap(chunk.mem)
+			continue
+		}
+		if err := syscall.Mprotect(chunk.mem, syscall.PROT_READ|syscall.PROT_WRITE); err != nil {
+			panic(fmt.Sprintf("mdbx: unprotecting unsafe views: %v", err))
+		}
+		chunk.used, chunk.views = 0, nil
+		views.free = append(views.free, chunk)
+	}
+	views.mu.Unlock()
+	if written != nil {
+		panic(written)
+	}
+}
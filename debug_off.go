@@ -0,0 +1,19 @@
+//go:build !mdbxdebug || !(linux || darwin)
+
+package mdbx
+
+import "unsafe"
+
+// debugViews guards the unsafe view checks, which are only compiled in with
+// the mdbxdebug build tag on Linux and macOS.
+const debugViews = false
+
+func debugIssue(txn unsafe.Pointer, v *Val, prev *byte) {}
+
+func debugWritable(v *Val) {}
+
+func debugView(v *Val) ([]byte, bool) {
+	return nil, false
+}
+
+func debugEnd(txn unsafe.Pointer) {}
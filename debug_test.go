@@ -0,0 +1,102 @@
+//go:build mdbxdebug && (linux || darwin)
+
+package mdbx
+
+import (
+	"errors"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+var viewSink byte
+
+func TestViews_Poisoned(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "views", DBDefaults)
+	if err := store.Update(func(tx *Tx) error {
+		key, data := StringConst("key"), StringConst("value")
+		return tx.Put(dbi, &key, &data, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var view []byte
+	if err := store.View(func(tx *Tx) error {
+		key, data := StringConst("key"), Val{}
+		if err := tx.Get(dbi, &key, &data); err != nil {
+			return err
+		}
+		view = data.UnsafeBytes()
+		if string(view) != "value" || data.UnsafeString() != "value" {
+			t.Fatalf("got %q, want value", view)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	func() {
+		defer func() {
+			r := recover()
+			fault, ok := r.(interface{ Addr() uintptr })
+			if !ok {
+				t.Fatalf("expected fault, got %v", r)
+			}
+			stack, ok := ViewOrigin(fault.Addr())
+			if !ok || !strings.Contains(stack, "TestViews_Poisoned") {
+				t.Fatalf("origin of %#x: %q", fault.Addr(), stack)
+			}
+		}()
+		viewSink = view[0]
+	}()
+}
+
+func TestViews_Written(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "views", DBDefaults)
+	err := store.Update(func(tx *Tx) error {
+		key, data := StringConst("key"), StringConst("value")
+		if err := tx.Put(dbi, &key, &data, 0); err != nil {
+			return err
+		}
+		data = Val{}
+		if err := tx.Get(dbi, &key, &data); err != nil {
+			return err
+		}
+		data.UnsafeBytes()[0] = 'V'
+		return nil
+	})
+	var viewErr *ViewError
+	if !errors.As(err, &viewErr) || viewErr.Len != len("value") {
+		t.Fatalf("expected ViewError, got %v", err)
+	}
+}
+
+func TestViews_Reserve(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "views", DBDefaults)
+	if err := store.Update(func(tx *Tx) error {
+		key, data := StringConst("key"), Val{Len: 5}
+		if err := tx.Put(dbi, &key, &data, PutReserve); err != nil {
+			return err
+		}
+		copy(data.UnsafeBytes(), "value")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.View(func(tx *Tx) error {
+		key, data := StringConst("key"), Val{}
+		if err := tx.Get(dbi, &key, &data); err != nil {
+			return err
+		}
+		if got := data.String(); got != "value" {
+			t.Fatalf("got %q, want value", got)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
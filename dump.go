@@ -0,0 +1,711 @@
+package mdbx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// DumpFormat selects how keys and values are written by DumpEx.
+type DumpFormat int
+
+const (
+	// DumpByteValue writes every byte as two hex digits (mdbx_dump default).
+	DumpByteValue DumpFormat = iota
+
+	// DumpPrint writes printable characters as is and escapes the rest as
+	// a backslash followed by two hex digits (mdbx_dump -p).
+	DumpPrint
+)
+
+var ErrDumpFormat = errors.New("mdbx: invalid dump format")
+
+const (
+	hexDigits = "0123456789abcdef"
+
+	// dumpMaxDBs is the number of named databases the utilities can open.
+	dumpMaxDBs = 4096
+)
+
+var dbFlagNames = []struct {
+	flag DBFlags
+	name string
+}{
+	{DBReverseKey, "reversekey"},
+	{DBDupSort, "dupsort"},
+	{DBIntegerKey, "integerkey"},
+	{DBDupFixed, "dupfixed"},
+	{DBIntegerGroup, "integerdup"},
+	{DBReverseDup, "reversedup"},
+}
+
+// Dump writes the named databases to w in the mdbx_dump text format using
+// hex byte values. With no names the main database is dumped. Records of the
+// main database that hold named databases are skipped.
+func Dump(tx *Tx, w io.Writer, dbiNames ...string) error {
+	return DumpEx(tx, w, DumpByteValue, dbiNames...)
+}
+
+// DumpEx is Dump with a choice of value encoding.
+func DumpEx(tx *Tx, w io.Writer, format DumpFormat, dbiNames ...string) error {
+	bw := bufio.NewWriterSize(w, 256*1024)
+	if len(dbiNames) == 0 {
+		dbiNames = []string{""}
+	}
+	for _, name := range dbiNames {
+		if err := dumpDBI(tx, bw, format, name); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// mainDBISkip returns the names of the named databases, which are stored as
+// records of the main database, and whether the main database holds any
+// other records.
+func mainDBISkip(tx *Tx) (map[string]bool, bool, error) {
+	names, err := tx.DBINames()
+	if err != nil {
+		return nil, false, err
+	}
+	skip := make(map[string]bool, len(names))
+	for _, name := range names {
+		skip[name] = true
+	}
+	main, err := tx.OpenDBI("", 0)
+	if err != nil {
+		return nil, false, err
+	}
+	var stat Stats
+	if err = tx.DBIStat(main, &stat); err != nil {
+		return nil, false, err
+	}
+	return skip, stat.Entries > uint64(len(names)), nil
+}
+
+func dumpDBI(tx *Tx, w *bufio.Writer, format DumpFormat, name string) error {
+	var (
+		dbi  DBI
+		err  error
+		skip map[string]bool
+	)
+	if name == "" {
+		if dbi, err = tx.OpenDBI("", 0); err != nil {
+			return err
+		}
+		var e error
+		if skip, _, e = mainDBISkip(tx); e != nil {
+			return e
+		}
+	} else if dbi, err = tx.OpenDBI(name, DBAccede); err != nil {
+		return err
+	}
+	flags, _, err := tx.DBIFlags(dbi)
+	if err != nil {
+		return err
+	}
+	var stat Stats
+	if err = tx.DBIStat(dbi, &stat); err != nil {
+		return err
+	}
+	var info EnvInfo
+	if err = tx.EnvInfo(&info); err != nil {
+		return err
+	}
+	var canary Canary
+	if err = tx.GetCanary(&canary); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "VERSION=3\n")
+	fmt.Fprintf(w, "geometry=l%d,c%d,u%d,s%d,g%d\n",
+		info.Geo.Lower, info.Geo.Current, info.Geo.Upper, info.Geo.Shrink, info.Geo.Grow)
+	if format == DumpPrint {
+		fmt.Fprintf(w, "format=print\n")
+	} else {
+		fmt.Fprintf(w, "format=bytevalue\n")
+	}
+	if name != "" {
+		fmt.Fprintf(w, "database=%s\n", name)
+	}
+	fmt.Fprintf(w, "type=btree\n")
+	fmt.Fprintf(w, "db_pagesize=%d\n", stat.PageSize)
+	fmt.Fprintf(w, "maxreaders=%d\n", info.MaxReaders)
+	for _, f := range dbFlagNames {
+		if flags&f.flag != 0 {
+			fmt.Fprintf(w, "%s=1\n", f.name)
+		}
+	}
+	if canary.V != 0 {
+		fmt.Fprintf(w, "canary=v%d,x%d,y%d,z%d\n", canary.V, canary.X, canary.Y, canary.Z)
+	}
+	fmt.Fprintf(w, "HEADER=END\n")
+
+	cursor, err := tx.OpenCursor(dbi)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	key, data := Val{}, Val{}
+	for {
+		if err = cursor.Get(&key, &data, CursorNext); err != nil {
+			if err == ErrNotFound {
+				break
+			}
+			return err
+		}
+		if skip != nil && skip[key.UnsafeString()] {
+			continue
+		}
+		dumpValue(w, format, key.UnsafeBytes())
+		dumpValue(w, format, data.UnsafeBytes())
+	}
+	_, e := w.WriteString("DATA=END\n")
+	return e
+}
+
+func dumpValue(w *bufio.Writer, format DumpFormat, b []byte) {
+	_ = w.WriteByte(' ')
+	for _, c := range b {
+		if format == DumpPrint && c >= 0x20 && c < 0x7f {
+			// mdbx_dump doubles the backslash, the escape character.
+			if c == '\\' {
+				_ = w.WriteByte('\\')
+			}
+			_ = w.WriteByte(c)
+			continue
+		}
+		if format == DumpPrint {
+			_ = w.WriteByte('\\')
+		}
+		_ = w.WriteByte(hexDigits[c>>4])
+		_ = w.WriteByte(hexDigits[c&15])
+	}
+	_ = w.WriteByte('\n')
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+// Load
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// LoadOptions controls how LoadEx writes records.
+type LoadOptions struct {
+	// DBIName is used for sections without a database header (mdbx_load -s).
+	DBIName string
+
+	// NoOverwrite keeps existing keys instead of replacing them (mdbx_load -N).
+	NoOverwrite bool
+
+	// Append uses PutAppend, requiring the input to be in database order (mdbx_load -a).
+	Append bool
+
+	// BatchBytes bounds the size of each write transaction. Defaults to 64MB.
+	BatchBytes int
+}
+
+// dumpHeader is a parsed mdbx_dump section header.
+type dumpHeader struct {
+	print    bool
+	name     string
+	hasName  bool
+	flags    DBFlags
+	geometry *Geometry
+	pageSize int
+	canary   *Canary
+}
+
+type dumpReader struct {
+	r       *bufio.Reader
+	line    int
+	pending *dumpHeader
+}
+
+func newDumpReader(r io.Reader) *dumpReader {
+	return &dumpReader{r: bufio.NewReaderSize(r, 256*1024)}
+}
+
+func (dr *dumpReader) readLine() ([]byte, error) {
+	line, err := dr.r.ReadBytes('\n')
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	dr.line++
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+func (dr *dumpReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrDumpFormat, dr.line, fmt.Sprintf(format, args...))
+}
+
+// readHeader returns io.EOF when there are no more sections.
+func (dr *dumpReader) readHeader() (*dumpHeader, error) {
+	if dr.pending != nil {
+		h := dr.pending
+		dr.pending = nil
+		return h, nil
+	}
+	h := &dumpHeader{}
+	for first := true; ; first = false {
+		line, err := dr.readLine()
+		if err != nil {
+			if err == io.EOF && !first {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		s := string(line)
+		if s == "HEADER=END" {
+			return h, nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return nil, dr.errorf("unexpected %q", s)
+		}
+		key, value := s[:eq], s[eq+1:]
+		switch key {
+		case "VERSION":
+			if value != "3" {
+				return nil, dr.errorf("unsupported version %s", value)
+			}
+		case "format":
+			switch value {
+			case "print":
+				h.print = true
+			case "bytevalue":
+			default:
+				return nil, dr.errorf("unsupported format %s", value)
+			}
+		case "type":
+			if value != "btree" {
+				return nil, dr.errorf("unsupported type %s", value)
+			}
+		case "database":
+			h.name, h.hasName = value, true
+		case "db_pagesize":
+			h.pageSize, _ = strconv.Atoi(value)
+		case "geometry":
+			if h.geometry, err = parseDumpGeometry(value); err != nil {
+				return nil, dr.errorf("invalid geometry %s", value)
+			}
+		case "canary":
+			var c Canary
+			if _, err = fmt.Sscanf(value, "v%d,x%d,y%d,z%d", &c.V, &c.X, &c.Y, &c.Z); err != nil {
+				return nil, dr.errorf("invalid canary %s", value)
+			}
+			h.canary = &c
+		case "duplicates":
+			if value == "1" {
+				h.flags |= DBDupSort
+			}
+		default:
+			for _, f := range dbFlagNames {
+				if f.name == key && value == "1" {
+					h.flags |= f.flag
+				}
+			}
+			// mapsize, maxreaders, sequence and unknown keys are ignored.
+		}
+	}
+}
+
+func parseDumpGeometry(s string) (*Geometry, error) {
+	g := &Geometry{}
+	for _, part := range strings.Split(s, ",") {
+		if len(part) < 2 {
+			return nil, ErrDumpFormat
+		}
+		v, err := strconv.ParseUint(part[1:], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		switch part[0] {
+		case 'l':
+			g.SizeLower = uintptr(v)
+		case 'c':
+			g.SizeNow = uintptr(v)
+		case 'u':
+			g.SizeUpper = uintptr(v)
+		case 's':
+			g.ShrinkThreshold = uintptr(v)
+		case 'g':
+			g.GrowthStep = uintptr(v)
+		default:
+			return nil, ErrDumpFormat
+		}
+	}
+	return g, nil
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// decodeValue decodes a data line without its leading space into dst.
+func decodeValue(dst, line []byte, print bool) ([]byte, bool) {
+	dst = dst[:0]
+	for i := 0; i < len(line); {
+		c := line[i]
+		if print && c != '\\' {
+			dst = append(dst, c)
+			i++
+			continue
+		}
+		if print {
+			i++
+			if i < len(line) && line[i] == '\\' {
+				dst = append(dst, '\\')
+				i++
+				continue
+			}
+		}
+		if i+1 >= len(line) {
+			return nil, false
+		}
+		hi, ok1 := unhex(line[i])
+		lo, ok2 := unhex(line[i+1])
+		if !ok1 || !ok2 {
+			return nil, false
+		}
+		dst = append(dst, hi<<4|lo)
+		i += 2
+	}
+	return dst, true
+}
+
+// Load reads data in the mdbx_dump text format and writes it to store,
+// creating databases as needed with the flags from each section header.
+func Load(r io.Reader, store *Store) error {
+	return LoadEx(r, store, LoadOptions{})
+}
+
+// LoadEx is Load with options.
+func LoadEx(r io.Reader, store *Store, opts LoadOptions) error {
+	return newDumpReader(r).load(store, opts)
+}
+
+func (dr *dumpReader) load(store *Store, opts LoadOptions) error {
+	if opts.BatchBytes <= 0 {
+		opts.BatchBytes = 64 * 1024 * 1024
+	}
+	for {
+		h, err := dr.readHeader()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err = dr.loadSection(store, h, opts); err != nil {
+			return err
+		}
+	}
+}
+
+func (dr *dumpReader) loadSection(store *Store, h *dumpHeader, opts LoadOptions) error {
+	name := opts.DBIName
+	if h.hasName {
+		name = h.name
+	}
+	put := PutUpsert
+	if opts.NoOverwrite {
+		put |= PutNoOverwrite
+	}
+	if opts.Append {
+		put |= PutAppend
+		if h.flags&DBDupSort != 0 {
+			put |= PutAppendDup
+		}
+	}
+
+	// The records of a transaction are read into a batch before it starts,
+	// so that Store.Update can run it again under a MapGrowth or RetryPolicy.
+	var (
+		dbi        DBI
+		opened     bool
+		key, value []byte
+		batch      []byte
+		records    []sortRecord
+	)
+	for done := false; !done; {
+		batch, records = batch[:0], records[:0]
+		for size := 0; size < opts.BatchBytes; {
+			line, err := dr.readLine()
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return err
+			}
+			if string(line) == "DATA=END" {
+				done = true
+				break
+			}
+			if len(line) == 0 || line[0] != ' ' {
+				return dr.errorf("expected key")
+			}
+			var ok bool
+			if key, ok = decodeValue(key, line[1:], h.print); !ok {
+				return dr.errorf("invalid key")
+			}
+			if line, err = dr.readLine(); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return err
+			}
+			if len(line) == 0 || line[0] != ' ' {
+				return dr.errorf("expected value")
+			}
+			if value, ok = decodeValue(value, line[1:], h.print); !ok {
+				return dr.errorf("invalid value")
+			}
+			records = append(records, sortRecord{offset: len(batch), keyLen: len(key), valLen: len(value)})
+			batch = append(append(batch, key...), value...)
+			size += len(key) + len(value)
+		}
+
+		err := store.Update(func(tx *Tx) error {
+			if !opened {
+				var err error
+				if dbi, err = tx.OpenDBI(name, h.flags|DBCreate); err != nil {
+					return err
+				}
+				if h.canary != nil {
+					if err = tx.PutCanary(h.canary); err != nil {
+						return err
+					}
+				}
+			}
+			for _, r := range records {
+				k := sliceVal(batch[r.offset : r.offset+r.keyLen])
+				v := sliceVal(batch[r.offset+r.keyLen : r.offset+r.keyLen+r.valLen])
+				if e := tx.Put(dbi, &k, &v, put); e != nil && !(e == ErrKeyExist && opts.NoOverwrite) {
+					return e
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		opened = true
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+// Command line
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// DumpMain implements the mdbx_dump utility and exits the program.
+// usage: mdbx_dump [-q] [-f file] [-l] [-p] [-n] [-a|-s name] dbpath
+//
+//	-q            be quiet
+//	-f file       write to file instead of stdout
+//	-l            list subDBs and exit
+//	-p            use printable characters
+//	-n            NOSUBDIR mode for open
+//	-a            dump main DB and all subDBs
+//	-s name       dump only the specified named subDB
+//	              by default dump only the main DB
+func DumpMain(args ...string) {
+	os.Exit(DumpCommand(args, os.Stdout, os.Stderr))
+}
+
+// DumpCommand runs the mdbx_dump utility like DumpMain, writing to stdout and
+// stderr, and returns the exit code instead of exiting.
+func DumpCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("mdbx_dump", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	quiet := fs.Bool("q", false, "be quiet")
+	file := fs.String("f", "", "write to file instead of stdout")
+	list := fs.Bool("l", false, "list subDBs and exit")
+	printable := fs.Bool("p", false, "use printable characters")
+	noSubDir := fs.Bool("n", false, "NOSUBDIR mode for open")
+	all := fs.Bool("a", false, "dump main DB and all subDBs")
+	sub := fs.String("s", "", "dump only the specified named subDB")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 || (*all && *sub != "") {
+		fs.Usage()
+		return 2
+	}
+	fail := func(err error) int {
+		if !*quiet {
+			fmt.Fprintf(stderr, "mdbx_dump: %v\n", err)
+		}
+		return 1
+	}
+
+	env, err := NewEnv()
+	if err != nil {
+		return fail(err)
+	}
+	defer env.Close(true)
+	if err = env.SetMaxDBS(dumpMaxDBs); err != nil {
+		return fail(err)
+	}
+	flags := EnvReadOnly | EnvAccede
+	if *noSubDir {
+		flags |= EnvNoSubDir
+	}
+	if err = env.Open(fs.Arg(0), flags, 0); err != nil {
+		return fail(err)
+	}
+	// There is no Store to begin the transaction with Store.View, which would
+	// keep it on one thread, and the dump runs long enough to migrate.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	tx := &Tx{}
+	if err = env.Begin(tx, TxReadOnly); err != nil {
+		return fail(err)
+	}
+	defer tx.Abort()
+
+	out := stdout
+	if *file != "" {
+		f, e := os.Create(*file)
+		if e != nil {
+			return fail(e)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var names []string
+	if *list || *all {
+		if names, err = tx.DBINames(); err != nil {
+			return fail(err)
+		}
+	}
+	if *list {
+		for _, name := range names {
+			fmt.Fprintln(out, name)
+		}
+		return 0
+	}
+	if *all {
+		_, hasRecords, e := mainDBISkip(tx)
+		if e != nil {
+			return fail(e)
+		}
+		if hasRecords {
+			names = append([]string{""}, names...)
+		}
+	} else {
+		names = []string{*sub}
+	}
+	format := DumpByteValue
+	if *printable {
+		format = DumpPrint
+	}
+	if e := DumpEx(tx, out, format, names...); e != nil {
+		return fail(e)
+	}
+	return 0
+}
+
+// LoadMain implements the mdbx_load utility and exits the program.
+// usage: mdbx_load [-q] [-f file] [-s name] [-N] [-a] [-n] dbpath
+//
+//	-q            be quiet
+//	-f file       read from file instead of stdin
+//	-s name       load into the specified named subDB when the input has no database header
+//	-N            use NOOVERWRITE on puts
+//	-a            append records in input order
+//	-n            NOSUBDIR mode for open
+func LoadMain(args ...string) {
+	os.Exit(LoadCommand(args, os.Stdin, os.Stderr))
+}
+
+// LoadCommand runs the mdbx_load utility like LoadMain, reading from stdin
+// and reporting to stderr, and returns the exit code instead of exiting.
+func LoadCommand(args []string, stdin io.Reader, stderr io.Writer) int {
+	fs := flag.NewFlagSet("mdbx_load", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	quiet := fs.Bool("q", false, "be quiet")
+	file := fs.String("f", "", "read from file instead of stdin")
+	sub := fs.String("s", "", "load into the specified named subDB")
+	noOverwrite := fs.Bool("N", false, "use NOOVERWRITE on puts")
+	appendMode := fs.Bool("a", false, "append records in input order")
+	noSubDir := fs.Bool("n", false, "NOSUBDIR mode for open")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	fail := func(err error) int {
+		if !*quiet {
+			fmt.Fprintf(stderr, "mdbx_load: %v\n", err)
+		}
+		return 1
+	}
+
+	in := stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fail(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// The first header carries the geometry of the source environment.
+	dr := newDumpReader(in)
+	h, err := dr.readHeader()
+	if err != nil {
+		if err == io.EOF {
+			return 0
+		}
+		return fail(err)
+	}
+	dr.pending = h
+
+	flags := EnvFlags(0)
+	if *noSubDir {
+		flags |= EnvNoSubDir
+	}
+	store, err := Open(fs.Arg(0), flags, 0, func(env *Env, create bool) error {
+		if err := env.SetMaxDBS(dumpMaxDBs); err != nil {
+			return err
+		}
+		if create && h.geometry != nil {
+			g := *h.geometry
+			g.PageSize = ^uintptr(0)
+			if h.pageSize > 0 {
+				g.PageSize = uintptr(h.pageSize)
+			}
+			return env.SetGeometry(g)
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer store.Close()
+
+	if err = dr.load(store, LoadOptions{
+		DBIName:     *sub,
+		NoOverwrite: *noOverwrite,
+		Append:      *appendMode,
+	}); err != nil {
+		return fail(err)
+	}
+	return 0
+}
@@ -0,0 +1,210 @@
+package mdbx
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func dumpRecords(t *testing.T, store *Store, name string) []string {
+	t.Helper()
+	var records []string
+	if err := store.View(func(tx *Tx) error {
+		dbi, err := tx.OpenDBI(name, DBAccede)
+		if err != nil {
+			return err
+		}
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		k, v := Val{}, Val{}
+		for cursor.Get(&k, &v, CursorNext) == nil {
+			records = append(records, fmt.Sprintf("%q=%q", k.UnsafeBytes(), v.UnsafeBytes()))
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestDumpLoad(t *testing.T) {
+	source := openTestStore(t, "", EnvSafeNoSync)
+	kv := openTestDBI(t, source, "kv", 0)
+	dups := openTestDBI(t, source, "dups", DBDupSort)
+	if err := source.Update(func(tx *Tx) error {
+		for i := 0; i < 300; i++ {
+			k := sliceVal([]byte(fmt.Sprintf("key\\%03d\x00", i)))
+			v := sliceVal([]byte{byte(i), '\n', 'a', '\\', 0xff})
+			if err := tx.Put(kv, &k, &v, 0); err != nil {
+				return err
+			}
+			d, m := StringConst(fmt.Sprintf("dup-%d", i%5)), StringConst(fmt.Sprintf("member %d", i))
+			if err := tx.Put(dups, &d, &m, 0); err != nil {
+				return err
+			}
+		}
+		empty := sliceVal(nil)
+		k := StringConst("empty")
+		return tx.Put(kv, &k, &empty, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []DumpFormat{DumpByteValue, DumpPrint} {
+		var buf bytes.Buffer
+		if err := source.View(func(tx *Tx) error {
+			return DumpEx(tx, &buf, format, "kv", "dups")
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "database=dups\ntype=btree\n") ||
+			!strings.Contains(buf.String(), "dupsort=1\n") {
+			t.Fatalf("unexpected header:\n%s", buf.String()[:200])
+		}
+
+		target := openTestStore(t, "", EnvSafeNoSync)
+		if err := LoadEx(&buf, target, LoadOptions{BatchBytes: 1024}); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"kv", "dups"} {
+			want, got := dumpRecords(t, source, name), dumpRecords(t, target, name)
+			if strings.Join(want, "\n") != strings.Join(got, "\n") {
+				t.Fatalf("format %d: %s differs after load: %d != %d records", format, name, len(got), len(want))
+			}
+		}
+		if err := target.View(func(tx *Tx) error {
+			dbi, err := tx.OpenDBI("dups", DBAccede)
+			if err != nil {
+				return err
+			}
+			flags, _, err := tx.DBIFlags(dbi)
+			if err != nil {
+				return err
+			}
+			if flags&DBDupSort == 0 {
+				t.Fatalf("dupsort flag not restored: %v", flags)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testdata/dump/print.txt is in the mdbx_dump -p format, with the backslash
+// written as \\ and other bytes outside of printable ASCII as hex.
+func TestDump_PrintFixture(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/dump/print.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := openTestStore(t, "", EnvSafeNoSync)
+	if err = Load(bytes.NewReader(fixture), store); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(dumpRecords(t, store, "kv"), ",")
+	if want := `"\x00\xff"="binary","a b~"="space and tilde","back\\slash"="\\","line\nbreak"="tab\t\x7f"`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err = store.View(func(tx *Tx) error {
+		return DumpEx(tx, &buf, DumpPrint, "kv")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The header depends on the environment, the records are identical.
+	data := func(dump []byte) string {
+		s := string(dump)
+		return s[strings.Index(s, "HEADER=END\n"):]
+	}
+	if data(buf.Bytes()) != data(fixture) {
+		t.Fatalf("got\n%s\nwant\n%s", data(buf.Bytes()), data(fixture))
+	}
+}
+
+func TestLoad_MainAndNoOverwrite(t *testing.T) {
+	input := "VERSION=3\nformat=print\ntype=btree\nHEADER=END\n a\\5c\\00\n one\n b\n two\nDATA=END\n"
+	store := openTestStore(t, "", EnvSafeNoSync)
+	main := openTestDBI(t, store, "", 0)
+	if err := store.Update(func(tx *Tx) error {
+		k, v := StringConst("b"), StringConst("kept")
+		return tx.Put(main, &k, &v, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadEx(strings.NewReader(input), store, LoadOptions{NoOverwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(dumpRecords(t, store, ""), ",")
+	if want := `"a\\\x00"="one","b"="kept"`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	if err := Load(strings.NewReader("VERSION=3\nHEADER=END\n zz\n"), store); err == nil {
+		t.Fatal("expected error for invalid input")
+	}
+}
+
+func TestLoad_MapGrowth(t *testing.T) {
+	store := openSmallStore(t)
+	if err := store.SetMapGrowth(&MapGrowth{Ceiling: 64 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	var input strings.Builder
+	input.WriteString("VERSION=3\nformat=print\ndatabase=kv\ntype=btree\nHEADER=END\n")
+	value := strings.Repeat("v", 1024)
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&input, " key%06d\n %s%d\n", i, value, i)
+	}
+	input.WriteString("DATA=END\n")
+
+	// Transactions failing with a full map are run again with the records
+	// read for them, none may be lost.
+	if err := LoadEx(strings.NewReader(input.String()), store, LoadOptions{BatchBytes: 256 << 10}); err != nil {
+		t.Fatal(err)
+	}
+	records := dumpRecords(t, store, "kv")
+	if len(records) != 3000 {
+		t.Fatalf("got %d records, want 3000", len(records))
+	}
+	for i, r := range records {
+		if want := fmt.Sprintf("%q=%q", fmt.Sprintf("key%06d", i), fmt.Sprintf("%s%d", value, i)); r != want {
+			t.Fatalf("record %d differs", i)
+		}
+	}
+}
+
+func TestDumpMain_All(t *testing.T) {
+	path := t.TempDir()
+	source := openTestStore(t, path, EnvSafeNoSync)
+	kv := openTestDBI(t, source, "kv", 0)
+	if err := source.Update(func(tx *Tx) error {
+		k, v := StringConst("k"), StringConst("v")
+		return tx.Put(kv, &k, &v, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_ = source.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := DumpCommand([]string{"-a", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if strings.Count(stdout.String(), "HEADER=END") != 1 || !strings.Contains(stdout.String(), "database=kv\n") {
+		t.Fatalf("unexpected dump:\n%s", stdout.String())
+	}
+	target := t.TempDir()
+	if code := LoadCommand([]string{target}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	store := openTestStore(t, target, EnvSafeNoSync)
+	if got := strings.Join(dumpRecords(t, store, "kv"), ","); got != `"k"="v"` {
+		t.Fatalf("got %s", got)
+	}
+}
@@ -0,0 +1,152 @@
+package mdbx
+
+import (
+	"errors"
+	"strconv"
+	"syscall"
+)
+
+// ErrTemporary matches every Error that may succeed when the operation is
+// retried, i.e. errors.Is(err, ErrTemporary) reports whether err is transient.
+var ErrTemporary = errors.New("mdbx: temporary error")
+
+// Temporary reports whether the operation that failed with e may succeed if
+// it is retried: the environment was busy or interrupted, the reader table or
+// the dirty page list of the transaction was full.
+func (e Error) Temporary() bool {
+	switch e {
+	case ErrBusy, ErrEINTR, ErrReadersFull, ErrTXNFull:
+		return true
+	}
+	return false
+}
+
+// Is implements errors.Is for the error classes of Error. Positive codes are
+// system errno values and match the corresponding syscall.Errno as well as
+// the os sentinels it matches, e.g. os.ErrNotExist or os.ErrPermission.
+func (e Error) Is(target error) bool {
+	if target == ErrTemporary {
+		return e.Temporary()
+	}
+	if e <= 0 {
+		return false
+	}
+	if errno, ok := target.(syscall.Errno); ok {
+		return syscall.Errno(e) == errno
+	}
+	return syscall.Errno(e).Is(target)
+}
+
+// IsTemporary reports whether err or an error it wraps is temporary.
+func IsTemporary(err error) bool {
+	return errors.Is(err, ErrTemporary)
+}
+
+// maxErrKeyPrefix is the number of key bytes kept by an OpError.
+const maxErrKeyPrefix = 16
+
+// OpError records the operation, database and key an Error occurred on.
+// errors.Is and errors.As see through it to the underlying Error.
+type OpError struct {
+	Op   string // Operation that failed, e.g. "put" or "cursor_get"
+	DBI  DBI    // Database handle, zero if the operation has none
+	Name string // Name of the database, if known
+	Key  []byte // Copy of at most the first 16 bytes of the key
+	Err  Error  // Result code
+}
+
+func (e *OpError) Error() string {
+	s := "mdbx: " + e.Op
+	if e.Name != "" {
+		s += " " + strconv.Quote(e.Name)
+	} else if e.DBI != 0 {
+		s += " dbi " + strconv.FormatUint(uint64(e.DBI), 10)
+	}
+	if e.Key != nil {
+		s += " key " + strconv.QuoteToASCII(string(e.Key))
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNotFound and ErrKeyExist are expected outcomes of lookups and
+// conditional puts. They are returned bare, without an OpError, so callers
+// may keep comparing with ==, and are boxed once so that doing so does not
+// allocate.
+var (
+	errNotFound error = ErrNotFound
+	errKeyExist error = ErrKeyExist
+)
+
+// opError converts the result code of an operation into an error, nil on
+// success.
+func opError(op string, rc Error) error {
+	switch rc {
+	case ErrSuccess:
+		return nil
+	case ErrNotFound:
+		return errNotFound
+	case ErrKeyExist:
+		return errKeyExist
+	}
+	return &OpError{Op: op, Err: rc}
+}
+
+// dbiError is opError for operations on a database. The name of the
+// database is looked up in env.
+func dbiError(op string, env *Env, dbi DBI, key *Val, rc Error) error {
+	err := opError(op, rc)
+	if e, ok := err.(*OpError); ok {
+		e.DBI = dbi
+		if env != nil {
+			e.Name, _ = env.DBIName(dbi)
+		}
+		e.Key = keyPrefix(key)
+	}
+	return err
+}
+
+// dbiOpenError is opError for opening the database called name.
+func dbiOpenError(name string, rc Error) error {
+	err := opError("dbi_open", rc)
+	if e, ok := err.(*OpError); ok {
+		e.Name = name
+	}
+	return err
+}
+
+// opError is dbiError for cursor operations. A cursor does not know the
+// environment, so the name of the database is left empty.
+func (cur *Cursor) opError(op string, key *Val, rc Error) error {
+	err := opError(op, rc)
+	if e, ok := err.(*OpError); ok {
+		e.DBI = cur.DBI()
+		e.Key = keyPrefix(key)
+	}
+	return err
+}
+
+// keyPrefix copies the first bytes of key for an OpError.
+func keyPrefix(key *Val) []byte {
+	if key == nil || key.Base == nil {
+		return nil
+	}
+	n := int(key.Len)
+	if n > maxErrKeyPrefix {
+		n = maxErrKeyPrefix
+	}
+	return append([]byte{}, key.UnsafeBytes()[:n]...)
+}
+
+// keyed reports whether the key is an input of the cursor operation.
+func (op CursorOp) keyed() bool {
+	switch op {
+	case CursorSet, CursorSetKey, CursorSetRange, CursorGetBoth, CursorGetBothRange,
+		CursorSetLowerBound, CursorSetUpperBound:
+		return true
+	}
+	return false
+}
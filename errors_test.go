@@ -0,0 +1,114 @@
+package mdbx
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestError_Errno(t *testing.T) {
+	if !errors.Is(Error(syscall.ENOENT), os.ErrNotExist) {
+		t.Fatal("ENOENT should match os.ErrNotExist")
+	}
+	if !errors.Is(Error(syscall.EACCES), os.ErrPermission) {
+		t.Fatal("EACCES should match os.ErrPermission")
+	}
+	if !errors.Is(&OpError{Op: "env_open", Err: Error(syscall.EIO)}, syscall.EIO) {
+		t.Fatal("EIO should match syscall.EIO")
+	}
+	if errors.Is(Error(syscall.EIO), syscall.ENOSPC) || errors.Is(ErrNotFound, os.ErrNotExist) {
+		t.Fatal("unexpected match")
+	}
+}
+
+func TestOpError(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+
+	if err := store.Update(func(tx *Tx) error {
+		k, v := StringConst("key"), StringConst("value")
+		if err := tx.Put(dbi, &k, &v, 0); err != nil {
+			return err
+		}
+		if err := tx.Put(dbi, &k, &v, PutNoOverwrite); err != ErrKeyExist {
+			t.Fatalf("expected bare ErrKeyExist, got %#v", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		k, v := StringConst("missing"), Val{}
+		if err := tx.Get(dbi, &k, &v); err != ErrNotFound || !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected bare ErrNotFound, got %#v", err)
+		}
+		if allocs := testing.AllocsPerRun(100, func() {
+			_ = tx.Get(dbi, &k, &v)
+		}); allocs != 0 {
+			t.Fatalf("ErrNotFound allocates %v times", allocs)
+		}
+
+		k, v = StringConst("a key longer than sixteen bytes"), StringConst("value")
+		err := tx.Put(dbi, &k, &v, 0)
+		var opErr *OpError
+		if !errors.As(err, &opErr) {
+			t.Fatalf("expected an OpError, got %#v", err)
+		}
+		if opErr.Op != "put" || opErr.DBI != dbi || opErr.Name != "kv" || string(opErr.Key) != "a key longer tha" {
+			t.Fatalf("unexpected context %+v", opErr)
+		}
+		if !errors.Is(err, ErrEACCESS) || !errors.Is(err, os.ErrPermission) {
+			t.Fatalf("expected a permission error, got %v", err)
+		}
+		if msg := err.Error(); !strings.HasPrefix(msg, `mdbx: put "kv" key "a key longer tha": `) {
+			t.Fatalf("unexpected message %q", msg)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Env().Sync(true, false); err != nil {
+		t.Fatalf("sync without changes: %v", err)
+	}
+}
+
+func TestCursor_EOF(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+	if err := store.Update(func(tx *Tx) error {
+		k, v := StringConst("key"), StringConst("value")
+		return tx.Put(dbi, &k, &v, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.View(func(tx *Tx) error {
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		k, v := Val{}, Val{}
+		if err = cursor.Get(&k, &v, CursorFirst); err != nil {
+			return err
+		}
+		if first, err := cursor.OnFirst(); err != nil || !first {
+			t.Fatalf("on first %v %v", first, err)
+		}
+		if eof, err := cursor.EOF(); err != nil || eof {
+			t.Fatalf("eof %v %v", eof, err)
+		}
+		if err = cursor.Get(&k, &v, CursorNext); err != ErrNotFound {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+		if eof, err := cursor.EOF(); err != nil || !eof {
+			t.Fatalf("eof %v %v", eof, err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,228 @@
+package mdbx
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+)
+
+// sortRecord locates a record in the buffer of an externalSort.
+type sortRecord struct {
+	offset int
+	keyLen int
+	valLen int
+}
+
+// externalSort sorts records too many to hold in memory: records are
+// buffered up to a memory limit, sorted and spilled to a temporary file as a
+// run, and the runs are merged at the end.
+type externalSort struct {
+	cmpKey  func(a, b []byte) int
+	cmpData func(a, b []byte) int
+	memory  int
+	dir     string
+	buf     []byte
+	records []sortRecord
+	runs    []*os.File
+}
+
+func newExternalSort(flags DBFlags, memory int, dir string) *externalSort {
+	if memory <= 0 {
+		memory = 64 * 1024 * 1024
+	}
+	return &externalSort{
+		cmpKey:  KeyComparator(flags),
+		cmpData: DataComparator(flags),
+		memory:  memory,
+		dir:     dir,
+	}
+}
+
+func (s *externalSort) compare(ak, av, bk, bv []byte) int {
+	if c := s.cmpKey(ak, bk); c != 0 {
+		return c
+	}
+	return s.cmpData(av, bv)
+}
+
+func (s *externalSort) record(r sortRecord) (key, value []byte) {
+	return s.buf[r.offset : r.offset+r.keyLen], s.buf[r.offset+r.keyLen : r.offset+r.keyLen+r.valLen]
+}
+
+func (s *externalSort) add(key, value []byte) error {
+	s.records = append(s.records, sortRecord{offset: len(s.buf), keyLen: len(key), valLen: len(value)})
+	s.buf = append(append(s.buf, key...), value...)
+	if len(s.buf) >= s.memory {
+		return s.spill()
+	}
+	return nil
+}
+
+func (s *externalSort) sortBuffer() {
+	sort.Slice(s.records, func(i, j int) bool {
+		ak, av := s.record(s.records[i])
+		bk, bv := s.record(s.records[j])
+		return s.compare(ak, av, bk, bv) < 0
+	})
+}
+
+// spill writes the buffered records sorted to a new run.
+func (s *externalSort) spill() error {
+	s.sortBuffer()
+	f, err := os.CreateTemp(s.dir, "mdbx-sort-*")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f)
+	w := bufio.NewWriterSize(f, 256*1024)
+	var tmp [2 * binary.MaxVarintLen64]byte
+	for _, r := range s.records {
+		n := binary.PutUvarint(tmp[:], uint64(r.keyLen))
+		n += binary.PutUvarint(tmp[n:], uint64(r.valLen))
+		if _, err = w.Write(tmp[:n]); err != nil {
+			return err
+		}
+		if _, err = w.Write(s.buf[r.offset : r.offset+r.keyLen+r.valLen]); err != nil {
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.buf, s.records = s.buf[:0], s.records[:0]
+	return nil
+}
+
+// source returns the sorted records. If nothing was spilled they are
+// returned from memory, otherwise the runs are merged.
+func (s *externalSort) source() (BulkSource, error) {
+	if len(s.runs) == 0 {
+		s.sortBuffer()
+		i := 0
+		return func() ([]byte, []byte, error) {
+			if i == len(s.records) {
+				return nil, nil, io.EOF
+			}
+			i++
+			key, value := s.record(s.records[i-1])
+			return key, value, nil
+		}, nil
+	}
+	if len(s.records) > 0 {
+		if err := s.spill(); err != nil {
+			return nil, err
+		}
+	}
+	s.buf, s.records = nil, nil
+
+	m := &sortMerge{s: s}
+	for _, f := range s.runs {
+		r := &sortRun{r: bufio.NewReaderSize(f, 256*1024)}
+		if err := r.next(); err == io.EOF {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		m.runs = append(m.runs, r)
+	}
+	heap.Init(m)
+	var last *sortRun
+	return func() ([]byte, []byte, error) {
+		// The previous record is returned before advancing its run, so that
+		// it stays valid until the next call.
+		if last != nil {
+			if err := last.next(); err == io.EOF {
+				heap.Pop(m)
+			} else if err != nil {
+				return nil, nil, err
+			} else {
+				heap.Fix(m, 0)
+			}
+			last = nil
+		}
+		if len(m.runs) == 0 {
+			return nil, nil, io.EOF
+		}
+		last = m.runs[0]
+		return last.key, last.value, nil
+	}, nil
+}
+
+// close removes the runs.
+func (s *externalSort) close() error {
+	var err error
+	for _, f := range s.runs {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+		if e := os.Remove(f.Name()); e != nil && err == nil {
+			err = e
+		}
+	}
+	s.runs = nil
+	return err
+}
+
+// sortRun reads the records of a run.
+type sortRun struct {
+	r     *bufio.Reader
+	buf   []byte
+	key   []byte
+	value []byte
+}
+
+func (r *sortRun) next() error {
+	keyLen, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return err
+	}
+	valLen, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if n := int(keyLen + valLen); cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	r.buf = r.buf[:keyLen+valLen]
+	if _, err = io.ReadFull(r.r, r.buf); err != nil {
+		return unexpectedEOF(err)
+	}
+	r.key, r.value = r.buf[:keyLen], r.buf[keyLen:]
+	return nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// sortMerge is a heap of runs ordered by their current record.
+type sortMerge struct {
+	s    *externalSort
+	runs []*sortRun
+}
+
+func (m *sortMerge) Len() int { return len(m.runs) }
+
+func (m *sortMerge) Less(i, j int) bool {
+	a, b := m.runs[i], m.runs[j]
+	return m.s.compare(a.key, a.value, b.key, b.value) < 0
+}
+
+func (m *sortMerge) Swap(i, j int) { m.runs[i], m.runs[j] = m.runs[j], m.runs[i] }
+
+func (m *sortMerge) Push(x interface{}) { m.runs = append(m.runs, x.(*sortRun)) }
+
+func (m *sortMerge) Pop() interface{} {
+	r := m.runs[len(m.runs)-1]
+	m.runs = m.runs[:len(m.runs)-1]
+	return r
+}
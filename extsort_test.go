@@ -0,0 +1,71 @@
+package mdbx
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestExternalSort(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, flags := range []DBFlags{DBDefaults, DBIntegerKey, DBReverseKey, DBDupSort | DBReverseDup} {
+		for _, memory := range []int{1 << 20, 1000} {
+			dir := t.TempDir()
+			s := newExternalSort(flags, memory, dir)
+			var want [][2][]byte
+			for i := 0; i < 2000; i++ {
+				key, value := make([]byte, 8), make([]byte, 1+rnd.Intn(8))
+				if flags&DBIntegerKey != 0 {
+					binary.LittleEndian.PutUint64(key, rnd.Uint64())
+				} else {
+					rnd.Read(key[:1+rnd.Intn(7)])
+				}
+				rnd.Read(value)
+				want = append(want, [2][]byte{key, value})
+				if err := s.add(key, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if (memory == 1000) != (len(s.runs) > 0) {
+				t.Fatalf("memory %d: %d runs", memory, len(s.runs))
+			}
+			src, err := s.source()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cmpKey, cmpData := KeyComparator(flags), DataComparator(flags)
+			sort.SliceStable(want, func(i, j int) bool {
+				if c := cmpKey(want[i][0], want[j][0]); c != 0 {
+					return c < 0
+				}
+				return cmpData(want[i][1], want[j][1]) < 0
+			})
+			for i := 0; ; i++ {
+				key, value, err := src()
+				if err == io.EOF {
+					if i != len(want) {
+						t.Fatalf("flags %v: got %d records, want %d", flags, i, len(want))
+					}
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cmpKey(key, want[i][0]) != 0 || cmpData(value, want[i][1]) != 0 {
+					t.Fatalf("flags %v memory %d: record %d is %x=%x, want %x=%x", flags, memory, i, key, value, want[i][0], want[i][1])
+				}
+			}
+
+			if err = s.close(); err != nil {
+				t.Fatal(err)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Fatalf("%d runs left", len(entries))
+			}
+		}
+	}
+}
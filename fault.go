@@ -0,0 +1,116 @@
+//go:build mdbxfault
+
+package mdbx
+
+import (
+	"sync"
+	"time"
+)
+
+// faultsEnabled guards the fault hooks. Without the mdbxfault build tag it is
+// false and the hooks are compiled out.
+const faultsEnabled = true
+
+// FaultRule makes calls of an operation fail or stall. Rules are only
+// available with the mdbxfault build tag and apply to every Env of the
+// process.
+type FaultRule struct {
+	// Point is the operation the rule applies to.
+	Point FaultPoint
+
+	// DBI restricts the rule to one database. Zero matches every database.
+	// Commit and Sync have no database and never match a non-zero DBI.
+	DBI DBI
+
+	// Nth is the first matching call that fails, counting from 1. Zero is
+	// the same as 1.
+	Nth int
+
+	// Times is the number of calls that fail from the Nth on. Zero fails
+	// every call from the Nth on.
+	Times int
+
+	// Delay is slept before the call proceeds or fails.
+	Delay time.Duration
+
+	// Err is the result of the failed calls. ErrSuccess only delays them.
+	Err Error
+}
+
+type faultState struct {
+	rule  FaultRule
+	calls int
+	hits  int
+}
+
+var faults struct {
+	rules []*faultState
+	mu    sync.Mutex
+}
+
+// InjectFault installs rule and returns a function that removes it.
+func InjectFault(rule FaultRule) (remove func()) {
+	state := &faultState{rule: rule}
+	if state.rule.Nth <= 0 {
+		state.rule.Nth = 1
+	}
+	faults.mu.Lock()
+	faults.rules = append(faults.rules, state)
+	faults.mu.Unlock()
+	return func() {
+		faults.mu.Lock()
+		defer faults.mu.Unlock()
+		for i, s := range faults.rules {
+			if s == state {
+				faults.rules = append(faults.rules[:i], faults.rules[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// ResetFaults removes every installed rule.
+func ResetFaults() {
+	faults.mu.Lock()
+	faults.rules = nil
+	faults.mu.Unlock()
+}
+
+// FaultHits returns how many calls rules for point have failed or delayed.
+func FaultHits(point FaultPoint) int {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	hits := 0
+	for _, s := range faults.rules {
+		if s.rule.Point == point {
+			hits += s.hits
+		}
+	}
+	return hits
+}
+
+// fault applies the rules for a call of point on dbi and returns the
+// injected result code. Every matching rule counts the call; the first one
+// that fires decides the outcome.
+func fault(point FaultPoint, dbi DBI) Error {
+	var delay time.Duration
+	rc, fired := ErrSuccess, false
+	faults.mu.Lock()
+	for _, s := range faults.rules {
+		r := &s.rule
+		if r.Point != point || (r.DBI != 0 && r.DBI != dbi) {
+			continue
+		}
+		s.calls++
+		if fired || s.calls < r.Nth || (r.Times > 0 && s.calls >= r.Nth+r.Times) {
+			continue
+		}
+		s.hits++
+		delay, rc, fired = r.Delay, r.Err, true
+	}
+	faults.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return rc
+}
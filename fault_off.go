@@ -0,0 +1,11 @@
+//go:build !mdbxfault
+
+package mdbx
+
+// faultsEnabled guards the fault hooks, which are only compiled in with the
+// mdbxfault build tag.
+const faultsEnabled = false
+
+func fault(point FaultPoint, dbi DBI) Error {
+	return ErrSuccess
+}
@@ -0,0 +1,26 @@
+package mdbx
+
+// FaultPoint identifies an operation faults can be injected into. Faults are
+// only injected in builds with the mdbxfault tag, see InjectFault.
+type FaultPoint uint8
+
+const (
+	FaultCommit    FaultPoint = iota + 1 // Tx.Commit and Tx.CommitEx
+	FaultPut                             // Tx.Put
+	FaultSync                            // Env.Sync
+	FaultCursorGet                       // Cursor.Get
+)
+
+func (p FaultPoint) String() string {
+	switch p {
+	case FaultCommit:
+		return "commit"
+	case FaultPut:
+		return "put"
+	case FaultSync:
+		return "sync"
+	case FaultCursorGet:
+		return "cursor_get"
+	}
+	return "unknown"
+}
@@ -0,0 +1,119 @@
+//go:build mdbxfault
+
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func putKeys(store *Store, dbi DBI, prefix string, n int) error {
+	return store.Update(func(tx *Tx) error {
+		for i := 0; i < n; i++ {
+			k, v := StringConst(fmt.Sprintf("%s-%d", prefix, i)), StringConst("value")
+			if err := tx.Put(dbi, &k, &v, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func countKeys(t *testing.T, store *Store, dbi DBI) int {
+	t.Helper()
+	var stat Stats
+	if err := store.View(func(tx *Tx) error {
+		return tx.DBIStat(dbi, &stat)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return int(stat.Entries)
+}
+
+func TestFault_Put(t *testing.T) {
+	t.Cleanup(ResetFaults)
+	store := openTestStore(t, "", EnvSafeNoSync)
+	a := openTestDBI(t, store, "a", 0)
+	b := openTestDBI(t, store, "b", 0)
+
+	remove := InjectFault(FaultRule{Point: FaultPut, DBI: a, Nth: 3, Times: 1, Err: ErrMapFull})
+	if err := putKeys(store, b, "b", 5); err != nil {
+		t.Fatalf("other DBI failed: %v", err)
+	}
+	err := putKeys(store, a, "a", 5)
+	var opErr *OpError
+	if !errors.Is(err, ErrMapFull) || !errors.As(err, &opErr) || opErr.Name != "a" || string(opErr.Key) != "a-2" {
+		t.Fatalf("expected ErrMapFull on the third put, got %v", err)
+	}
+	if n := countKeys(t, store, a); n != 0 {
+		t.Fatalf("failed update left %d keys", n)
+	}
+	if err = putKeys(store, a, "a", 5); err != nil {
+		t.Fatalf("rule fired more than once: %v", err)
+	}
+	if hits := FaultHits(FaultPut); hits != 1 {
+		t.Fatalf("hits %d", hits)
+	}
+	remove()
+	if hits := FaultHits(FaultPut); hits != 0 {
+		t.Fatalf("hits %d after remove", hits)
+	}
+}
+
+func TestFault_CommitAndSync(t *testing.T) {
+	t.Cleanup(ResetFaults)
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+
+	InjectFault(FaultRule{Point: FaultCommit, Times: 1, Err: ErrEIO})
+	if err := putKeys(store, dbi, "k", 3); !errors.Is(err, syscall.EIO) {
+		t.Fatalf("expected EIO, got %v", err)
+	}
+	if n := countKeys(t, store, dbi); n != 0 {
+		t.Fatalf("failed commit left %d keys", n)
+	}
+
+	InjectFault(FaultRule{Point: FaultSync, Err: ErrCorrupted})
+	if err := store.Sync(); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("expected ErrCorrupted, got %v", err)
+	}
+}
+
+func TestFault_RetryAndDelay(t *testing.T) {
+	t.Cleanup(ResetFaults)
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+	if err := store.SetRetryPolicy(&RetryPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	InjectFault(FaultRule{Point: FaultPut, Nth: 2, Times: 1, Err: ErrBusy})
+	if err := putKeys(store, dbi, "k", 3); err != nil {
+		t.Fatalf("transient fault was not retried: %v", err)
+	}
+
+	InjectFault(FaultRule{Point: FaultCursorGet, DBI: dbi, Delay: 5 * time.Millisecond, Times: 2})
+	start := time.Now()
+	if err := store.View(func(tx *Tx) error {
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		k, v := Val{}, Val{}
+		for err = cursor.Get(&k, &v, CursorFirst); err == nil; err = cursor.Get(&k, &v, CursorNext) {
+		}
+		if err != ErrNotFound {
+			return err
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond || FaultHits(FaultCursorGet) != 2 {
+		t.Fatalf("expected two delays, took %v with %d hits", elapsed, FaultHits(FaultCursorGet))
+	}
+}
@@ -0,0 +1,356 @@
+//go:build go1.18
+
+package mdbx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// fuzzModel is the expected content of a database as a sorted slice of
+// records, compared with the same order mdbx uses for its flags.
+type fuzzModel struct {
+	flags   DBFlags
+	records []fuzzRecord
+}
+
+type fuzzRecord struct {
+	key, value []byte
+}
+
+func (m *fuzzModel) compareKeys(a, b []byte) int {
+	if m.flags&DBReverseKey != 0 {
+		for i, j := len(a)-1, len(b)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+			if a[i] != b[j] {
+				if a[i] < b[j] {
+					return -1
+				}
+				return 1
+			}
+		}
+		return len(a) - len(b)
+	}
+	return bytes.Compare(a, b)
+}
+
+func (m *fuzzModel) compare(a, b fuzzRecord) int {
+	if c := m.compareKeys(a.key, b.key); c != 0 || m.flags&DBDupSort == 0 {
+		return c
+	}
+	return bytes.Compare(a.value, b.value)
+}
+
+// search returns the index of the first record not ordered before r.
+func (m *fuzzModel) search(r fuzzRecord) int {
+	return sort.Search(len(m.records), func(i int) bool {
+		return m.compare(m.records[i], r) >= 0
+	})
+}
+
+// first returns the index of the first record of key or -1.
+func (m *fuzzModel) first(key []byte) int {
+	i := sort.Search(len(m.records), func(i int) bool {
+		return m.compareKeys(m.records[i].key, key) >= 0
+	})
+	if i < len(m.records) && m.compareKeys(m.records[i].key, key) == 0 {
+		return i
+	}
+	return -1
+}
+
+func (m *fuzzModel) has(r fuzzRecord) bool {
+	i := m.search(r)
+	return i < len(m.records) && m.compare(m.records[i], r) == 0
+}
+
+func (m *fuzzModel) put(r fuzzRecord) {
+	i := m.search(r)
+	if i < len(m.records) && m.compare(m.records[i], r) == 0 {
+		m.records[i] = r
+		return
+	}
+	m.records = append(m.records, fuzzRecord{})
+	copy(m.records[i+1:], m.records[i:])
+	m.records[i] = r
+}
+
+func (m *fuzzModel) delete(key, value []byte) bool {
+	deleted := false
+	for i := m.first(key); i >= 0; i = m.first(key) {
+		if value != nil && !bytes.Equal(m.records[i].value, value) {
+			if i = m.search(fuzzRecord{key, value}); i >= len(m.records) || m.compare(m.records[i], fuzzRecord{key, value}) != 0 {
+				return deleted
+			}
+		}
+		m.records = append(m.records[:i], m.records[i+1:]...)
+		deleted = true
+		if value != nil {
+			break
+		}
+	}
+	return deleted
+}
+
+// fuzzReader decodes operations from fuzz input. It returns zeros once the
+// input is exhausted.
+type fuzzReader []byte
+
+func (r *fuzzReader) byte() byte {
+	if len(*r) == 0 {
+		return 0
+	}
+	b := (*r)[0]
+	*r = (*r)[1:]
+	return b
+}
+
+func (r *fuzzReader) bytes(n int) []byte {
+	if n > len(*r) {
+		n = len(*r)
+	}
+	b := append([]byte{}, (*r)[:n]...)
+	*r = (*r)[n:]
+	return b
+}
+
+var fuzzDBFlags = []DBFlags{0, DBReverseKey, DBDupSort}
+
+// FuzzTx applies random sequences of puts, deletes, replaces, gets and
+// cursor positioning to a database and to a model of it, comparing every
+// result and the final order of the records.
+func FuzzTx(f *testing.F) {
+	store := openTestStore(f, "", EnvSafeNoSync)
+	dbis := make([]DBI, len(fuzzDBFlags))
+	for i, flags := range fuzzDBFlags {
+		dbis[i] = openTestDBI(f, store, "fuzz"+string(rune('a'+i)), flags)
+	}
+
+	f.Add(uint8(0), []byte("\x00\x01a\x01v\x00\x01b\x00\x03\x01a\x00\x05\x00\x00\x01\x01a\x00"))
+	f.Add(uint8(1), []byte("\x00\x02ab\x01x\x00\x02cb\x00\x00\x01b\x02yy\x04\x02ab\x01z\x05\x01b\x00"))
+	f.Add(uint8(2), []byte("\x00\x01k\x01a\x00\x01k\x01b\x02\x01k\x01b\x20\x01k\x01a\x03\x01k\x00\x01\x01k\x00"))
+	f.Add(uint8(2), []byte("\x30\x01a\x01a\x40\x01b\x01a\x40\x01b\x01b\x40\x01b\x01a\x05\x00\x00"))
+
+	f.Fuzz(func(t *testing.T, kind uint8, ops []byte) {
+		kind %= uint8(len(fuzzDBFlags))
+		dbi, flags := dbis[kind], fuzzDBFlags[kind]
+		model := &fuzzModel{flags: flags}
+
+		if err := store.Update(func(tx *Tx) error {
+			if err := tx.Drop(dbi, false); err != nil {
+				return err
+			}
+			r := fuzzReader(ops)
+			for step := 0; len(r) > 0; step++ {
+				op := r.byte()
+				key := r.bytes(int(r.byte() % 25))
+				value := r.bytes(int(r.byte() % 49))
+				if err := fuzzStep(tx, dbi, model, op, key, value); err != nil {
+					return fmt.Errorf("step %d op %#x key %q value %q: %w", step, op, key, value, err)
+				}
+			}
+			return fuzzCompare(tx, dbi, model)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.View(func(tx *Tx) error {
+			return fuzzCompare(tx, dbi, model)
+		}); err != nil {
+			t.Fatalf("after commit: %v", err)
+		}
+	})
+}
+
+func fuzzStep(tx *Tx, dbi DBI, model *fuzzModel, op byte, key, value []byte) error {
+	dupsort := model.flags&DBDupSort != 0
+	r := fuzzRecord{key, value}
+	k, v := Bytes(&key), Bytes(&value)
+	expect := func(err, want error) error {
+		if err != want {
+			return errors.New("got " + errString(err) + ", want " + errString(want))
+		}
+		return nil
+	}
+
+	switch op & 0x0f {
+	case 0, 6: // Put
+		var flags PutFlags
+		switch op >> 4 {
+		case 1:
+			flags = PutNoOverwrite
+		case 2:
+			flags = PutNoDupData
+		case 3:
+			flags = PutAppend
+		case 4:
+			flags = PutAppendDup
+		}
+		if !dupsort && (flags == PutNoDupData || flags == PutAppendDup) {
+			flags = 0
+		}
+		var want error
+		switch flags {
+		case PutNoOverwrite:
+			if i := model.first(key); i >= 0 {
+				if err := expect(tx.Put(dbi, &k, &v, flags), ErrKeyExist); err != nil {
+					return err
+				}
+				if !dupsort && !bytes.Equal(v.UnsafeBytes(), model.records[i].value) {
+					return errors.New("put with no-overwrite did not return the current value")
+				}
+				return nil
+			}
+		case PutNoDupData:
+			if model.has(r) {
+				want = ErrKeyExist
+			}
+		case PutAppend:
+			if n := len(model.records); n > 0 {
+				switch c := model.compareKeys(key, model.records[n-1].key); {
+				case c == 0:
+					return nil // Outcome depends on details not worth modelling.
+				case c < 0:
+					want = ErrEKeyMismatch
+				}
+			}
+		case PutAppendDup:
+			// Only the values of the key have to be appended in order.
+			if i := model.first(key); i >= 0 {
+				for i+1 < len(model.records) && model.compareKeys(model.records[i+1].key, key) == 0 {
+					i++
+				}
+				switch c := bytes.Compare(value, model.records[i].value); {
+				case c == 0:
+					return nil
+				case c < 0:
+					want = ErrEKeyMismatch
+				}
+			}
+		}
+		err := tx.Put(dbi, &k, &v, flags)
+		if errors.Is(err, ErrEKeyMismatch) && want == ErrEKeyMismatch {
+			return nil
+		}
+		if err = expect(err, want); err != nil || want != nil {
+			return err
+		}
+		model.put(r)
+
+	case 1: // Delete a key with all its values
+		want := errNotFound
+		if model.delete(key, nil) {
+			want = nil
+		}
+		return expect(tx.Delete(dbi, &k, nil), want)
+
+	case 2: // Delete a single value
+		if !dupsort {
+			return nil
+		}
+		want := errNotFound
+		if model.delete(key, value) {
+			want = nil
+		}
+		return expect(tx.Delete(dbi, &k, &v), want)
+
+	case 3: // Get
+		var data Val
+		i := model.first(key)
+		if i < 0 {
+			return expect(tx.Get(dbi, &k, &data), errNotFound)
+		}
+		if err := tx.Get(dbi, &k, &data); err != nil {
+			return err
+		}
+		if !bytes.Equal(data.UnsafeBytes(), model.records[i].value) {
+			return errors.New("get returned " + string(data.Bytes()))
+		}
+
+	case 4: // Replace
+		if dupsort {
+			return nil
+		}
+		buf := make([]byte, 64)
+		old := Bytes(&buf)
+		if err := tx.Replace(dbi, &k, &v, &old, 0); err != nil {
+			return err
+		}
+		if i := model.first(key); i >= 0 {
+			if !bytes.Equal(old.UnsafeBytes(), model.records[i].value) {
+				return errors.New("replace returned old value " + string(old.Bytes()))
+			}
+		} else if old.Base != nil {
+			return errors.New("replace of a missing key returned an old value")
+		}
+		model.put(r)
+
+	case 5: // Cursor positioning
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		cursorOp, i := CursorSetRange, model.search(fuzzRecord{key: key})
+		if dupsort && op&0x10 != 0 {
+			cursorOp, i = CursorGetBoth, -1
+			if model.has(r) {
+				i = model.search(r)
+			}
+		}
+		data := v
+		if i < 0 || i >= len(model.records) {
+			return expect(cursor.Get(&k, &data, cursorOp), errNotFound)
+		}
+		if err = cursor.Get(&k, &data, cursorOp); err != nil {
+			return err
+		}
+		want := model.records[i]
+		if !bytes.Equal(k.UnsafeBytes(), want.key) || !bytes.Equal(data.UnsafeBytes(), want.value) {
+			return errors.New("cursor is at " + string(k.Bytes()) + "=" + string(data.Bytes()))
+		}
+	}
+	return nil
+}
+
+// fuzzCompare iterates the database forward and backward and compares the
+// records with the model.
+func fuzzCompare(tx *Tx, dbi DBI, model *fuzzModel) error {
+	cursor, err := tx.OpenCursor(dbi)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	for _, dir := range []struct{ first, next CursorOp }{{CursorFirst, CursorNext}, {CursorLast, CursorPrev}} {
+		k, v := Val{}, Val{}
+		n := 0
+		for err = cursor.Get(&k, &v, dir.first); err == nil; err = cursor.Get(&k, &v, dir.next) {
+			i := n
+			if dir.first == CursorLast {
+				i = len(model.records) - 1 - n
+			}
+			if i < 0 || i >= len(model.records) {
+				return errors.New("more records than expected")
+			}
+			if want := model.records[i]; !bytes.Equal(k.UnsafeBytes(), want.key) || !bytes.Equal(v.UnsafeBytes(), want.value) {
+				return errors.New("record " + string(k.Bytes()) + "=" + string(v.Bytes()) + " out of order")
+			}
+			n++
+		}
+		if err != ErrNotFound {
+			return err
+		}
+		if n != len(model.records) {
+			return errors.New("fewer records than expected")
+		}
+	}
+	return nil
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "nil"
+	}
+	return err.Error()
+}
@@ -0,0 +1,187 @@
+//go:build go1.23
+
+package mdbx
+
+import (
+	"bytes"
+	"iter"
+)
+
+// All, Range, Reverse, Prefix and Dups return the records of a database as
+// an iter.Seq2 of keys and values, together with a function returning the
+// error that stopped the last loop over it, if any. Every loop opens a
+// cursor which is closed when the loop ends, including on break.
+//
+// The keys and values yielded point into the memory map like the slices
+// returned by Val.UnsafeBytes. They are valid until the transaction ends or
+// the database is modified and must be copied to be retained.
+//
+//	records, errf := tx.Prefix(dbi, []byte("user/"))
+//	for k, v := range records {
+//		...
+//	}
+//	if err := errf(); err != nil {
+//		return err
+//	}
+//
+// The bounds of Range and Reverse are located once with CursorSetLowerBound
+// in the order of the database, the loop then only checks for the key at
+// which it stops. Both ranges are half-open, so CursorSetUpperBound is not
+// needed.
+type cursorRange struct {
+	tx      *Tx
+	dbi     DBI
+	from    []byte
+	to      []byte
+	prefix  []byte
+	dups    bool
+	reverse bool
+	err     error
+}
+
+// All returns the records of dbi in key order.
+func (tx *Tx) All(dbi DBI) (iter.Seq2[[]byte, []byte], func() error) {
+	return (&cursorRange{tx: tx, dbi: dbi}).seq()
+}
+
+// Range returns the records of dbi with keys >= from and < to in key order.
+// A nil from or to leaves the range open at that end. If both are given they
+// are compared once by KeyComparator of the flags of dbi, an empty range
+// yields no records.
+func (tx *Tx) Range(dbi DBI, from, to []byte) (iter.Seq2[[]byte, []byte], func() error) {
+	return (&cursorRange{tx: tx, dbi: dbi, from: from, to: to}).seq()
+}
+
+// Reverse is like Range but in reverse key order, from the last record with
+// a key < to to the first with a key >= from.
+func (tx *Tx) Reverse(dbi DBI, from, to []byte) (iter.Seq2[[]byte, []byte], func() error) {
+	return (&cursorRange{tx: tx, dbi: dbi, from: from, to: to, reverse: true}).seq()
+}
+
+// Prefix returns the records of dbi with keys starting with prefix. The keys
+// of dbi must be ordered by bytes.Compare.
+func (tx *Tx) Prefix(dbi DBI, prefix []byte) (iter.Seq2[[]byte, []byte], func() error) {
+	return (&cursorRange{tx: tx, dbi: dbi, from: prefix, prefix: prefix}).seq()
+}
+
+// Dups returns the values of key in the DBDupSort database dbi, in value
+// order.
+func (tx *Tx) Dups(dbi DBI, key []byte) (iter.Seq2[[]byte, []byte], func() error) {
+	return (&cursorRange{tx: tx, dbi: dbi, from: key, dups: true}).seq()
+}
+
+func (r *cursorRange) seq() (iter.Seq2[[]byte, []byte], func() error) {
+	return func(yield func(key, data []byte) bool) {
+			r.err = r.run(yield)
+		}, func() error {
+			return r.err
+		}
+}
+
+// empty reports whether from is not below to, which leaves Range and Reverse
+// without records. It is the only comparison made in Go.
+func (r *cursorRange) empty() (bool, error) {
+	if r.dups || r.prefix != nil || r.from == nil || r.to == nil {
+		return false, nil
+	}
+	flags, _, err := r.tx.DBIFlags(r.dbi)
+	if err != nil {
+		return false, err
+	}
+	return KeyComparator(flags)(r.from, r.to) >= 0, nil
+}
+
+// stop returns the key at which the loop stops: the first key >= to going
+// forward, the last key < from in reverse. It is nil if the loop runs to the
+// end of the database.
+func (r *cursorRange) stop(cursor *Cursor) ([]byte, error) {
+	var key, data Val
+	var err error
+	switch {
+	case r.dups || r.prefix != nil:
+		return nil, nil
+	case r.reverse && r.from != nil:
+		key = Bytes(&r.from)
+		err = cursor.Get(&key, &data, CursorSetLowerBound)
+		switch {
+		case err == nil:
+			err = cursor.Get(&key, &data, CursorPrev)
+		case err == ErrNotFound:
+			err = cursor.Get(&key, &data, CursorLast)
+		}
+	case !r.reverse && r.to != nil:
+		key = Bytes(&r.to)
+		err = cursor.Get(&key, &data, CursorSetLowerBound)
+	default:
+		return nil, nil
+	}
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key.Bytes(), nil
+}
+
+func (r *cursorRange) run(yield func(key, data []byte) bool) error {
+	if empty, err := r.empty(); empty || err != nil {
+		return err
+	}
+
+	cursor, err := r.tx.OpenCursor(r.dbi)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	stop, err := r.stop(cursor)
+	if err != nil {
+		return err
+	}
+
+	var key, data Val
+	next := CursorNext
+	switch {
+	case r.dups:
+		key = Bytes(&r.from)
+		err = cursor.Get(&key, &data, CursorSetKey)
+		next = CursorNextDup
+	case r.reverse:
+		next = CursorPrev
+		if r.to == nil {
+			err = cursor.Get(&key, &data, CursorLast)
+			break
+		}
+		key = Bytes(&r.to)
+		err = cursor.Get(&key, &data, CursorSetLowerBound)
+		switch {
+		case err == nil:
+			err = cursor.Get(&key, &data, CursorPrev)
+		case err == ErrNotFound:
+			err = cursor.Get(&key, &data, CursorLast)
+		}
+	case r.from != nil:
+		key = Bytes(&r.from)
+		err = cursor.Get(&key, &data, CursorSetLowerBound)
+	default:
+		err = cursor.Get(&key, &data, CursorFirst)
+	}
+
+	for ; err == nil; err = cursor.Get(&key, &data, next) {
+		k := key.UnsafeBytes()
+		if stop != nil && bytes.Equal(k, stop) {
+			return nil
+		}
+		if r.prefix != nil && !bytes.HasPrefix(k, r.prefix) {
+			return nil
+		}
+		if !yield(k, data.UnsafeBytes()) {
+			return nil
+		}
+	}
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
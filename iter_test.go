@@ -0,0 +1,104 @@
+//go:build go1.23
+
+package mdbx
+
+import (
+	"iter"
+	"strings"
+	"testing"
+)
+
+func TestIter(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "iter", DBDefaults)
+	dups := openTestDBI(t, store, "iter-dups", DBDupSort)
+	if err := store.Update(func(tx *Tx) error {
+		for _, k := range []string{"a", "b", "ba", "bb", "c", "d"} {
+			key, data := StringConst(k), StringConst("v"+k)
+			if err := tx.Put(dbi, &key, &data, 0); err != nil {
+				return err
+			}
+		}
+		for _, kv := range []string{"a=3", "a=1", "a=2", "b=1"} {
+			key, data := StringConst(kv[:1]), StringConst(kv[2:])
+			if err := tx.Put(dups, &key, &data, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func(seq iter.Seq2[[]byte, []byte], errf func() error) string {
+		var records []string
+		for k, v := range seq {
+			records = append(records, string(k)+"="+string(v))
+		}
+		if err := errf(); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(records, " ")
+	}
+	if err := store.View(func(tx *Tx) error {
+		type records struct {
+			seq  iter.Seq2[[]byte, []byte]
+			errf func() error
+		}
+		of := func(seq iter.Seq2[[]byte, []byte], errf func() error) records {
+			return records{seq, errf}
+		}
+		for _, tc := range []struct {
+			name string
+			it   records
+			want string
+		}{
+			{"All", of(tx.All(dbi)), "a=va b=vb ba=vba bb=vbb c=vc d=vd"},
+			{"Range", of(tx.Range(dbi, []byte("b"), []byte("c"))), "b=vb ba=vba bb=vbb"},
+			{"Range/open", of(tx.Range(dbi, []byte("bb"), nil)), "bb=vbb c=vc d=vd"},
+			{"Range/missing", of(tx.Range(dbi, []byte("aa"), []byte("bab"))), "b=vb ba=vba"},
+			{"Range/empty", of(tx.Range(dbi, []byte("e"), nil)), ""},
+			{"Range/inverted", of(tx.Range(dbi, []byte("c"), []byte("b"))), ""},
+			{"Range/between", of(tx.Range(dbi, []byte("bab"), []byte("bac"))), ""},
+			{"Reverse", of(tx.Reverse(dbi, []byte("b"), []byte("c"))), "bb=vbb ba=vba b=vb"},
+			{"Reverse/open", of(tx.Reverse(dbi, nil, []byte("ba"))), "b=vb a=va"},
+			{"Reverse/all", of(tx.Reverse(dbi, nil, nil)), "d=vd c=vc bb=vbb ba=vba b=vb a=va"},
+			{"Reverse/past", of(tx.Reverse(dbi, []byte("c"), []byte("z"))), "d=vd c=vc"},
+			{"Reverse/inverted", of(tx.Reverse(dbi, []byte("c"), []byte("b"))), ""},
+			{"Reverse/dups", of(tx.Reverse(dups, []byte("b"), nil)), "b=1"},
+			{"Prefix", of(tx.Prefix(dbi, []byte("b"))), "b=vb ba=vba bb=vbb"},
+			{"Prefix/none", of(tx.Prefix(dbi, []byte("bc"))), ""},
+			{"Dups", of(tx.Dups(dups, []byte("a"))), "a=1 a=2 a=3"},
+			{"Dups/single", of(tx.Dups(dups, []byte("b"))), "b=1"},
+			{"Dups/missing", of(tx.Dups(dups, []byte("c"))), ""},
+			{"Range/dups", of(tx.Range(dups, nil, []byte("b"))), "a=1 a=2 a=3"},
+		} {
+			if got := collect(tc.it.seq, tc.it.errf); got != tc.want {
+				t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+			}
+		}
+
+		// Breaking out of a loop closes the cursor and the records can be
+		// ranged over again.
+		all, _ := tx.All(dbi)
+		for i := 0; i < 3; i++ {
+			for k := range all {
+				if string(k) != "a" {
+					t.Fatalf("got %q, want a", k)
+				}
+				break
+			}
+		}
+
+		invalid, errf := tx.All(DBI(1000))
+		for range invalid {
+			t.Fatal("record of invalid dbi")
+		}
+		if errf() == nil {
+			t.Fatal("expected error for invalid dbi")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+// Package kv abstracts the transactions of an mdbx Store behind interfaces
+// so that code can run against a real environment or against the in-memory
+// backend returned by NewMemory, e.g. in unit tests.
+//
+// Both backends take and return mdbx.Val, mdbx.DBI and the mdbx flags and
+// report the same errors: ErrNotFound and ErrKeyExist bare, everything else
+// as an *mdbx.OpError.
+//
+//	func Count(store kv.Store, dbi mdbx.DBI) (n int, err error) {
+//		err = store.View(func(tx kv.Tx) error {
+//			var stat mdbx.Stats
+//			if err := tx.DBIStat(dbi, &stat); err != nil {
+//				return err
+//			}
+//			n = int(stat.Entries)
+//			return nil
+//		})
+//		return
+//	}
+package kv
+
+import (
+	"github.com/moontrade/mdbx-go"
+)
+
+// Store runs transactions. Update runs fn in a write transaction and commits
+// it if fn returns nil, View runs fn in a read-only transaction.
+type Store interface {
+	Update(fn func(tx Tx) error) error
+	View(fn func(tx Tx) error) error
+	Close() error
+}
+
+// Tx is the subset of the methods of *mdbx.Tx both backends implement.
+type Tx interface {
+	ID() uint64
+	OpenDBI(name string, flags mdbx.DBFlags) (mdbx.DBI, error)
+	DBIStat(dbi mdbx.DBI, stat *mdbx.Stats) error
+	Drop(dbi mdbx.DBI, del bool) error
+	Get(dbi mdbx.DBI, key *mdbx.Val, data *mdbx.Val) error
+	Put(dbi mdbx.DBI, key *mdbx.Val, data *mdbx.Val, flags mdbx.PutFlags) error
+	Delete(dbi mdbx.DBI, key *mdbx.Val, data *mdbx.Val) error
+	OpenCursor(dbi mdbx.DBI) (Cursor, error)
+}
+
+// Cursor is the subset of the methods of *mdbx.Cursor both backends
+// implement.
+type Cursor interface {
+	Get(key *mdbx.Val, data *mdbx.Val, op mdbx.CursorOp) error
+	Put(key *mdbx.Val, data *mdbx.Val, flags mdbx.PutFlags) error
+	Delete(flags mdbx.PutFlags) error
+	Count() (int, error)
+	Close() error
+}
+
+// New returns a Store running its transactions on store. Closing it closes
+// store.
+func New(store *mdbx.Store) Store {
+	return mdbxStore{store}
+}
+
+type mdbxStore struct {
+	store *mdbx.Store
+}
+
+func (s mdbxStore) Update(fn func(tx Tx) error) error {
+	return s.store.Update(func(tx *mdbx.Tx) error {
+		return fn(mdbxTx{tx})
+	})
+}
+
+func (s mdbxStore) View(fn func(tx Tx) error) error {
+	return s.store.View(func(tx *mdbx.Tx) error {
+		return fn(mdbxTx{tx})
+	})
+}
+
+func (s mdbxStore) Close() error {
+	return s.store.Close()
+}
+
+type mdbxTx struct {
+	*mdbx.Tx
+}
+
+func (tx mdbxTx) OpenCursor(dbi mdbx.DBI) (Cursor, error) {
+	cursor, err := tx.Tx.OpenCursor(dbi)
+	if err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
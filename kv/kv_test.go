@@ -0,0 +1,443 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moontrade/mdbx-go"
+)
+
+func openMDBX(t testing.TB) Store {
+	t.Helper()
+	store, err := mdbx.Open(t.TempDir(), mdbx.EnvSafeNoSync, 0664, func(env *mdbx.Env, create bool) error {
+		if err := env.SetGeometry(mdbx.Geometry{
+			SizeLower:  1024 * 1024,
+			SizeNow:    1024 * 1024,
+			SizeUpper:  1024 * 1024 * 256,
+			GrowthStep: 1024 * 1024,
+			PageSize:   4096,
+		}); err != nil {
+			return err
+		}
+		return env.SetMaxDBS(16)
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return New(store)
+}
+
+func backends(t *testing.T, fn func(t *testing.T, store Store)) {
+	t.Run("mdbx", func(t *testing.T) {
+		fn(t, openMDBX(t))
+	})
+	t.Run("memory", func(t *testing.T) {
+		store := NewMemory()
+		t.Cleanup(func() {
+			_ = store.Close()
+		})
+		fn(t, store)
+	})
+}
+
+func TestStore_Backends(t *testing.T) {
+	backends(t, func(t *testing.T, store Store) {
+		var dbi mdbx.DBI
+		if err := store.Update(func(tx Tx) (err error) {
+			if dbi, err = tx.OpenDBI("kv", mdbx.DBDupSort|mdbx.DBCreate); err != nil {
+				return err
+			}
+			for _, kv := range [][2]string{{"b", "2"}, {"a", "1"}, {"b", "1"}, {"c", "3"}} {
+				k, v := mdbx.StringConst(kv[0]), mdbx.StringConst(kv[1])
+				if err = tx.Put(dbi, &k, &v, 0); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.View(func(tx Tx) error {
+			if _, err := tx.OpenDBI("kv", mdbx.DBIntegerKey); !errors.Is(err, mdbx.ErrIncompatible) {
+				t.Fatalf("expected ErrIncompatible, got %v", err)
+			}
+			k, v := mdbx.StringConst("b"), mdbx.Val{}
+			if err := tx.Put(dbi, &k, &v, 0); !errors.Is(err, mdbx.ErrEACCESS) {
+				t.Fatalf("expected ErrEACCESS, got %v", err)
+			}
+			if err := tx.Get(dbi, &k, &v); err != nil || v.String() != "1" {
+				t.Fatalf("get returned %q, %v", v.String(), err)
+			}
+			cursor, err := tx.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close()
+			if err = cursor.Get(&k, &v, mdbx.CursorSet); err != nil {
+				return err
+			}
+			if n, err := cursor.Count(); n != 2 || err != nil {
+				t.Fatalf("count %d, %v", n, err)
+			}
+			var got []string
+			for err = cursor.Get(&k, &v, mdbx.CursorFirst); err == nil; err = cursor.Get(&k, &v, mdbx.CursorNext) {
+				got = append(got, k.String()+v.String())
+			}
+			if err != mdbx.ErrNotFound {
+				return err
+			}
+			if fmt.Sprint(got) != "[a1 b1 b2 c3]" {
+				t.Fatalf("records %v", got)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestMemory_Snapshot(t *testing.T) {
+	store := NewMemory()
+	put := func(tx Tx, dbi mdbx.DBI, key, value string) error {
+		k, v := mdbx.StringConst(key), mdbx.StringConst(value)
+		return tx.Put(dbi, &k, &v, 0)
+	}
+	get := func(tx Tx, dbi mdbx.DBI, key string) string {
+		k, v := mdbx.StringConst(key), mdbx.Val{}
+		if err := tx.Get(dbi, &k, &v); err != nil {
+			return err.Error()
+		}
+		return v.String()
+	}
+
+	var dbi mdbx.DBI
+	if err := store.Update(func(tx Tx) (err error) {
+		if dbi, err = tx.OpenDBI("kv", mdbx.DBCreate); err != nil {
+			return err
+		}
+		return put(tx, dbi, "a", "1")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	read, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = store.View(func(tx Tx) error {
+			read <- struct{}{}
+			<-read
+			if v := get(tx, dbi, "a"); v != "1" {
+				t.Errorf("snapshot sees %q", v)
+			}
+			return nil
+		})
+	}()
+	<-read
+	if err := store.Update(func(tx Tx) error {
+		if err := put(tx, dbi, "a", "2"); err != nil {
+			return err
+		}
+		if v := get(tx, dbi, "a"); v != "2" {
+			t.Errorf("write transaction sees %q", v)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(func(tx Tx) error {
+		if err := put(tx, dbi, "a", "3"); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	}); err == nil {
+		t.Fatal("expected the error of fn")
+	}
+	read <- struct{}{}
+	<-done
+
+	if err := store.View(func(tx Tx) error {
+		if v := get(tx, dbi, "a"); v != "2" {
+			t.Errorf("committed value %q", v)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.View(func(tx Tx) error { return nil }); err != os.ErrClosed {
+		t.Fatalf("expected os.ErrClosed, got %v", err)
+	}
+}
+
+// diffFlags are the databases the differential test runs against.
+var diffFlags = []mdbx.DBFlags{
+	mdbx.DBDefaults,
+	mdbx.DBReverseKey,
+	mdbx.DBIntegerKey,
+	mdbx.DBDupSort,
+	mdbx.DBDupSort | mdbx.DBReverseDup | mdbx.DBReverseKey,
+	mdbx.DBDupSort | mdbx.DBDupFixed,
+	mdbx.DBDupSort | mdbx.DBDupFixed | mdbx.DBIntegerGroup | mdbx.DBIntegerKey,
+}
+
+var diffPutFlags = []mdbx.PutFlags{
+	mdbx.PutUpsert, mdbx.PutNoOverwrite, mdbx.PutNoDupData, mdbx.PutAppend,
+	mdbx.PutAppendDup, mdbx.PutAppend | mdbx.PutAppendDup, mdbx.PutCurrent,
+	mdbx.PutAllDups, mdbx.PutReserve,
+}
+
+var diffCursorOps = []mdbx.CursorOp{
+	mdbx.CursorFirst, mdbx.CursorLast, mdbx.CursorNext, mdbx.CursorPrev,
+	mdbx.CursorNextDup, mdbx.CursorPrevDup, mdbx.CursorNextNoDup, mdbx.CursorPrevNoDup,
+	mdbx.CursorFirstDup, mdbx.CursorLastDup, mdbx.CursorGetCurrent,
+	mdbx.CursorSet, mdbx.CursorSetKey, mdbx.CursorSetRange, mdbx.CursorGetBoth,
+	mdbx.CursorGetBothRange, mdbx.CursorSetLowerBound, mdbx.CursorSetUpperBound,
+}
+
+// result describes the outcome of an operation for comparison.
+func result(err error, vals ...*mdbx.Val) string {
+	if err != nil {
+		var rc mdbx.Error
+		if !errors.As(err, &rc) {
+			return err.Error()
+		}
+		if _, bare := err.(mdbx.Error); bare {
+			return "bare " + rc.Error()
+		}
+		return rc.Error()
+	}
+	s := "ok"
+	for _, v := range vals {
+		s += fmt.Sprintf(" %x", v.UnsafeBytes())
+	}
+	return s
+}
+
+type diffOp struct {
+	kind   int
+	key    []byte
+	value  []byte
+	flags  mdbx.PutFlags
+	op     mdbx.CursorOp
+	nodata bool
+}
+
+func (o diffOp) String() string {
+	return fmt.Sprintf("kind %d key %x value %x flags %#x op %d nodata %v", o.kind, o.key, o.value, o.flags, o.op, o.nodata)
+}
+
+// apply runs o in tx with the cursor of the transaction.
+func (o diffOp) apply(tx Tx, dbi mdbx.DBI, cursor Cursor) string {
+	key, value := append([]byte(nil), o.key...), append([]byte(nil), o.value...)
+	k, v := mdbx.Bytes(&key), mdbx.Bytes(&value)
+	switch o.kind {
+	case 0:
+		// mdbx decides PutAllDups and PutCurrent by whether a key ever had
+		// more than one value rather than by its current values, so they
+		// are left to cursor operations.
+		err := tx.Put(dbi, &k, &v, o.flags&^(mdbx.PutAllDups|mdbx.PutCurrent))
+		if err == nil && o.flags&mdbx.PutReserve != 0 {
+			copy(v.UnsafeBytes(), o.value)
+			return result(err)
+		}
+		if err == mdbx.ErrKeyExist && o.flags&mdbx.PutNoOverwrite != 0 {
+			return result(err) + " " + result(nil, &v)
+		}
+		return result(err)
+	case 1:
+		if o.nodata {
+			return result(tx.Delete(dbi, &k, nil))
+		}
+		return result(tx.Delete(dbi, &k, &v))
+	case 2:
+		err := tx.Get(dbi, &k, &v)
+		return result(err, &v)
+	case 3:
+		err := cursor.Get(&k, &v, o.op)
+		if err == nil && (o.op == mdbx.CursorFirstDup || o.op == mdbx.CursorLastDup) {
+			// mdbx leaves key alone if the key ever had more than one value.
+			return result(err) + " " + result(nil, &v)
+		}
+		if err == nil {
+			return result(err) + " " + result(nil, &k, &v)
+		}
+		return result(err)
+	case 4:
+		flags := o.flags & (mdbx.PutCurrent | mdbx.PutNoOverwrite | mdbx.PutNoDupData | mdbx.PutUpsert)
+		err := cursor.Put(&k, &v, flags)
+		if err != nil {
+			return result(err)
+		}
+		k, v = mdbx.Val{}, mdbx.Val{}
+		return result(cursor.Get(&k, &v, mdbx.CursorGetCurrent), &k, &v)
+	case 5:
+		return result(cursor.Delete(o.flags & mdbx.PutAllDups))
+	default:
+		n, err := cursor.Count()
+		return fmt.Sprint(n, " ", result(err))
+	}
+}
+
+// repositioning are the cursor operations that do not depend on the
+// position of the cursor. CursorSetUpperBound is left out: mdbx does not
+// step past an exact match on the first record of a page when a failed
+// operation left the cursor past the end.
+var repositioning = []mdbx.CursorOp{
+	mdbx.CursorFirst, mdbx.CursorLast, mdbx.CursorSet, mdbx.CursorSetKey, mdbx.CursorSetRange,
+	mdbx.CursorGetBoth, mdbx.CursorGetBothRange, mdbx.CursorSetLowerBound,
+}
+
+// randomOp returns a random operation. The backends only agree on the
+// position of a cursor after it moved or put successfully, so with
+// reposition set cursor operations reposition the cursor, and after a
+// delete through the cursor they reposition it or move to the next record.
+func randomOp(rng *rand.Rand, flags mdbx.DBFlags, reposition, deleted bool) diffOp {
+	o := diffOp{
+		kind:   rng.Intn(7),
+		flags:  diffPutFlags[rng.Intn(len(diffPutFlags))],
+		op:     diffCursorOps[rng.Intn(len(diffCursorOps))],
+		nodata: rng.Intn(2) == 0,
+	}
+	if (reposition || deleted) && o.kind >= 3 {
+		o.kind, o.op = 3, repositioning[rng.Intn(len(repositioning))]
+		if deleted && rng.Intn(2) == 0 {
+			o.op = mdbx.CursorNext
+		}
+	}
+	if flags&mdbx.DBIntegerKey != 0 {
+		o.key = make([]byte, 8)
+		binary.LittleEndian.PutUint64(o.key, uint64(rng.Intn(8))<<uint(rng.Intn(2)*40))
+	} else {
+		o.key = make([]byte, rng.Intn(4))
+		for i := range o.key {
+			o.key[i] = byte('a' + rng.Intn(3))
+		}
+	}
+	switch {
+	case flags&mdbx.DBIntegerGroup != 0:
+		o.value = make([]byte, 4)
+		binary.LittleEndian.PutUint32(o.value, uint32(rng.Intn(5))<<uint(rng.Intn(2)*20))
+	case flags&mdbx.DBDupFixed != 0:
+		o.value = []byte{byte('a' + rng.Intn(3)), byte('a' + rng.Intn(3))}
+	default:
+		o.value = make([]byte, rng.Intn(4))
+		for i := range o.value {
+			o.value[i] = byte('a' + rng.Intn(3))
+		}
+	}
+	return o
+}
+
+func scan(tx Tx, dbi mdbx.DBI) (string, error) {
+	cursor, err := tx.OpenCursor(dbi)
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close()
+	var b bytes.Buffer
+	k, v := mdbx.Val{}, mdbx.Val{}
+	for err = cursor.Get(&k, &v, mdbx.CursorFirst); err == nil; err = cursor.Get(&k, &v, mdbx.CursorNext) {
+		fmt.Fprintf(&b, "%x=%x ", k.UnsafeBytes(), v.UnsafeBytes())
+	}
+	if err != mdbx.ErrNotFound {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// TestMemory_Differential runs random operations on the mdbx and the memory
+// backend and compares their results.
+func TestMemory_Differential(t *testing.T) {
+	stores := []Store{openMDBX(t), NewMemory()}
+	rng := rand.New(rand.NewSource(1))
+	rounds := 300
+	if testing.Short() {
+		rounds = 30
+	}
+
+	for i, flags := range diffFlags {
+		name := fmt.Sprintf("db%d", i)
+		for round := 0; round < rounds; round++ {
+			n := 1 + rng.Intn(40)
+			var records [2]string
+			err := stores[0].Update(func(tx0 Tx) error {
+				return stores[1].Update(func(tx1 Tx) error {
+					txs, cursors := [2]Tx{tx0, tx1}, [2]Cursor{}
+					dbis := [2]mdbx.DBI{}
+					for s, tx := range txs {
+						dbi, err := tx.OpenDBI(name, flags|mdbx.DBCreate)
+						if err != nil {
+							return err
+						}
+						if err = tx.Drop(dbi, false); err != nil {
+							return err
+						}
+						if cursors[s], err = tx.OpenCursor(dbi); err != nil {
+							return err
+						}
+						defer cursors[s].Close()
+						dbis[s] = dbi
+					}
+
+					reposition, deleted, put := true, false, false
+					var ops []diffOp
+					for j := 0; j < n; j++ {
+						o := randomOp(rng, flags, reposition, deleted)
+						ops = append(ops, o)
+						r0, r1 := o.apply(txs[0], dbis[0], cursors[0]), o.apply(txs[1], dbis[1], cursors[1])
+						if r0 != r1 {
+							for _, o := range ops {
+								t.Log(o)
+							}
+							for s, tx := range txs {
+								records, _ := scan(tx, dbis[s])
+								t.Log(records)
+							}
+							return fmt.Errorf("flags %v: mdbx %q, memory %q", flags, r0, r1)
+						}
+						ok := strings.HasPrefix(r0, "ok") || strings.HasPrefix(r0, "MDBX_RESULT_TRUE")
+						switch o.kind {
+						case 0, 1:
+							reposition, deleted = true, false
+						case 3, 4, 5:
+							reposition, deleted = !ok, o.kind == 5 && ok
+							// mdbx keeps flags of the previous position of a
+							// cursor across a put, e.g. the end of the
+							// database after CursorLast, so a delete after a
+							// cursor put does not keep its position.
+							if deleted && put {
+								reposition, deleted = true, false
+							}
+							put = o.kind == 4 && ok
+						}
+					}
+					var err error
+					for s, tx := range txs {
+						if records[s], err = scan(tx, dbis[s]); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if records[0] != records[1] {
+				t.Fatalf("flags %v: records differ\nmdbx   %s\nmemory %s", flags, records[0], records[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,774 @@
+package kv
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/moontrade/mdbx-go"
+)
+
+// mainDBI is the handle of the unnamed database, as in mdbx.
+const mainDBI = mdbx.DBI(1)
+
+// persistentFlags are the DBFlags a database is created with.
+const persistentFlags = mdbx.DBReverseKey | mdbx.DBDupSort | mdbx.DBIntegerKey |
+	mdbx.DBDupFixed | mdbx.DBIntegerGroup | mdbx.DBReverseDup
+
+// NewMemory returns an empty Store that keeps its databases in memory.
+//
+// Records are ordered by mdbx.KeyComparator and mdbx.DataComparator of the
+// flags a database was created with and put, delete and cursor operations
+// follow the semantics of mdbx, including the errors they return. Write
+// transactions are serialized and copy a database on its first modification,
+// read transactions see the last database versions committed before they
+// began.
+//
+// The memory backend is meant for tests with small databases: a modified
+// database is copied once per transaction and puts and deletes move the
+// records after the modified one. Named databases are not stored as records
+// of the unnamed database, DBIStat only sets Entries and ModTxnID, and
+// CursorGetMultiple, CursorNextMultiple and CursorPrevMultiple are not
+// supported.
+func NewMemory() Store {
+	return &memStore{
+		state: &memState{dbs: map[mdbx.DBI]*memDB{
+			mainDBI: newMemDB("", 0),
+		}},
+		names: map[string]mdbx.DBI{"": mainDBI},
+	}
+}
+
+type memStore struct {
+	state   *memState
+	names   map[string]mdbx.DBI
+	closed  bool
+	writeMu sync.Mutex
+	mu      sync.Mutex
+}
+
+// memState is a committed version of the databases. It is never modified
+// once it was committed.
+type memState struct {
+	txID uint64
+	dbs  map[mdbx.DBI]*memDB
+}
+
+func (s *memStore) Update(fn func(tx Tx) error) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	base := s.state
+	s.mu.Unlock()
+
+	tx := &memTx{
+		store: s,
+		state: &memState{txID: base.txID + 1, dbs: make(map[mdbx.DBI]*memDB, len(base.dbs))},
+		owned: make(map[mdbx.DBI]bool),
+		write: true,
+	}
+	for dbi, db := range base.dbs {
+		tx.state.dbs[dbi] = db
+	}
+	defer tx.end()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.state = tx.state
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memStore) View(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	tx := &memTx{store: s, state: s.state}
+	s.mu.Unlock()
+	defer tx.end()
+	return fn(tx)
+}
+
+func (s *memStore) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	s.closed = true
+	s.state = nil
+	return nil
+}
+
+// dbi returns the handle of the database called name, assigning a new one
+// if create is set.
+func (s *memStore) dbi(name string, create bool) (mdbx.DBI, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dbi, ok := s.names[name]
+	if !ok && create {
+		dbi = mdbx.DBI(len(s.names) + 1)
+		s.names[name] = dbi
+		ok = true
+	}
+	return dbi, ok
+}
+
+type memRecord struct {
+	key, data []byte
+}
+
+type memDB struct {
+	name     string
+	flags    mdbx.DBFlags
+	keyCmp   func(a, b []byte) int
+	dataCmp  func(a, b []byte) int
+	records  []memRecord
+	modTxnID uint64
+}
+
+func newMemDB(name string, flags mdbx.DBFlags) *memDB {
+	flags &= persistentFlags
+	return &memDB{
+		name:    name,
+		flags:   flags,
+		keyCmp:  mdbx.KeyComparator(flags),
+		dataCmp: mdbx.DataComparator(flags),
+	}
+}
+
+func (db *memDB) dupSort() bool {
+	return db.flags&mdbx.DBDupSort != 0
+}
+
+// lowerBound returns the index of the first record with a key not ordered
+// before key.
+func (db *memDB) lowerBound(key []byte) int {
+	return sort.Search(len(db.records), func(i int) bool {
+		return db.keyCmp(db.records[i].key, key) >= 0
+	})
+}
+
+// upperBound returns the index of the first record with a key ordered after
+// key.
+func (db *memDB) upperBound(key []byte) int {
+	return sort.Search(len(db.records), func(i int) bool {
+		return db.keyCmp(db.records[i].key, key) > 0
+	})
+}
+
+// seek returns the index of the first record not ordered before key and
+// data, and whether it matches them. The data of records in databases
+// without DBDupSort is ignored.
+func (db *memDB) seek(key, data []byte) (int, bool) {
+	if !db.dupSort() {
+		i := db.lowerBound(key)
+		return i, i < len(db.records) && db.keyCmp(db.records[i].key, key) == 0
+	}
+	i := sort.Search(len(db.records), func(i int) bool {
+		r := &db.records[i]
+		if c := db.keyCmp(r.key, key); c != 0 {
+			return c > 0
+		}
+		return db.dataCmp(r.data, data) >= 0
+	})
+	return i, i < len(db.records) && db.keyCmp(db.records[i].key, key) == 0 &&
+		db.dataCmp(db.records[i].data, data) == 0
+}
+
+// keyRange returns the records of key as records[lo:hi].
+func (db *memDB) keyRange(key []byte) (lo, hi int) {
+	lo = db.lowerBound(key)
+	if lo == len(db.records) || db.keyCmp(db.records[lo].key, key) != 0 {
+		return lo, lo
+	}
+	return lo, db.upperBound(key)
+}
+
+func (db *memDB) insert(i int, r memRecord) {
+	db.records = append(db.records, memRecord{})
+	copy(db.records[i+1:], db.records[i:])
+	db.records[i] = r
+}
+
+func (db *memDB) remove(lo, hi int) {
+	db.records = append(db.records[:lo], db.records[hi:]...)
+}
+
+// check validates the sizes of a key and value for the flags of db.
+func (db *memDB) check(key, data []byte) mdbx.Error {
+	if db.flags&mdbx.DBIntegerKey != 0 && len(key) != 4 && len(key) != 8 {
+		return mdbx.ErrBadValSize
+	}
+	if db.flags&mdbx.DBIntegerGroup != 0 && len(data) != 4 && len(data) != 8 {
+		return mdbx.ErrBadValSize
+	}
+	if db.flags&mdbx.DBDupFixed != 0 && len(db.records) > 0 && len(db.records[0].data) != len(data) {
+		return mdbx.ErrBadValSize
+	}
+	return mdbx.ErrSuccess
+}
+
+// put stores key and data with flags and returns the index of the record.
+// On ErrKeyExist it returns the index of the existing record.
+func (db *memDB) put(key []byte, data *mdbx.Val, flags mdbx.PutFlags) (int, mdbx.Error) {
+	value := valBytes(data)
+	if flags&mdbx.PutReserve != 0 {
+		if db.dupSort() {
+			return 0, mdbx.ErrIncompatible
+		}
+		value = make([]byte, data.Len)
+	}
+	if rc := db.check(key, value); rc != mdbx.ErrSuccess {
+		return 0, rc
+	}
+	lo, hi := db.keyRange(key)
+	exists := lo < hi
+	switch {
+	case flags&mdbx.PutNoOverwrite != 0 && exists:
+		*data = val(db.records[lo].data)
+		return lo, mdbx.ErrKeyExist
+	case flags&mdbx.PutCurrent != 0:
+		if !exists {
+			return 0, mdbx.ErrNotFound
+		}
+		if db.dupSort() && flags&mdbx.PutAllDups == 0 {
+			if hi-lo > 1 {
+				return 0, mdbx.ErrEMultiVal
+			}
+			db.remove(lo, hi)
+			hi = lo
+		}
+	case flags&mdbx.PutAppend != 0 && len(db.records) > 0:
+		c := db.keyCmp(key, db.records[len(db.records)-1].key)
+		if c < 0 || (c == 0 && flags&mdbx.PutAppendDup == 0) {
+			return 0, mdbx.ErrEKeyMismatch
+		}
+	}
+	if !db.dupSort() {
+		r := memRecord{key: clone(key), data: value}
+		if flags&mdbx.PutReserve == 0 {
+			r.data = clone(value)
+		}
+		if exists {
+			db.records[lo] = r
+		} else {
+			db.insert(lo, r)
+		}
+		if flags&mdbx.PutReserve != 0 {
+			*data = val(r.data)
+		}
+		return lo, mdbx.ErrSuccess
+	}
+
+	if flags&mdbx.PutAllDups != 0 {
+		db.remove(lo, hi)
+		hi = lo
+	}
+	if flags&mdbx.PutAppendDup != 0 && lo < hi && db.dataCmp(value, db.records[hi-1].data) <= 0 {
+		return 0, mdbx.ErrEKeyMismatch
+	}
+	i, exact := db.seek(key, value)
+	if exact {
+		if flags&mdbx.PutNoDupData != 0 {
+			return i, mdbx.ErrKeyExist
+		}
+		return i, mdbx.ErrSuccess
+	}
+	db.insert(i, memRecord{key: clone(key), data: clone(value)})
+	return i, mdbx.ErrSuccess
+}
+
+type memTx struct {
+	store *memStore
+	state *memState
+	owned map[mdbx.DBI]bool
+	write bool
+	done  bool
+}
+
+func (tx *memTx) end() {
+	tx.done = true
+}
+
+func (tx *memTx) ID() uint64 {
+	return tx.state.txID
+}
+
+// db returns the database dbi for an operation, or the result code the
+// operation fails with.
+func (tx *memTx) db(dbi mdbx.DBI, write bool) (*memDB, mdbx.Error) {
+	if tx.done {
+		return nil, mdbx.ErrBadTXN
+	}
+	db := tx.state.dbs[dbi]
+	if db == nil {
+		return nil, mdbx.ErrBadDBI
+	}
+	if !write {
+		return db, mdbx.ErrSuccess
+	}
+	if !tx.write {
+		return nil, mdbx.ErrEACCESS
+	}
+	if !tx.owned[dbi] {
+		c := *db
+		c.records = append([]memRecord(nil), db.records...)
+		db = &c
+		tx.state.dbs[dbi] = db
+		tx.owned[dbi] = true
+	}
+	db.modTxnID = tx.state.txID
+	return db, mdbx.ErrSuccess
+}
+
+func (tx *memTx) OpenDBI(name string, flags mdbx.DBFlags) (mdbx.DBI, error) {
+	if tx.done {
+		return 0, dbiOpenError(name, mdbx.ErrBadTXN)
+	}
+	dbi, ok := tx.store.dbi(name, flags&mdbx.DBCreate != 0)
+	if !ok {
+		return 0, dbiOpenError(name, mdbx.ErrNotFound)
+	}
+	db := tx.state.dbs[dbi]
+	if db == nil {
+		if flags&mdbx.DBCreate == 0 {
+			return 0, dbiOpenError(name, mdbx.ErrNotFound)
+		}
+		if !tx.write {
+			return 0, dbiOpenError(name, mdbx.ErrEACCESS)
+		}
+		db = newMemDB(name, flags)
+		db.modTxnID = tx.state.txID
+		tx.state.dbs[dbi] = db
+		tx.owned[dbi] = true
+		return dbi, nil
+	}
+	// An existing database is opened with the flags it was created with, if
+	// no or exactly these flags are given. It is recreated with other flags
+	// if it is empty and DBCreate is given.
+	if (flags^db.flags)&persistentFlags == 0 || flags&^mdbx.DBAccede == 0 {
+		return dbi, nil
+	}
+	if flags&mdbx.DBCreate == 0 || len(db.records) > 0 {
+		return 0, dbiOpenError(name, mdbx.ErrIncompatible)
+	}
+	if !tx.write {
+		return 0, dbiOpenError(name, mdbx.ErrEACCESS)
+	}
+	db = newMemDB(name, flags)
+	db.modTxnID = tx.state.txID
+	tx.state.dbs[dbi] = db
+	tx.owned[dbi] = true
+	return dbi, nil
+}
+
+func (tx *memTx) DBIStat(dbi mdbx.DBI, stat *mdbx.Stats) error {
+	db, rc := tx.db(dbi, false)
+	if rc != mdbx.ErrSuccess {
+		return tx.opError("dbi_stat", dbi, nil, rc)
+	}
+	*stat = mdbx.Stats{Entries: uint64(len(db.records)), ModTxnID: db.modTxnID}
+	return nil
+}
+
+func (tx *memTx) Drop(dbi mdbx.DBI, del bool) error {
+	db, rc := tx.db(dbi, true)
+	if rc != mdbx.ErrSuccess {
+		return tx.opError("drop", dbi, nil, rc)
+	}
+	if del && dbi != mainDBI {
+		delete(tx.state.dbs, dbi)
+		delete(tx.owned, dbi)
+		return nil
+	}
+	db.records = nil
+	return nil
+}
+
+func (tx *memTx) Get(dbi mdbx.DBI, key *mdbx.Val, data *mdbx.Val) error {
+	db, rc := tx.db(dbi, false)
+	if rc != mdbx.ErrSuccess {
+		return tx.opError("get", dbi, key, rc)
+	}
+	lo, hi := db.keyRange(valBytes(key))
+	if lo == hi {
+		return tx.opError("get", dbi, key, mdbx.ErrNotFound)
+	}
+	*data = val(db.records[lo].data)
+	return nil
+}
+
+func (tx *memTx) Put(dbi mdbx.DBI, key *mdbx.Val, data *mdbx.Val, flags mdbx.PutFlags) error {
+	db, rc := tx.db(dbi, true)
+	if rc == mdbx.ErrSuccess {
+		_, rc = db.put(valBytes(key), data, flags)
+	}
+	return tx.opError("put", dbi, key, rc)
+}
+
+func (tx *memTx) Delete(dbi mdbx.DBI, key *mdbx.Val, data *mdbx.Val) error {
+	db, rc := tx.db(dbi, true)
+	if rc != mdbx.ErrSuccess {
+		return tx.opError("del", dbi, key, rc)
+	}
+	lo, hi := db.keyRange(valBytes(key))
+	if data != nil {
+		i, exact := db.seek(valBytes(key), valBytes(data))
+		if !db.dupSort() {
+			exact = exact && db.dataCmp(db.records[i].data, valBytes(data)) == 0
+		}
+		if !exact {
+			return tx.opError("del", dbi, key, mdbx.ErrNotFound)
+		}
+		lo, hi = i, i+1
+	}
+	if lo == hi {
+		return tx.opError("del", dbi, key, mdbx.ErrNotFound)
+	}
+	db.remove(lo, hi)
+	return nil
+}
+
+func (tx *memTx) OpenCursor(dbi mdbx.DBI) (Cursor, error) {
+	if _, rc := tx.db(dbi, false); rc != mdbx.ErrSuccess {
+		return nil, tx.opError("cursor_open", dbi, nil, rc)
+	}
+	return &memCursor{tx: tx, dbi: dbi}, nil
+}
+
+// opError converts a result code into an error like the mdbx backend does.
+func (tx *memTx) opError(op string, dbi mdbx.DBI, key *mdbx.Val, rc mdbx.Error) error {
+	err := opError(op, dbi, key, rc)
+	if e, ok := err.(*mdbx.OpError); ok {
+		if db := tx.state.dbs[dbi]; db != nil {
+			e.Name = db.name
+		}
+	}
+	return err
+}
+
+// memCursor is positioned at a record by its key and data rather than by
+// its index, so that it stays in place when the records before it change.
+// After the record was deleted the cursor is between its neighbours, a
+// cursor moved past either end stays at its record.
+type memCursor struct {
+	tx     *memTx
+	dbi    mdbx.DBI
+	key    []byte
+	data   []byte
+	set    bool
+	del    bool
+	closed bool
+}
+
+func (c *memCursor) db(write bool) (*memDB, mdbx.Error) {
+	if c.closed {
+		return nil, mdbx.ErrEINVAL
+	}
+	return c.tx.db(c.dbi, write)
+}
+
+// pos returns the index of the current record, or of the record after it if
+// it was deleted, and whether it still exists.
+func (c *memCursor) pos(db *memDB) (int, bool) {
+	return db.seek(c.key, c.data)
+}
+
+// at positions the cursor at record i, or reports ErrNotFound and leaves it in
+// place if i is out of range.
+func (c *memCursor) at(db *memDB, i int, key, data *mdbx.Val) mdbx.Error {
+	if i < 0 || i >= len(db.records) {
+		return mdbx.ErrNotFound
+	}
+	r := db.records[i]
+	c.key, c.data, c.set, c.del = r.key, r.data, true, false
+	*key, *data = val(r.key), val(r.data)
+	return mdbx.ErrSuccess
+}
+
+func (c *memCursor) Get(key *mdbx.Val, data *mdbx.Val, op mdbx.CursorOp) error {
+	db, rc := c.db(false)
+	if rc == mdbx.ErrSuccess {
+		rc = c.get(db, key, data, op)
+	}
+	if rc == mdbx.ErrSuccess || rc == mdbx.ErrResultTrue {
+		return nil
+	}
+	if !keyed(op) {
+		key = nil
+	}
+	return opError("cursor_get", c.dbi, key, rc)
+}
+
+func (c *memCursor) get(db *memDB, key *mdbx.Val, data *mdbx.Val, op mdbx.CursorOp) mdbx.Error {
+	n := len(db.records)
+	switch op {
+	case mdbx.CursorFirst:
+		return c.at(db, 0, key, data)
+	case mdbx.CursorLast:
+		return c.at(db, n-1, key, data)
+	case mdbx.CursorSet, mdbx.CursorSetKey:
+		lo, hi := db.keyRange(valBytes(key))
+		if lo == hi {
+			return mdbx.ErrNotFound
+		}
+		return c.at(db, lo, key, data)
+	case mdbx.CursorSetRange:
+		return c.at(db, db.lowerBound(valBytes(key)), key, data)
+	case mdbx.CursorGetBoth, mdbx.CursorGetBothRange:
+		if !db.dupSort() {
+			return mdbx.ErrIncompatible
+		}
+		i, exact := db.seek(valBytes(key), valBytes(data))
+		if !exact && (op == mdbx.CursorGetBoth || i == n || db.keyCmp(db.records[i].key, valBytes(key)) != 0) {
+			return mdbx.ErrNotFound
+		}
+		return c.at(db, i, key, data)
+	case mdbx.CursorSetLowerBound, mdbx.CursorSetUpperBound:
+		i, exact := db.seek(valBytes(key), valBytes(data))
+		if op == mdbx.CursorSetUpperBound && exact {
+			i++
+		}
+		if rc := c.at(db, i, key, data); rc != mdbx.ErrSuccess || exact || op == mdbx.CursorSetUpperBound {
+			return rc
+		}
+		return mdbx.ErrResultTrue
+	case mdbx.CursorGetCurrent:
+		if !c.set {
+			return mdbx.ErrENODAT
+		}
+	case mdbx.CursorFirstDup, mdbx.CursorLastDup:
+		if !db.dupSort() {
+			return mdbx.ErrIncompatible
+		}
+		if !c.set {
+			return mdbx.ErrEINVAL
+		}
+	case mdbx.CursorNext, mdbx.CursorNextDup, mdbx.CursorNextNoDup:
+		if c.del && op == mdbx.CursorNextDup {
+			return mdbx.ErrNotFound
+		}
+		if !c.set {
+			return c.at(db, 0, key, data)
+		}
+	case mdbx.CursorPrev, mdbx.CursorPrevDup, mdbx.CursorPrevNoDup:
+		if c.del && op == mdbx.CursorPrevDup {
+			return mdbx.ErrNotFound
+		}
+		if !c.set {
+			return c.at(db, n-1, key, data)
+		}
+	default:
+		return mdbx.ErrEINVAL
+	}
+
+	// The remaining operations move relative to the current record. Without
+	// DBDupSort every key has a single value and the dup operations move
+	// between keys like their plain counterparts.
+	if !db.dupSort() {
+		switch op {
+		case mdbx.CursorNextDup:
+			op = mdbx.CursorNext
+		case mdbx.CursorPrevDup:
+			op = mdbx.CursorPrev
+		}
+	}
+	i, exact := c.pos(db)
+	switch op {
+	case mdbx.CursorGetCurrent:
+		if !exact {
+			return mdbx.ErrNotFound
+		}
+		return c.at(db, i, key, data)
+	case mdbx.CursorNext, mdbx.CursorNextDup:
+		if exact {
+			i++
+		}
+		if i < n && op == mdbx.CursorNextDup && db.keyCmp(db.records[i].key, c.key) != 0 {
+			return mdbx.ErrNotFound
+		}
+		return c.at(db, i, key, data)
+	case mdbx.CursorPrev, mdbx.CursorPrevDup:
+		if i > 0 && op == mdbx.CursorPrevDup && db.keyCmp(db.records[i-1].key, c.key) != 0 {
+			return mdbx.ErrNotFound
+		}
+		return c.at(db, i-1, key, data)
+	case mdbx.CursorNextNoDup:
+		return c.at(db, db.upperBound(c.key), key, data)
+	case mdbx.CursorPrevNoDup:
+		return c.at(db, db.lowerBound(c.key)-1, key, data)
+	default: // CursorFirstDup, CursorLastDup
+		lo, hi := db.keyRange(c.key)
+		if lo == hi {
+			return mdbx.ErrNotFound
+		}
+		i := lo
+		if op == mdbx.CursorLastDup {
+			i = hi - 1
+		}
+		// Like mdbx, only set key if it has a single value.
+		if hi-lo > 1 {
+			k := *key
+			rc := c.at(db, i, key, data)
+			*key = k
+			return rc
+		}
+		return c.at(db, i, key, data)
+	}
+}
+
+func (c *memCursor) Put(key *mdbx.Val, data *mdbx.Val, flags mdbx.PutFlags) error {
+	db, rc := c.db(true)
+	if rc == mdbx.ErrSuccess {
+		rc = c.put(db, key, data, flags)
+	}
+	return opError("cursor_put", c.dbi, key, rc)
+}
+
+func (c *memCursor) put(db *memDB, key *mdbx.Val, data *mdbx.Val, flags mdbx.PutFlags) mdbx.Error {
+	if flags&mdbx.PutCurrent != 0 {
+		if !c.set {
+			return mdbx.ErrEINVAL
+		}
+		i, exact := c.pos(db)
+		if !exact {
+			return mdbx.ErrNotFound
+		}
+		if db.keyCmp(valBytes(key), c.key) != 0 {
+			return mdbx.ErrEKeyMismatch
+		}
+		if db.dupSort() && flags&mdbx.PutAllDups == 0 {
+			// Replace the current value, which moves it among the values of
+			// the key.
+			value := valBytes(data)
+			if rc := db.check(c.key, value); rc != mdbx.ErrSuccess {
+				return rc
+			}
+			removed := db.records[i]
+			db.remove(i, i+1)
+			j, exact := db.seek(c.key, value)
+			if !exact {
+				db.insert(j, memRecord{key: removed.key, data: clone(value)})
+			}
+			var k, v mdbx.Val
+			return c.at(db, j, &k, &v)
+		}
+		flags &^= mdbx.PutCurrent
+		if !db.dupSort() {
+			flags |= mdbx.PutCurrent
+		}
+	}
+	i, rc := db.put(valBytes(key), data, flags)
+	if rc == mdbx.ErrSuccess || rc == mdbx.ErrKeyExist {
+		var k, v mdbx.Val
+		c.at(db, i, &k, &v)
+	}
+	return rc
+}
+
+func (c *memCursor) Delete(flags mdbx.PutFlags) error {
+	db, rc := c.db(true)
+	if rc == mdbx.ErrSuccess {
+		rc = c.delete(db, flags)
+	}
+	return opError("cursor_del", c.dbi, nil, rc)
+}
+
+func (c *memCursor) delete(db *memDB, flags mdbx.PutFlags) mdbx.Error {
+	if !c.set {
+		return mdbx.ErrENODAT
+	}
+	i, exact := c.pos(db)
+	if !exact {
+		return mdbx.ErrNotFound
+	}
+	c.del = true
+	if flags&(mdbx.PutAllDups|mdbx.PutNoDupData) != 0 {
+		lo, hi := db.keyRange(c.key)
+		db.remove(lo, hi)
+		return mdbx.ErrSuccess
+	}
+	db.remove(i, i+1)
+	return mdbx.ErrSuccess
+}
+
+func (c *memCursor) Count() (int, error) {
+	db, rc := c.db(false)
+	if rc == mdbx.ErrSuccess && !c.set {
+		rc = mdbx.ErrEINVAL
+	}
+	if rc != mdbx.ErrSuccess {
+		return 0, opError("cursor_count", c.dbi, nil, rc)
+	}
+	lo, hi := db.keyRange(c.key)
+	return hi - lo, nil
+}
+
+func (c *memCursor) Close() error {
+	c.closed = true
+	return nil
+}
+
+// opError converts a result code into an error like the mdbx backend does:
+// nil on success, ErrNotFound and ErrKeyExist bare and an *mdbx.OpError for
+// every other code.
+func opError(op string, dbi mdbx.DBI, key *mdbx.Val, rc mdbx.Error) error {
+	switch rc {
+	case mdbx.ErrSuccess:
+		return nil
+	case mdbx.ErrNotFound, mdbx.ErrKeyExist:
+		return rc
+	}
+	e := &mdbx.OpError{Op: op, DBI: dbi, Err: rc}
+	if key != nil && key.Base != nil {
+		b := valBytes(key)
+		if len(b) > 16 {
+			b = b[:16]
+		}
+		e.Key = clone(b)
+	}
+	return e
+}
+
+func dbiOpenError(name string, rc mdbx.Error) error {
+	err := opError("dbi_open", 0, nil, rc)
+	if e, ok := err.(*mdbx.OpError); ok {
+		e.Name = name
+	}
+	return err
+}
+
+// keyed reports whether the key is an input of the cursor operation.
+func keyed(op mdbx.CursorOp) bool {
+	switch op {
+	case mdbx.CursorSet, mdbx.CursorSetKey, mdbx.CursorSetRange, mdbx.CursorGetBoth,
+		mdbx.CursorGetBothRange, mdbx.CursorSetLowerBound, mdbx.CursorSetUpperBound:
+		return true
+	}
+	return false
+}
+
+func valBytes(v *mdbx.Val) []byte {
+	if v == nil || v.Base == nil {
+		return nil
+	}
+	return v.UnsafeBytes()
+}
+
+func val(b []byte) mdbx.Val {
+	return mdbx.Bytes(&b)
+}
+
+func clone(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
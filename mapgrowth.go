@@ -0,0 +1,129 @@
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// MapGrowth is an opt-in policy for Store.Update to recover from ErrMapFull
+// and ErrUnableExtendMapSize. The failed transaction is aborted, the upper
+// bound of the datafile is raised with SetGeometry, and the closure is run
+// again. The closure must therefore be safe to re-run.
+type MapGrowth struct {
+	// Factor multiplies the current upper bound. Defaults to 2.
+	Factor float64 `json:"factor,omitempty"`
+
+	// Step is the minimum amount the upper bound grows by.
+	Step Size `json:"step,omitempty"`
+
+	// Ceiling is the limit the upper bound is never raised above. Required.
+	Ceiling Size `json:"ceiling"`
+
+	// OnGrow is called after the upper bound was raised.
+	OnGrow func(event MapGrowthEvent) `json:"-"`
+}
+
+// MapGrowthEvent reports a change of the datafile upper bound.
+type MapGrowthEvent struct {
+	Previous uint64 // Upper bound before growing
+	Upper    uint64 // New upper bound
+	Ceiling  uint64 // Configured ceiling
+	Err      error  // Error that triggered the growth
+}
+
+func (p *MapGrowth) validate() error {
+	if p.Ceiling <= 0 {
+		return fmt.Errorf("%w: map_growth ceiling is required", ErrInvalidOptions)
+	}
+	if p.Factor != 0 && p.Factor <= 1 && p.Step <= 0 {
+		return fmt.Errorf("%w: map_growth needs a factor above 1 or a step", ErrInvalidOptions)
+	}
+	if p.Step < 0 {
+		return fmt.Errorf("%w: map_growth step is negative", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// next returns the grown upper bound or false if the ceiling was reached.
+func (p *MapGrowth) next(upper uint64) (uint64, bool) {
+	ceiling := uint64(p.Ceiling)
+	if upper >= ceiling {
+		return upper, false
+	}
+	factor := p.Factor
+	if factor == 0 {
+		factor = 2
+	}
+	next := upper
+	if factor > 1 {
+		next = uint64(float64(upper) * factor)
+	}
+	if min := upper + uint64(p.Step); next < min {
+		next = min
+	}
+	if next > ceiling {
+		next = ceiling
+	}
+	return next, next > upper
+}
+
+// SetMapGrowth installs the map growth policy used by Update. A nil policy
+// disables it.
+func (s *Store) SetMapGrowth(policy *MapGrowth) error {
+	if policy != nil {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		p := *policy
+		policy = &p
+	}
+	s.writeMu.Lock()
+	s.mapGrowth = policy
+	s.writeMu.Unlock()
+	return nil
+}
+
+func isMapFull(err error) bool {
+	return errors.Is(err, ErrMapFull) || errors.Is(err, ErrUnableExtendMapSize)
+}
+
+// growMap raises the upper bound of the datafile according to the policy.
+// It must be called with writeMu held and no write transaction running.
+func (s *Store) growMap(cause error) bool {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	tx := Tx{}
+	if s.env.Begin(&tx, TxReadOnly) != nil {
+		return false
+	}
+	var info EnvInfo
+	err := tx.EnvInfo(&info)
+	_ = tx.Abort()
+	if err != nil {
+		return false
+	}
+	upper, ok := s.mapGrowth.next(info.Geo.Upper)
+	if !ok {
+		return false
+	}
+	if s.env.SetGeometry(Geometry{
+		SizeLower:       ^uintptr(0),
+		SizeNow:         ^uintptr(0),
+		SizeUpper:       uintptr(upper),
+		GrowthStep:      ^uintptr(0),
+		ShrinkThreshold: ^uintptr(0),
+		PageSize:        ^uintptr(0),
+	}) != nil {
+		return false
+	}
+	if s.mapGrowth.OnGrow != nil {
+		s.mapGrowth.OnGrow(MapGrowthEvent{
+			Previous: info.Geo.Upper,
+			Upper:    upper,
+			Ceiling:  uint64(s.mapGrowth.Ceiling),
+			Err:      cause,
+		})
+	}
+	return true
+}
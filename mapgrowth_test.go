@@ -0,0 +1,85 @@
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func openSmallStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(t.TempDir(), EnvSafeNoSync, 0664, func(env *Env, create bool) error {
+		if err := env.SetGeometry(Geometry{
+			SizeLower:       1 << 20,
+			SizeNow:         1 << 20,
+			SizeUpper:       1 << 20,
+			GrowthStep:      1 << 20,
+			ShrinkThreshold: 0,
+			PageSize:        4096,
+		}); err != nil {
+			return err
+		}
+		return env.SetMaxDBS(4)
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+func fillStore(store *Store, dbi DBI, n int) error {
+	value := make([]byte, 1024)
+	return store.Update(func(tx *Tx) error {
+		for i := 0; i < n; i++ {
+			k, v := StringConst(fmt.Sprintf("key-%06d", i)), sliceVal(value)
+			if err := tx.Put(dbi, &k, &v, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestStore_MapGrowth(t *testing.T) {
+	store := openSmallStore(t)
+	dbi := openTestDBI(t, store, "kv", 0)
+
+	if err := fillStore(store, dbi, 3000); !errors.Is(err, ErrMapFull) {
+		t.Fatalf("expected ErrMapFull without a policy, got %v", err)
+	}
+
+	var events []MapGrowthEvent
+	if err := store.SetMapGrowth(&MapGrowth{
+		Ceiling: 16 << 20,
+		OnGrow: func(event MapGrowthEvent) {
+			events = append(events, event)
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fillStore(store, dbi, 3000); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || events[0].Previous != 1<<20 || events[0].Upper != 2<<20 || !errors.Is(events[0].Err, ErrMapFull) {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if last := events[len(events)-1]; last.Upper > 16<<20 {
+		t.Fatalf("grew above the ceiling: %+v", last)
+	}
+
+	// Growth stops at the ceiling.
+	events = nil
+	if err := fillStore(store, dbi, 40000); !errors.Is(err, ErrMapFull) {
+		t.Fatalf("expected ErrMapFull at the ceiling, got %v", err)
+	}
+	if len(events) == 0 || events[len(events)-1].Upper != 16<<20 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	if err := store.SetMapGrowth(&MapGrowth{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected invalid policy, got %v", err)
+	}
+}
@@ -865,6 +865,57 @@ void do_mdbx_estimate_distance(size_t arg0, size_t arg1) {
 //	int32_t result;
 //} mdbx_estimate_move_t;
 
+// mdbx_dbi_open_cmp calls the deprecated mdbx_dbi_open_ex without a warning.
+static int mdbx_dbi_open_cmp(MDBX_txn *txn, const char *name, MDBX_db_flags_t flags,
+                             MDBX_dbi *dbi, MDBX_cmp_func *keycmp, MDBX_cmp_func *datacmp) {
+#pragma GCC diagnostic push
+#pragma GCC diagnostic ignored "-Wdeprecated-declarations"
+	return mdbx_dbi_open_ex(txn, name, flags, dbi, keycmp, datacmp);
+#pragma GCC diagnostic pop
+}
+
+typedef struct mdbx_reader_t {
+	int32_t slot;
+	int32_t pid;
+	uint64_t thread;
+	uint64_t txnid;
+	uint64_t lag;
+	uint64_t bytes_used;
+	uint64_t bytes_retained;
+} mdbx_reader_t;
+
+typedef struct mdbx_reader_list_t {
+	mdbx_reader_t* readers;
+	int32_t cap;
+	int32_t count;
+} mdbx_reader_list_t;
+
+static int mdbx_reader_list_collect(void *ctx, int num, int slot, mdbx_pid_t pid,
+                                    mdbx_tid_t thread, uint64_t txnid,
+                                    uint64_t lag, size_t bytes_used,
+                                    size_t bytes_retained) {
+	mdbx_reader_list_t* list = (mdbx_reader_list_t*)ctx;
+	if (list->count >= list->cap) {
+		return -1;
+	}
+	mdbx_reader_t* r = &list->readers[list->count++];
+	r->slot = (int32_t)slot;
+	r->pid = (int32_t)pid;
+	r->thread = (uint64_t)(uintptr_t)thread;
+	r->txnid = txnid;
+	r->lag = lag;
+	r->bytes_used = (uint64_t)bytes_used;
+	r->bytes_retained = (uint64_t)bytes_retained;
+	return 0;
+}
+
+static int mdbx_reader_list_all(MDBX_env* env, mdbx_reader_t* readers, int32_t cap, int32_t* count) {
+	mdbx_reader_list_t list = {readers, cap, 0};
+	int rc = mdbx_reader_list(env, mdbx_reader_list_collect, &list);
+	*count = list.count;
+	return rc;
+}
+
 */
 import "C"
 import (
@@ -2076,10 +2127,13 @@ const (
 // \retval MDBX_RESULT_TRUE   No corresponding files or directories were found,
 //
 //	so no deletion was performed.
-func Delete(path string, mode DeleteMode) Error {
+func Delete(path string, mode DeleteMode) error {
 	p := C.CString(path)
 	defer C.free(unsafe.Pointer(p))
-	return Error(C.mdbx_env_delete(p, (C.MDBX_env_delete_mode_t)(mode)))
+	if err := Error(C.mdbx_env_delete(p, (C.MDBX_env_delete_mode_t)(mode))); err != ErrResultTrue {
+		return opError("env_delete", err)
+	}
+	return nil
 }
 
 //////////////////////////////////////////////////////////////////////////////////////////
@@ -2091,9 +2145,34 @@ type Env struct {
 	opened int64
 	info   EnvInfo
 	closed int64
+	names  map[DBI]string
 	mu     sync.Mutex
 }
 
+// DBIName returns the name a DBI handle was opened with. The main database
+// has an empty name. The second result is false if the handle is unknown.
+func (env *Env) DBIName(dbi DBI) (string, bool) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	name, ok := env.names[dbi]
+	return name, ok
+}
+
+func (env *Env) setDBIName(dbi DBI, name string) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if env.names == nil {
+		env.names = make(map[DBI]string)
+	}
+	env.names[dbi] = name
+}
+
+func (env *Env) removeDBIName(dbi DBI) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	delete(env.names, dbi)
+}
+
 // NewEnv \brief Create an MDBX environment instance.
 // \ingroup c_opening
 //
@@ -2108,13 +2187,13 @@ type Env struct {
 // \param [out] penv  The address where the new handle will be stored.
 //
 // \returns a non-zero error value on failure and 0 on success.
-func NewEnv() (*Env, Error) {
+func NewEnv() (*Env, error) {
 	env := &Env{}
 	err := Error(C.mdbx_env_create((**C.MDBX_env)(unsafe.Pointer(&env.env))))
 	if err != ErrSuccess {
-		return nil, err
+		return nil, opError("env_create", err)
 	}
-	return env, err
+	return env, nil
 }
 
 // FD returns the open file descriptor (or Windows file handle) for the given
@@ -2130,9 +2209,8 @@ func (env *Env) FD() (uintptr, error) {
 
 	var mf C.mdbx_filehandle_t
 	err := Error(C.mdbx_env_get_fd(env.env, &mf))
-	//err := operrno("mdbx_env_get_fd", ret)
 	if err != ErrSuccess {
-		return 0, err
+		return 0, opError("env_get_fd", err)
 	}
 	fd := uintptr(mf)
 
@@ -2142,30 +2220,49 @@ func (env *Env) FD() (uintptr, error) {
 	return fd, nil
 }
 
-// ReaderList dumps the contents of the reader lock table as text.  Readers
-// start on the second line as space-delimited fields described by the first
-// line.
+// ReaderInfo describes an active entry in the reader lock table.
+type ReaderInfo struct {
+	Slot          int    // Reader slot number
+	PID           int    // Process ID of the reader
+	Thread        uint64 // Thread ID of the reader
+	TxnID         uint64 // Snapshot transaction ID the reader is using
+	Lag           uint64 // Number of transactions committed since the reader started
+	BytesUsed     uint64 // Size of the database snapshot in use
+	BytesRetained uint64 // Space that can't be reclaimed while the reader is active
+}
+
+// ReaderList returns the active entries of the reader lock table.
 //
 // See mdbx_reader_list.
-//func (env *Env) ReaderList(fn func(string) error) error {
-//	ctx, done := newMsgFunc(fn)
-//	defer done()
-//	if fn == nil {
-//		ctx = 0
-//	}
-//
-//	ret := C.mdbxgo_reader_list(env._env, C.size_t(ctx))
-//	if ret >= 0 {
-//		return nil
-//	}
-//	if ret < 0 && ctx != 0 {
-//		err := ctx.get().err
-//		if err != nil {
-//			return err
-//		}
-//	}
-//	return operrno("mdbx_reader_list", ret)
-//}
+func (env *Env) ReaderList() ([]ReaderInfo, error) {
+	slots, err := env.GetMaxReaders()
+	if err != nil {
+		return nil, err
+	}
+	if slots == 0 {
+		slots = 1
+	}
+	readers := make([]C.mdbx_reader_t, slots)
+	var count C.int32_t
+	rc := Error(C.mdbx_reader_list_all(env.env, &readers[0], C.int32_t(len(readers)), &count))
+	if rc != ErrSuccess && rc != ErrResultTrue {
+		return nil, opError("reader_list", rc)
+	}
+	list := make([]ReaderInfo, int(count))
+	for i := range list {
+		r := &readers[i]
+		list[i] = ReaderInfo{
+			Slot:          int(r.slot),
+			PID:           int(r.pid),
+			Thread:        uint64(r.thread),
+			TxnID:         uint64(r.txnid),
+			Lag:           uint64(r.lag),
+			BytesUsed:     uint64(r.bytes_used),
+			BytesRetained: uint64(r.bytes_retained),
+		}
+	}
+	return list, nil
+}
 
 // ReaderCheck clears stale entries from the reader lock table and returns the
 // number of entries cleared.
@@ -2175,7 +2272,7 @@ func (env *Env) ReaderCheck() (int, error) {
 	var dead C.int
 	err := Error(C.mdbx_reader_check(env.env, &dead))
 	if err != ErrSuccess {
-		return int(dead), err
+		return int(dead), opError("reader_check", err)
 	}
 	return int(dead), nil
 }
@@ -2188,7 +2285,7 @@ func (env *Env) Path() (string, error) {
 	var cpath *C.char
 	err := Error(C.mdbx_env_get_path(env.env, &cpath))
 	if err != ErrSuccess {
-		return "", err
+		return "", opError("env_get_path", err)
 	}
 	if cpath == nil {
 		return "", os.ErrNotExist
@@ -2251,18 +2348,18 @@ func (env *Env) MaxKeySize() int {
 //	proper manner.
 //
 // \retval MDBX_EIO    An error occurred during synchronization.
-func (env *Env) Close(dontSync bool) Error {
+func (env *Env) Close(dontSync bool) error {
 	env.mu.Lock()
 	defer env.mu.Unlock()
 	if env.closed > 0 {
-		return ErrSuccess
+		return nil
 	}
 	err := Error(C.mdbx_env_close_ex(env.env, (C.bool)(dontSync)))
 	if err != ErrSuccess {
-		return err
+		return opError("env_close", err)
 	}
 	env.closed = time.Now().UnixNano()
-	return err
+	return nil
 }
 
 // SetFlags Set environment flags.
@@ -2289,8 +2386,8 @@ func (env *Env) Close(dontSync bool) Error {
 //	some possible errors are:
 //
 // \retval MDBX_EINVAL  An invalid parameter was specified.
-func (env *Env) SetFlags(flags EnvFlags, onoff bool) Error {
-	return Error(C.mdbx_env_set_flags(env.env, (C.MDBX_env_flags_t)(flags), (C.bool)(onoff)))
+func (env *Env) SetFlags(flags EnvFlags, onoff bool) error {
+	return opError("env_set_flags", Error(C.mdbx_env_set_flags(env.env, (C.MDBX_env_flags_t)(flags), (C.bool)(onoff))))
 }
 
 // GetFlags Get environment flags.
@@ -2305,10 +2402,10 @@ func (env *Env) SetFlags(flags EnvFlags, onoff bool) Error {
 //	some possible errors are:
 //
 // \retval MDBX_EINVAL An invalid parameter was specified.
-func (env *Env) GetFlags() (EnvFlags, Error) {
+func (env *Env) GetFlags() (EnvFlags, error) {
 	flags := C.unsigned(0)
 	err := Error(C.mdbx_env_get_flags(env.env, &flags))
-	return EnvFlags(flags), err
+	return EnvFlags(flags), opError("env_get_flags", err)
 }
 
 // Copy an MDBX environment to the specified path, with options.
@@ -2344,13 +2441,13 @@ func (env *Env) GetFlags() (EnvFlags, Error) {
 //	    Force to make resizeable copy, i.e. dynamic size instead of fixed.
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (env *Env) Copy(dest string, flags CopyFlags) Error {
+func (env *Env) Copy(dest string, flags CopyFlags) error {
 	if env.env == nil {
-		return ErrSuccess
+		return nil
 	}
 	d := C.CString(dest)
 	defer C.free(unsafe.Pointer(d))
-	return Error(C.mdbx_env_copy(env.env, d, (C.MDBX_copy_flags_t)(flags)))
+	return opError("env_copy", Error(C.mdbx_env_copy(env.env, d, (C.MDBX_copy_flags_t)(flags))))
 }
 
 // Open \brief Open an environment instance.
@@ -2443,9 +2540,9 @@ func (env *Env) Copy(dest string, flags CopyFlags) Error {
 // \retval MDBX_TOO_LARGE      Database is too large for this process,
 //
 //	i.e. 32-bit process tries to open >4Gb database.
-func (env *Env) Open(path string, flags EnvFlags, mode os.FileMode) Error {
+func (env *Env) Open(path string, flags EnvFlags, mode os.FileMode) error {
 	if env.opened > 0 {
-		return ErrSuccess
+		return nil
 	}
 
 	p := C.CString(path)
@@ -2458,11 +2555,11 @@ func (env *Env) Open(path string, flags EnvFlags, mode os.FileMode) Error {
 		(C.mdbx_mode_t)(mode),
 	))
 	if err != ErrSuccess {
-		return err
+		return opError("env_open", err)
 	}
 
 	env.opened = time.Now().UnixNano()
-	return err
+	return nil
 }
 
 type Geometry struct {
@@ -2676,11 +2773,11 @@ type Geometry struct {
 //
 //	given size, or a 32-bit process requests too much
 //	bytes for the 32-bit address space.
-func (env *Env) SetGeometry(args Geometry) Error {
+func (env *Env) SetGeometry(args Geometry) error {
 	args.env = uintptr(unsafe.Pointer(env.env))
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_env_set_geometry), ptr, 0)
-	return args.err
+	return opError("env_set_geometry", args.err)
 }
 
 // GetOption \brief Gets the value of runtime options from an environment.
@@ -2693,14 +2790,14 @@ func (env *Env) SetGeometry(args Geometry) Error {
 // \see MDBX_option_t
 // \see mdbx_env_get_option()
 // \returns A non-zero error value on failure and 0 on success.
-func (env *Env) GetOption(option Opt) (uint64, Error) {
+func (env *Env) GetOption(option Opt) (uint64, error) {
 	value := uint64(0)
 	err := Error(C.mdbx_env_get_option(
 		(*C.MDBX_env)(unsafe.Pointer(env.env)),
 		(C.MDBX_option_t)(option),
 		(*C.uint64_t)(unsafe.Pointer(&value))),
 	)
-	return value, err
+	return value, opError("env_get_option", err)
 }
 
 // SetOption \brief Sets the value of a runtime options for an environment.
@@ -2713,12 +2810,12 @@ func (env *Env) GetOption(option Opt) (uint64, Error) {
 // \see MDBX_option_t
 // \see mdbx_env_get_option()
 // \returns A non-zero error value on failure and 0 on success.
-func (env *Env) SetOption(option Opt, value uint64) Error {
-	return Error(C.mdbx_env_set_option(
+func (env *Env) SetOption(option Opt, value uint64) error {
+	return opError("env_set_option", Error(C.mdbx_env_set_option(
 		(*C.MDBX_env)(unsafe.Pointer(env.env)),
 		(C.MDBX_option_t)(option),
 		C.uint64_t(value)),
-	)
+	))
 }
 
 type EnvInfo struct {
@@ -2819,8 +2916,16 @@ type EnvInfo struct {
 //
 // \retval MDBX_EINVAL   an invalid parameter was specified.
 // \retval MDBX_EIO      an error occurred during synchronization.
-func (env *Env) Sync(force, nonblock bool) Error {
-	return Error(C.mdbx_env_sync_ex(env.env, (C.bool)(force), (C.bool)(nonblock)))
+func (env *Env) Sync(force, nonblock bool) error {
+	if faultsEnabled {
+		if rc := fault(FaultSync, 0); rc != ErrSuccess {
+			return opError("env_sync", rc)
+		}
+	}
+	if err := Error(C.mdbx_env_sync_ex(env.env, (C.bool)(force), (C.bool)(nonblock))); err != ErrResultTrue {
+		return opError("env_sync", err)
+	}
+	return nil
 }
 
 // CloseDBI Close a database handle. Normally unnecessary.
@@ -2845,8 +2950,12 @@ func (env *Env) Sync(force, nonblock bool) Error {
 // \param [in] dbi  A database handle returned by \ref mdbx_dbi_open().
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (env *Env) CloseDBI(dbi DBI) Error {
-	return Error(C.mdbx_dbi_close(env.env, (C.MDBX_dbi)(dbi)))
+func (env *Env) CloseDBI(dbi DBI) error {
+	err := Error(C.mdbx_dbi_close(env.env, (C.MDBX_dbi)(dbi)))
+	if err == ErrSuccess {
+		env.removeDBIName(dbi)
+	}
+	return dbiError("dbi_close", env, dbi, nil, err)
 }
 
 // GetMaxDBS Controls the maximum number of named databases for the environment.
@@ -2857,7 +2966,7 @@ func (env *Env) CloseDBI(dbi DBI) Error {
 // may only set after \ref mdbx_env_create() and before \ref mdbx_env_open().
 //
 // \see mdbx_env_set_maxdbs() \see mdbx_env_get_maxdbs()
-func (env *Env) GetMaxDBS() (uint64, Error) {
+func (env *Env) GetMaxDBS() (uint64, error) {
 	return env.GetOption(OptMaxDB)
 }
 
@@ -2869,7 +2978,7 @@ func (env *Env) GetMaxDBS() (uint64, Error) {
 // may only set after \ref mdbx_env_create() and before \ref mdbx_env_open().
 //
 // \see mdbx_env_set_maxdbs() \see mdbx_env_get_maxdbs()
-func (env *Env) SetMaxDBS(max uint16) Error {
+func (env *Env) SetMaxDBS(max uint16) error {
 	return env.SetOption(OptMaxDB, uint64(max))
 }
 
@@ -2887,7 +2996,7 @@ func (env *Env) SetMaxDBS(max uint16) Error {
 // the first process interacts with the database.
 //
 // \see mdbx_env_set_maxreaders() \see mdbx_env_get_maxreaders()
-func (env *Env) GetMaxReaders() (uint64, Error) {
+func (env *Env) GetMaxReaders() (uint64, error) {
 	return env.GetOption(OptMaxReaders)
 }
 
@@ -2905,7 +3014,7 @@ func (env *Env) GetMaxReaders() (uint64, Error) {
 // the first process interacts with the database.
 //
 // \see mdbx_env_set_maxreaders() \see mdbx_env_get_maxreaders()
-func (env *Env) SetMaxReaders(max uint64) Error {
+func (env *Env) SetMaxReaders(max uint64) error {
 	return env.SetOption(OptMaxReaders, max)
 }
 
@@ -2913,7 +3022,7 @@ func (env *Env) SetMaxReaders(max uint64) Error {
 // buffers to disk, if \ref MDBX_SAFE_NOSYNC is used.
 //
 // \see mdbx_env_set_syncbytes() \see mdbx_env_get_syncbytes()
-func (env *Env) GetSyncBytes() (uint64, Error) {
+func (env *Env) GetSyncBytes() (uint64, error) {
 	return env.GetOption(OptSyncBytes)
 }
 
@@ -2921,7 +3030,7 @@ func (env *Env) GetSyncBytes() (uint64, Error) {
 // buffers to disk, if \ref MDBX_SAFE_NOSYNC is used.
 //
 // \see mdbx_env_set_syncbytes() \see mdbx_env_get_syncbytes()
-func (env *Env) SetSyncBytes(bytes uint64) Error {
+func (env *Env) SetSyncBytes(bytes uint64) error {
 	return env.SetOption(OptSyncBytes, bytes)
 }
 
@@ -2929,7 +3038,7 @@ func (env *Env) SetSyncBytes(bytes uint64) Error {
 // unsteady commit to force flush the data buffers to disk,
 // if \ref MDBX_SAFE_NOSYNC is used.
 // \see mdbx_env_set_syncperiod() \see mdbx_env_get_syncperiod()
-func (env *Env) GetSyncPeriod() (uint64, Error) {
+func (env *Env) GetSyncPeriod() (uint64, error) {
 	return env.GetOption(OptSyncPeriod)
 }
 
@@ -2937,7 +3046,7 @@ func (env *Env) GetSyncPeriod() (uint64, Error) {
 // unsteady commit to force flush the data buffers to disk,
 // if \ref MDBX_SAFE_NOSYNC is used.
 // \see mdbx_env_set_syncperiod() \see mdbx_env_get_syncperiod()
-func (env *Env) SetSyncPeriod(period uint64) Error {
+func (env *Env) SetSyncPeriod(period uint64) error {
 	return env.SetOption(OptSyncPeriod, period)
 }
 
@@ -2958,7 +3067,7 @@ func (env *Env) SetSyncPeriod(period uint64) Error {
 //
 // The `MDBX_opt_rp_augment_limit` controls described limit for the current
 // process. Default is 262144, it is usually enough for most cases.
-func (env *Env) GetRPAugmentLimit() (uint64, Error) {
+func (env *Env) GetRPAugmentLimit() (uint64, error) {
 	return env.GetOption(OptRpAugmentLimit)
 }
 
@@ -2979,7 +3088,7 @@ func (env *Env) GetRPAugmentLimit() (uint64, Error) {
 //
 // The `MDBX_opt_rp_augment_limit` controls described limit for the current
 // process. Default is 262144, it is usually enough for most cases.
-func (env *Env) SetRPAugmentLimit(limit uint64) Error {
+func (env *Env) SetRPAugmentLimit(limit uint64) error {
 	return env.SetOption(OptRpAugmentLimit, limit)
 }
 
@@ -2994,7 +3103,7 @@ func (env *Env) SetRPAugmentLimit(limit uint64) Error {
 //
 // The `MDBX_opt_loose_limit` allows you to set a limit for such cache inside
 // the current process. Should be in the range 0..255, default is 64.
-func (env *Env) GetLooseLimit() (uint64, Error) {
+func (env *Env) GetLooseLimit() (uint64, error) {
 	return env.GetOption(OptLooseLimit)
 }
 
@@ -3009,7 +3118,7 @@ func (env *Env) GetLooseLimit() (uint64, Error) {
 //
 // The `MDBX_opt_loose_limit` allows you to set a limit for such cache inside
 // the current process. Should be in the range 0..255, default is 64.
-func (env *Env) SetLooseLimit(limit uint64) Error {
+func (env *Env) SetLooseLimit(limit uint64) error {
 	return env.SetOption(OptLooseLimit, limit)
 }
 
@@ -3025,7 +3134,7 @@ func (env *Env) SetLooseLimit(limit uint64) Error {
 //
 // The `MDBX_opt_dp_reserve_limit` allows you to set a limit for such reserve
 // inside the current process. Default is 1024.
-func (env *Env) GetDPReserveLimit() (uint64, Error) {
+func (env *Env) GetDPReserveLimit() (uint64, error) {
 	return env.GetOption(OptDpReserveLimit)
 }
 
@@ -3041,7 +3150,7 @@ func (env *Env) GetDPReserveLimit() (uint64, Error) {
 //
 // The `MDBX_opt_dp_reserve_limit` allows you to set a limit for such reserve
 // inside the current process. Default is 1024.
-func (env *Env) SetDPReserveLimit(limit uint64) Error {
+func (env *Env) SetDPReserveLimit(limit uint64) error {
 	return env.SetOption(OptDpReserveLimit, limit)
 }
 
@@ -3057,7 +3166,7 @@ func (env *Env) SetDPReserveLimit(limit uint64) Error {
 //
 // The `MDBX_opt_txn_dp_limit` controls described threshold for the current
 // process. Default is 65536, it is usually enough for most cases.
-func (env *Env) GetTxDPLimit() (uint64, Error) {
+func (env *Env) GetTxDPLimit() (uint64, error) {
 	return env.GetOption(OptTxnDpLimit)
 }
 
@@ -3073,19 +3182,19 @@ func (env *Env) GetTxDPLimit() (uint64, Error) {
 //
 // The `MDBX_opt_txn_dp_limit` controls described threshold for the current
 // process. Default is 65536, it is usually enough for most cases.
-func (env *Env) SetTxDPLimit(limit uint64) Error {
+func (env *Env) SetTxDPLimit(limit uint64) error {
 	return env.SetOption(OptTxnDpLimit, limit)
 }
 
 // GetTxDPInitial Controls the in-process initial allocation size for dirty pages
 // list of a write transaction. Default is 1024.
-func (env *Env) GetTxDPInitial() (uint64, Error) {
+func (env *Env) GetTxDPInitial() (uint64, error) {
 	return env.GetOption(OptTxnDpInitial)
 }
 
 // SetTxDPInitial Controls the in-process initial allocation size for dirty pages
 // list of a write transaction. Default is 1024.
-func (env *Env) SetTxDPInitial(initial uint64) Error {
+func (env *Env) SetTxDPInitial(initial uint64) error {
 	return env.SetOption(OptTxnDpInitial, initial)
 }
 
@@ -3103,7 +3212,7 @@ func (env *Env) SetTxDPInitial(initial uint64) Error {
 // Should be in the range 0..255, where zero means no restriction at the
 // bottom. Default is 8, i.e. at least the 1/8 of the current dirty pages
 // should be spilled when reached the condition described above.
-func (env *Env) GetSpillMinDenominator() (uint64, Error) {
+func (env *Env) GetSpillMinDenominator() (uint64, error) {
 	return env.GetOption(OptSpillMinDenomiator)
 }
 
@@ -3121,7 +3230,7 @@ func (env *Env) GetSpillMinDenominator() (uint64, Error) {
 // Should be in the range 0..255, where zero means no restriction at the
 // bottom. Default is 8, i.e. at least the 1/8 of the current dirty pages
 // should be spilled when reached the condition described above.
-func (env *Env) SetSpillMinDenominator(min uint64) Error {
+func (env *Env) SetSpillMinDenominator(min uint64) error {
 	return env.SetOption(OptSpillMinDenomiator, min)
 }
 
@@ -3139,7 +3248,7 @@ func (env *Env) SetSpillMinDenominator(min uint64) Error {
 // Should be in the range 0..255, where zero means no limit, i.e. all dirty
 // pages could be spilled. Default is 8, i.e. no more than 7/8 of the current
 // dirty pages may be spilled when reached the condition described above.
-func (env *Env) GetSpillMaxDenominator() (uint64, Error) {
+func (env *Env) GetSpillMaxDenominator() (uint64, error) {
 	return env.GetOption(OptSpillMaxDenomiator)
 }
 
@@ -3157,7 +3266,7 @@ func (env *Env) GetSpillMaxDenominator() (uint64, Error) {
 // Should be in the range 0..255, where zero means no limit, i.e. all dirty
 // pages could be spilled. Default is 8, i.e. no more than 7/8 of the current
 // dirty pages may be spilled when reached the condition described above.
-func (env *Env) SetSpillMaxDenominator(max uint64) Error {
+func (env *Env) SetSpillMaxDenominator(max uint64) error {
 	return env.SetOption(OptSpillMaxDenomiator, max)
 }
 
@@ -3182,7 +3291,7 @@ func (env *Env) SetSpillMaxDenominator(max uint64) Error {
 // be performed during starting nested transactions.
 // Default is 0, i.e. by default no spilling performed during starting nested
 // transactions, that correspond historically behaviour.
-func (env *Env) GetSpillParent4ChildDeominator() (uint64, Error) {
+func (env *Env) GetSpillParent4ChildDeominator() (uint64, error) {
 	return env.GetOption(OptSpillParent4ChildDenominator)
 }
 
@@ -3207,7 +3316,7 @@ func (env *Env) GetSpillParent4ChildDeominator() (uint64, Error) {
 // be performed during starting nested transactions.
 // Default is 0, i.e. by default no spilling performed during starting nested
 // transactions, that correspond historically behaviour.
-func (env *Env) SetSpillParent4ChildDeominator(value uint64) Error {
+func (env *Env) SetSpillParent4ChildDeominator(value uint64) error {
 	return env.SetOption(OptSpillParent4ChildDenominator, value)
 }
 
@@ -3220,7 +3329,7 @@ func (env *Env) SetSpillParent4ChildDeominator(value uint64) Error {
 // format. The specified value must be in the range from 12.5% (almost empty)
 // to 50% (half empty) which corresponds to the range from 8192 and to 32768
 // in units respectively.
-func (env *Env) GetMergeThreshold16Dot16Percent() (uint64, Error) {
+func (env *Env) GetMergeThreshold16Dot16Percent() (uint64, error) {
 	return env.GetOption(OptMergeThreshold16Dot16Percent)
 }
 
@@ -3233,7 +3342,7 @@ func (env *Env) GetMergeThreshold16Dot16Percent() (uint64, Error) {
 // format. The specified value must be in the range from 12.5% (almost empty)
 // to 50% (half empty) which corresponds to the range from 8192 and to 32768
 // in units respectively.
-func (env *Env) SetMergeThreshold16Dot16Percent(percent uint64) Error {
+func (env *Env) SetMergeThreshold16Dot16Percent(percent uint64) error {
 	return env.SetOption(OptMergeThreshold16Dot16Percent, percent)
 }
 
@@ -3260,6 +3369,11 @@ func (v *Val) String() string {
 }
 
 func (v *Val) UnsafeString() string {
+	if debugViews {
+		if b, ok := debugView(v); ok {
+			return *(*string)(unsafe.Pointer(&b))
+		}
+	}
 	return *(*string)(unsafe.Pointer(&reflect.StringHeader{
 		Data: uintptr(unsafe.Pointer(v.Base)),
 		Len:  int(v.Len),
@@ -3277,6 +3391,11 @@ func (v *Val) Bytes() []byte {
 }
 
 func (v *Val) UnsafeBytes() []byte {
+	if debugViews {
+		if b, ok := debugView(v); ok {
+			return b
+		}
+	}
 	return *(*[]byte)(unsafe.Pointer(&reflect.SliceHeader{
 		Data: uintptr(unsafe.Pointer(v.Base)),
 		Len:  int(v.Len),
@@ -3284,6 +3403,14 @@ func (v *Val) UnsafeBytes() []byte {
 	}))
 }
 
+// valBase returns the base of v, nil if v is nil.
+func valBase(v *Val) *byte {
+	if v == nil {
+		return nil
+	}
+	return v.Base
+}
+
 func (v *Val) Copy(dst []byte) []byte {
 	src := *(*[]byte)(unsafe.Pointer(&reflect.SliceHeader{
 		Data: uintptr(unsafe.Pointer(v.Base)),
@@ -3370,13 +3497,23 @@ func F64(v *float64) Val {
 	}
 }
 
+// Bytes returns a Val pointing to the contents of *b. Empty and nil slices
+// return an empty Val.
 func Bytes(b *[]byte) Val {
+	if len(*b) == 0 {
+		return Val{}
+	}
 	return Val{
 		Base: &(*b)[0],
 		Len:  uint64(len(*b)),
 	}
 }
 
+// sliceVal is Bytes for a slice value.
+func sliceVal(b []byte) Val {
+	return Bytes(&b)
+}
+
 func String(s *string) Val {
 	h := *(*reflect.StringHeader)(unsafe.Pointer(s))
 	return Val{
@@ -3470,6 +3607,8 @@ func (v *Val) F64() float64 {
 type Tx struct {
 	env       *Env
 	txn       *C.MDBX_txn
+	changes   *changeLog
+	arena     *Arena
 	shared    bool
 	reset     bool
 	aborted   bool
@@ -3495,9 +3634,10 @@ func (tx *Tx) IsCommitted() bool {
 	return tx.committed
 }
 
-func (env *Env) Begin(txn *Tx, flags TxFlags) Error {
+func (env *Env) Begin(txn *Tx, flags TxFlags) error {
 	txn.env = env
 	txn.txn = nil
+	txn.changes = nil
 	txn.reset = false
 	txn.aborted = false
 	txn.committed = false
@@ -3516,7 +3656,7 @@ func (env *Env) Begin(txn *Tx, flags TxFlags) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_txn_begin_ex), ptr, 0)
-	return args.result
+	return opError("txn_begin", args.result)
 }
 
 // TxInfo Information about the transaction
@@ -3581,7 +3721,7 @@ type TxInfo struct {
 //	See description of \ref MDBX_txn_info.
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (tx *Tx) Info(info *TxInfo) Error {
+func (tx *Tx) Info(info *TxInfo) error {
 	args := struct {
 		txn     uintptr
 		info    uintptr
@@ -3593,7 +3733,7 @@ func (tx *Tx) Info(info *TxInfo) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_txn_info), ptr, 0)
-	return args.result
+	return opError("txn_info", args.result)
 }
 
 // Flags Return the transaction's flags.
@@ -3667,7 +3807,19 @@ type CommitLatency struct {
 // \see mdbx_txn_commit()
 // \ingroup c_statinfo
 // \warning This function may be changed in future releases.
-func (tx *Tx) CommitEx(latency *CommitLatency) Error {
+func (tx *Tx) CommitEx(latency *CommitLatency) error {
+	if faultsEnabled {
+		if rc := fault(FaultCommit, 0); rc != ErrSuccess {
+			_ = tx.Abort()
+			return opError("txn_commit", rc)
+		}
+	}
+	if tx.changes != nil {
+		if err := tx.changes.flush(tx); err != ErrSuccess {
+			_ = tx.Abort()
+			return opError("txn_commit", err)
+		}
+	}
 	args := struct {
 		txn     uintptr
 		latency uintptr
@@ -3678,7 +3830,11 @@ func (tx *Tx) CommitEx(latency *CommitLatency) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_txn_commit_ex), ptr, 0)
-	return args.result
+	tx.ended()
+	if args.result == ErrSuccess && tx.changes != nil {
+		tx.changes.feed.notify()
+	}
+	return opError("txn_commit", args.result)
 }
 
 // Commit all the operations of a transaction into the database.
@@ -3728,7 +3884,7 @@ func (tx *Tx) CommitEx(latency *CommitLatency) Error {
 // \retval MDBX_ENOSPC           No more disk space.
 // \retval MDBX_EIO              A system-level I/O error occurred.
 // \retval MDBX_ENOMEM           Out of memory.
-func (tx *Tx) Commit() Error {
+func (tx *Tx) Commit() error {
 	tx.committed = true
 	return tx.CommitEx(nil)
 }
@@ -3774,7 +3930,7 @@ func (tx *Tx) Commit() Error {
 //	by current thread.
 //
 // \retval MDBX_EINVAL           Transaction handle is NULL.
-func (tx *Tx) Abort() Error {
+func (tx *Tx) Abort() error {
 	args := struct {
 		txn    uintptr
 		result Error
@@ -3784,7 +3940,8 @@ func (tx *Tx) Abort() Error {
 	tx.aborted = true
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_txn_abort), ptr, 0)
-	return args.result
+	tx.ended()
+	return opError("txn_abort", args.result)
 }
 
 // Break Marks transaction as broken.
@@ -3798,7 +3955,7 @@ func (tx *Tx) Abort() Error {
 //
 // \see mdbx_txn_abort() \see mdbx_txn_reset() \see mdbx_txn_commit()
 // \returns A non-zero error value on failure and 0 on success.
-func (tx *Tx) Break() Error {
+func (tx *Tx) Break() error {
 	args := struct {
 		txn    uintptr
 		result Error
@@ -3807,7 +3964,7 @@ func (tx *Tx) Break() Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_txn_break), ptr, 0)
-	return args.result
+	return opError("txn_break", args.result)
 }
 
 // Reset a read-only transaction.
@@ -3850,7 +4007,7 @@ func (tx *Tx) Break() Error {
 //	by current thread.
 //
 // \retval MDBX_EINVAL           Transaction handle is NULL.
-func (tx *Tx) Reset() Error {
+func (tx *Tx) Reset() error {
 	args := struct {
 		txn    uintptr
 		result Error
@@ -3860,7 +4017,8 @@ func (tx *Tx) Reset() Error {
 	tx.reset = true
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_txn_reset), ptr, 0)
-	return args.result
+	tx.ended()
+	return opError("txn_reset", args.result)
 }
 
 // Renew a read-only transaction.
@@ -3891,7 +4049,7 @@ func (tx *Tx) Reset() Error {
 //	by current thread.
 //
 // \retval MDBX_EINVAL           Transaction handle is NULL.
-func (tx *Tx) Renew() Error {
+func (tx *Tx) Renew() error {
 	args := struct {
 		txn    uintptr
 		result Error
@@ -3901,7 +4059,7 @@ func (tx *Tx) Renew() Error {
 	tx.reset = false
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_txn_renew), ptr, 0)
-	return args.result
+	return opError("txn_renew", args.result)
 }
 
 type Canary struct {
@@ -3927,7 +4085,7 @@ type Canary struct {
 //	  `z`.
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (tx *Tx) PutCanary(canary *Canary) Error {
+func (tx *Tx) PutCanary(canary *Canary) error {
 	args := struct {
 		txn    uintptr
 		canary uintptr
@@ -3938,7 +4096,7 @@ func (tx *Tx) PutCanary(canary *Canary) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_canary_put), ptr, 0)
-	return args.result
+	return opError("canary_put", args.result)
 }
 
 // GetCanary Returns fours integers markers (aka "canary") associated with the
@@ -3952,7 +4110,7 @@ func (tx *Tx) PutCanary(canary *Canary) Error {
 //	information will be copied.
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (tx *Tx) GetCanary(canary *Canary) Error {
+func (tx *Tx) GetCanary(canary *Canary) error {
 	args := struct {
 		txn    uintptr
 		canary uintptr
@@ -3963,7 +4121,7 @@ func (tx *Tx) GetCanary(canary *Canary) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_canary_get), ptr, 0)
-	return args.result
+	return opError("canary_get", args.result)
 }
 
 // EnvInfo Return information about the MDBX environment.
@@ -3987,9 +4145,9 @@ func (tx *Tx) GetCanary(canary *Canary) Error {
 // \param [in] bytes   The size of \ref MDBX_envinfo.
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (tx *Tx) EnvInfo(info *EnvInfo) Error {
+func (tx *Tx) EnvInfo(info *EnvInfo) error {
 	if info == nil {
-		return ErrInvalid
+		return opError("env_info", ErrInvalid)
 	}
 	args := struct {
 		env    uintptr
@@ -4005,7 +4163,7 @@ func (tx *Tx) EnvInfo(info *EnvInfo) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_env_info_ex), ptr, 0)
-	return Error(args.result)
+	return opError("env_info", Error(args.result))
 }
 
 // OpenDBI Open or Create a database in the environment.
@@ -4108,50 +4266,58 @@ func (tx *Tx) EnvInfo(info *EnvInfo) Error {
 // \retval MDBX_THREAD_MISMATCH  Given transaction is not owned
 //
 //	by current thread.
-func (tx *Tx) OpenDBI(name string, flags DBFlags) (DBI, Error) {
+func (tx *Tx) OpenDBI(name string, flags DBFlags) (DBI, error) {
+	var dbi DBI
+	var err Error
+	if len(name) == 0 {
+		err = Error(C.mdbx_dbi_open(tx.txn, nil, (C.MDBX_db_flags_t)(flags), (*C.MDBX_dbi)(unsafe.Pointer(&dbi))))
+	} else {
+		n := C.CString(name)
+		defer C.free(unsafe.Pointer(n))
+		err = Error(C.mdbx_dbi_open(tx.txn, n, (C.MDBX_db_flags_t)(flags), (*C.MDBX_dbi)(unsafe.Pointer(&dbi))))
+	}
+	if err != ErrSuccess {
+		return dbi, dbiOpenError(name, err)
+	}
+	tx.env.setDBIName(dbi, name)
+	return dbi, nil
+}
+
+// OpenDBIEx OpenDBI with custom comparators.
+// \ref avoid_custom_comparators "avoid using custom comparators" and use
+// \ref mdbx_dbi_open() instead.
+//
+// \ingroup c_dbi
+//
+// \param [in] txn    transaction handle returned by \ref mdbx_txn_begin().
+// \param [in] name   The name of the database to open. If only a single
+//
+//	database is needed in the environment,
+//	this value may be NULL.
+//
+// \param [in] flags  Special options for this database.
+// \param [in] keycmp  Optional custom key comparison function for a database.
+// \param [in] datacmp Optional custom data comparison function for a database.
+// \param [out] dbi    Address where the new MDBX_dbi handle will be stored.
+// \returns A non-zero error value on failure and 0 on success.
+func (tx *Tx) OpenDBIEx(name string, flags DBFlags, keyCompare, dataCompare *Cmp) (DBI, error) {
+	var dbi DBI
+	var err Error
 	if len(name) == 0 {
-		var dbi DBI
-		err := Error(C.mdbx_dbi_open(tx.txn, nil, (C.MDBX_db_flags_t)(flags), (*C.MDBX_dbi)(unsafe.Pointer(&dbi))))
-		return dbi, err
+		err = Error(C.mdbx_dbi_open_cmp(tx.txn, nil, (C.MDBX_db_flags_t)(flags), (*C.MDBX_dbi)(unsafe.Pointer(&dbi)),
+			(*C.MDBX_cmp_func)(unsafe.Pointer(keyCompare)), (*C.MDBX_cmp_func)(unsafe.Pointer(dataCompare))))
 	} else {
 		n := C.CString(name)
 		defer C.free(unsafe.Pointer(n))
-		var dbi DBI
-		err := Error(C.mdbx_dbi_open(tx.txn, n, (C.MDBX_db_flags_t)(flags), (*C.MDBX_dbi)(unsafe.Pointer(&dbi))))
-		return dbi, err
-	}
-}
-
-//// OpenDBIEx OpenDBI with custom comparators.
-//// \ref avoid_custom_comparators "avoid using custom comparators" and use
-//// \ref mdbx_dbi_open() instead.
-////
-//// \ingroup c_dbi
-////
-//// \param [in] txn    transaction handle returned by \ref mdbx_txn_begin().
-//// \param [in] name   The name of the database to open. If only a single
-////                    database is needed in the environment,
-////                    this value may be NULL.
-//// \param [in] flags  Special options for this database.
-//// \param [in] keycmp  Optional custom key comparison function for a database.
-//// \param [in] datacmp Optional custom data comparison function for a database.
-//// \param [out] dbi    Address where the new MDBX_dbi handle will be stored.
-//// \returns A non-zero error value on failure and 0 on success.
-//func (tx *Tx) OpenDBIEx(name string, flags DBFlags, keyCompare, dataCompare *Cmp) (DBI, Error) {
-//	if len(name) == 0 {
-//		var dbi DBI
-//		err := Error(C.mdbx_dbi_open_ex(tx.txn, nil, (C.MDBX_db_flags_t)(flags), (*C.MDBX_dbi)(unsafe.Pointer(&dbi)),
-//			(*C.MDBX_cmp_func)(unsafe.Pointer(keyCompare)), (*C.MDBX_cmp_func)(unsafe.Pointer(dataCompare))))
-//		return dbi, err
-//	} else {
-//		n := C.CString(name)
-//		defer C.free(unsafe.Pointer(n))
-//		var dbi DBI
-//		err := Error(C.mdbx_dbi_open_ex(tx.txn, n, (C.MDBX_db_flags_t)(flags), (*C.MDBX_dbi)(unsafe.Pointer(&dbi)),
-//			(*C.MDBX_cmp_func)(unsafe.Pointer(keyCompare)), (*C.MDBX_cmp_func)(unsafe.Pointer(dataCompare))))
-//		return dbi, err
-//	}
-//}
+		err = Error(C.mdbx_dbi_open_cmp(tx.txn, n, (C.MDBX_db_flags_t)(flags), (*C.MDBX_dbi)(unsafe.Pointer(&dbi)),
+			(*C.MDBX_cmp_func)(unsafe.Pointer(keyCompare)), (*C.MDBX_cmp_func)(unsafe.Pointer(dataCompare))))
+	}
+	if err != ErrSuccess {
+		return dbi, dbiOpenError(name, err)
+	}
+	tx.env.setDBIName(dbi, name)
+	return dbi, nil
+}
 
 // Stats Statistics for a database in the environment
 // \ingroup c_statinfo
@@ -4186,7 +4352,7 @@ type Stats struct {
 //	by current thread.
 //
 // \retval MDBX_EINVAL   An invalid parameter was specified.
-func (tx *Tx) DBIStat(dbi DBI, stat *Stats) Error {
+func (tx *Tx) DBIStat(dbi DBI, stat *Stats) error {
 	args := struct {
 		txn    uintptr
 		stat   uintptr
@@ -4201,7 +4367,7 @@ func (tx *Tx) DBIStat(dbi DBI, stat *Stats) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_dbi_stat), ptr, 0)
-	return args.result
+	return dbiError("dbi_stat", tx.env, dbi, nil, args.result)
 }
 
 // DBIFlags Retrieve the DB flags and status for a database handle.
@@ -4213,7 +4379,7 @@ func (tx *Tx) DBIStat(dbi DBI, stat *Stats) Error {
 // \param [out] state  Address where the state will be returned.
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (tx *Tx) DBIFlags(dbi DBI) (DBFlags, DBIState, Error) {
+func (tx *Tx) DBIFlags(dbi DBI) (DBFlags, DBIState, error) {
 	var flags DBFlags
 	var state DBIState
 
@@ -4231,7 +4397,7 @@ func (tx *Tx) DBIFlags(dbi DBI) (DBFlags, DBIState, Error) {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_dbi_flags_ex), ptr, 0)
-	return flags, state, args.result
+	return flags, state, dbiError("dbi_flags", tx.env, dbi, nil, args.result)
 }
 
 // Drop Empty or delete and close a database.
@@ -4246,7 +4412,17 @@ func (tx *Tx) DBIFlags(dbi DBI) (DBFlags, DBIState, Error) {
 //	from the environment and close the DB handle.
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (tx *Tx) Drop(dbi DBI, del bool) Error {
+func (tx *Tx) Drop(dbi DBI, del bool) error {
+	var err Error
+	if tx.changes != nil {
+		err = tx.changes.drop(tx, dbi, del)
+	} else {
+		err = tx.drop(dbi, del)
+	}
+	return dbiError("drop", tx.env, dbi, nil, err)
+}
+
+func (tx *Tx) drop(dbi DBI, del bool) Error {
 	args := struct {
 		txn    uintptr
 		del    uintptr
@@ -4261,6 +4437,9 @@ func (tx *Tx) Drop(dbi DBI, del bool) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_drop), ptr, 0)
+	if del && args.result == ErrSuccess {
+		tx.env.removeDBIName(dbi)
+	}
 	return args.result
 }
 
@@ -4297,7 +4476,7 @@ func (tx *Tx) Drop(dbi DBI, del bool) Error {
 //
 // \retval MDBX_NOTFOUND  The key was not in the database.
 // \retval MDBX_EINVAL    An invalid parameter was specified.
-func (tx *Tx) Get(dbi DBI, key *Val, data *Val) Error {
+func (tx *Tx) Get(dbi DBI, key *Val, data *Val) error {
 	args := struct {
 		txn    uintptr
 		key    uintptr
@@ -4310,9 +4489,16 @@ func (tx *Tx) Get(dbi DBI, key *Val, data *Val) Error {
 		data: uintptr(unsafe.Pointer(data)),
 		dbi:  uint32(dbi),
 	}
+	var prev *byte
+	if debugViews {
+		prev = valBase(data)
+	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_get), ptr, 0)
-	return args.result
+	if debugViews && args.result == ErrSuccess {
+		debugIssue(unsafe.Pointer(tx.txn), data, prev)
+	}
+	return dbiError("get", tx.env, dbi, key, args.result)
 }
 
 // GetEqualOrGreat Get equal or great item from a database.
@@ -4349,7 +4535,7 @@ func (tx *Tx) Get(dbi DBI, key *Val, data *Val) Error {
 //
 // \retval MDBX_NOTFOUND      The key was not in the database.
 // \retval MDBX_EINVAL        An invalid parameter was specified.
-func (tx *Tx) GetEqualOrGreat(dbi DBI, key *Val, data *Val) Error {
+func (tx *Tx) GetEqualOrGreat(dbi DBI, key *Val, data *Val) error {
 	args := struct {
 		txn    uintptr
 		key    uintptr
@@ -4362,9 +4548,17 @@ func (tx *Tx) GetEqualOrGreat(dbi DBI, key *Val, data *Val) Error {
 		data: uintptr(unsafe.Pointer(data)),
 		dbi:  uint32(dbi),
 	}
+	var prevKey, prevData *byte
+	if debugViews {
+		prevKey, prevData = valBase(key), valBase(data)
+	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_get_equal_or_great), ptr, 0)
-	return args.result
+	if debugViews {
+		debugIssue(unsafe.Pointer(tx.txn), key, prevKey)
+		debugIssue(unsafe.Pointer(tx.txn), data, prevData)
+	}
+	return dbiError("get_equal_or_great", tx.env, dbi, key, args.result)
 }
 
 // GetEx Get items from a database
@@ -4404,7 +4598,7 @@ func (tx *Tx) GetEqualOrGreat(dbi DBI, key *Val, data *Val) Error {
 //
 // \retval MDBX_NOTFOUND  The key was not in the database.
 // \retval MDBX_EINVAL    An invalid parameter was specified.
-func (tx *Tx) GetEx(dbi DBI, key *Val, data *Val) (int, Error) {
+func (tx *Tx) GetEx(dbi DBI, key *Val, data *Val) (int, error) {
 	var valuesCount uintptr
 	args := struct {
 		txn         uintptr
@@ -4420,9 +4614,17 @@ func (tx *Tx) GetEx(dbi DBI, key *Val, data *Val) (int, Error) {
 		valuesCount: uintptr(unsafe.Pointer(&valuesCount)),
 		dbi:         uint32(dbi),
 	}
+	var prevKey, prevData *byte
+	if debugViews {
+		prevKey, prevData = valBase(key), valBase(data)
+	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_get_ex), ptr, 0)
-	return int(valuesCount), args.result
+	if debugViews {
+		debugIssue(unsafe.Pointer(tx.txn), key, prevKey)
+		debugIssue(unsafe.Pointer(tx.txn), data, prevData)
+	}
+	return int(valuesCount), dbiError("get_ex", tx.env, dbi, key, args.result)
 }
 
 // Put Store items into a database.
@@ -4515,7 +4717,22 @@ func (tx *Tx) GetEx(dbi DBI, key *Val, data *Val) (int, Error) {
 //	in a read-only transaction.
 //
 // \retval MDBX_EINVAL    An invalid parameter was specified.
-func (tx *Tx) Put(dbi DBI, key *Val, data *Val, flags PutFlags) Error {
+func (tx *Tx) Put(dbi DBI, key *Val, data *Val, flags PutFlags) error {
+	if faultsEnabled {
+		if rc := fault(FaultPut, dbi); rc != ErrSuccess {
+			return dbiError("put", tx.env, dbi, key, rc)
+		}
+	}
+	var err Error
+	if tx.changes != nil {
+		err = tx.changes.put(tx, dbi, key, data, flags)
+	} else {
+		err = tx.put(dbi, key, data, flags)
+	}
+	return dbiError("put", tx.env, dbi, key, err)
+}
+
+func (tx *Tx) put(dbi DBI, key *Val, data *Val, flags PutFlags) Error {
 	args := struct {
 		txn    uintptr
 		key    uintptr
@@ -4530,8 +4747,19 @@ func (tx *Tx) Put(dbi DBI, key *Val, data *Val, flags PutFlags) Error {
 		dbi:   uint32(dbi),
 		flags: uint32(flags),
 	}
+	var prev *byte
+	if debugViews {
+		prev = valBase(data)
+	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_put), ptr, 0)
+	if debugViews {
+		if flags&PutReserve != 0 {
+			debugWritable(data)
+		} else if args.result == ErrKeyExist {
+			debugIssue(unsafe.Pointer(tx.txn), data, prev)
+		}
+	}
 	return args.result
 }
 
@@ -4585,7 +4813,17 @@ func (tx *Tx) Put(dbi DBI, key *Val, data *Val, flags PutFlags) Error {
 // \see \ref c_crud_hints "Quick reference for Insert/Update/Delete operations"
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (tx *Tx) Replace(dbi DBI, key *Val, data *Val, oldData *Val, flags PutFlags) Error {
+func (tx *Tx) Replace(dbi DBI, key *Val, data *Val, oldData *Val, flags PutFlags) error {
+	var err Error
+	if tx.changes != nil {
+		err = tx.changes.replace(tx, dbi, key, data, oldData, flags)
+	} else {
+		err = tx.replace(dbi, key, data, oldData, flags)
+	}
+	return dbiError("replace", tx.env, dbi, key, err)
+}
+
+func (tx *Tx) replace(dbi DBI, key *Val, data *Val, oldData *Val, flags PutFlags) Error {
 	args := struct {
 		txn     uintptr
 		key     uintptr
@@ -4636,7 +4874,17 @@ func (tx *Tx) Replace(dbi DBI, key *Val, data *Val, oldData *Val, flags PutFlags
 //	in a read-only transaction.
 //
 // \retval MDBX_EINVAL   An invalid parameter was specified.
-func (tx *Tx) Delete(dbi DBI, key *Val, data *Val) Error {
+func (tx *Tx) Delete(dbi DBI, key *Val, data *Val) error {
+	var err Error
+	if tx.changes != nil {
+		err = tx.changes.delete(tx, dbi, key, data)
+	} else {
+		err = tx.delete(dbi, key, data)
+	}
+	return dbiError("del", tx.env, dbi, key, err)
+}
+
+func (tx *Tx) delete(dbi DBI, key *Val, data *Val) Error {
 	args := struct {
 		txn    uintptr
 		key    uintptr
@@ -4725,7 +4973,7 @@ func NewCursor() *Cursor {
 //	by current thread.
 //
 // \retval MDBX_EINVAL  An invalid parameter was specified.
-func (tx *Tx) Bind(cursor *Cursor, dbi DBI) Error {
+func (tx *Tx) Bind(cursor *Cursor, dbi DBI) error {
 	args := struct {
 		txn    uintptr
 		cursor uintptr
@@ -4738,7 +4986,7 @@ func (tx *Tx) Bind(cursor *Cursor, dbi DBI) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_bind), ptr, 0)
-	return args.result
+	return dbiError("cursor_bind", tx.env, dbi, nil, args.result)
 }
 
 // OpenCursor Create a cursor handle for the specified transaction and DBI handle.
@@ -4778,7 +5026,7 @@ func (tx *Tx) Bind(cursor *Cursor, dbi DBI) Error {
 //	by current thread.
 //
 // \retval MDBX_EINVAL  An invalid parameter was specified.
-func (tx *Tx) OpenCursor(dbi DBI) (*Cursor, Error) {
+func (tx *Tx) OpenCursor(dbi DBI) (*Cursor, error) {
 	var cursor *C.MDBX_cursor
 	args := struct {
 		txn    uintptr
@@ -4792,7 +5040,7 @@ func (tx *Tx) OpenCursor(dbi DBI) (*Cursor, Error) {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_open), ptr, 0)
-	return (*Cursor)(unsafe.Pointer(cursor)), args.result
+	return (*Cursor)(unsafe.Pointer(cursor)), dbiError("cursor_open", tx.env, dbi, nil, args.result)
 }
 
 // Close a cursor handle.
@@ -4810,10 +5058,10 @@ func (tx *Tx) OpenCursor(dbi DBI) (*Cursor, Error) {
 // \param [in] cursor  A cursor handle returned by \ref mdbx_cursor_open()
 //
 //	or \ref mdbx_cursor_create().
-func (cur *Cursor) Close() Error {
+func (cur *Cursor) Close() error {
 	ptr := uintptr(unsafe.Pointer(cur))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_close), ptr, 0)
-	return ErrSuccess
+	return nil
 }
 
 // Renew a cursor handle.
@@ -4844,7 +5092,7 @@ func (cur *Cursor) Close() Error {
 //	by current thread.
 //
 // \retval MDBX_EINVAL  An invalid parameter was specified.
-func (cur *Cursor) Renew(tx *Tx) Error {
+func (cur *Cursor) Renew(tx *Tx) error {
 	args := struct {
 		txn    uintptr
 		cursor uintptr
@@ -4855,7 +5103,7 @@ func (cur *Cursor) Renew(tx *Tx) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_renew), ptr, 0)
-	return args.result
+	return cur.opError("cursor_renew", nil, args.result)
 }
 
 // Tx Return the cursor's transaction handle.
@@ -4900,7 +5148,7 @@ func (cur *Cursor) DBI() DBI {
 // by \ref mdbx_cursor_create() or \ref mdbx_cursor_open().
 //
 // \returns A non-zero error value on failure and 0 on success.
-func (cur *Cursor) Copy(dest *Cursor) Error {
+func (cur *Cursor) Copy(dest *Cursor) error {
 	args := struct {
 		src    uintptr
 		dest   uintptr
@@ -4911,7 +5159,7 @@ func (cur *Cursor) Copy(dest *Cursor) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_copy), ptr, 0)
-	return args.result
+	return cur.opError("cursor_copy", nil, args.result)
 }
 
 // Get Retrieve by cursor.
@@ -4939,7 +5187,15 @@ func (cur *Cursor) Copy(dest *Cursor) Error {
 //
 // \retval MDBX_NOTFOUND  No matching key found.
 // \retval MDBX_EINVAL    An invalid parameter was specified.
-func (cur *Cursor) Get(key *Val, data *Val, op CursorOp) Error {
+//
+// Get returns nil for both the exact and the inexact matches of
+// \ref MDBX_SET_LOWERBOUND, compare the returned key to tell them apart.
+func (cur *Cursor) Get(key *Val, data *Val, op CursorOp) error {
+	if faultsEnabled {
+		if rc := fault(FaultCursorGet, cur.DBI()); rc != ErrSuccess {
+			return cur.opError("cursor_get", nil, rc)
+		}
+	}
 	args := struct {
 		cursor uintptr
 		key    uintptr
@@ -4952,9 +5208,25 @@ func (cur *Cursor) Get(key *Val, data *Val, op CursorOp) Error {
 		data:   uintptr(unsafe.Pointer(data)),
 		op:     op,
 	}
+	var prevKey, prevData *byte
+	if debugViews {
+		prevKey, prevData = valBase(key), valBase(data)
+	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_get), ptr, 0)
-	return args.result
+	if debugViews && (args.result == ErrSuccess || args.result == ErrResultTrue) {
+		txn := unsafe.Pointer(cur.Tx())
+		debugIssue(txn, key, prevKey)
+		debugIssue(txn, data, prevData)
+	}
+	// An inexact CursorSetLowerBound match is a result, not an error.
+	if args.result == ErrSuccess || args.result == ErrResultTrue {
+		return nil
+	}
+	if !op.keyed() {
+		key = nil
+	}
+	return cur.opError("cursor_get", key, args.result)
 }
 
 // Put Store by cursor.
@@ -5048,7 +5320,7 @@ func (cur *Cursor) Get(key *Val, data *Val, op CursorOp) Error {
 //	transaction.
 //
 // \retval MDBX_EINVAL        An invalid parameter was specified.
-func (cur *Cursor) Put(key *Val, data *Val, flags PutFlags) Error {
+func (cur *Cursor) Put(key *Val, data *Val, flags PutFlags) error {
 	args := struct {
 		cursor uintptr
 		key    uintptr
@@ -5063,7 +5335,10 @@ func (cur *Cursor) Put(key *Val, data *Val, flags PutFlags) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_put), ptr, 0)
-	return args.result
+	if debugViews && flags&PutReserve != 0 {
+		debugWritable(data)
+	}
+	return cur.opError("cursor_put", key, args.result)
 }
 
 // Delete current key/data pair.
@@ -5104,7 +5379,7 @@ func (cur *Cursor) Put(key *Val, data *Val, flags PutFlags) Error {
 //	transaction.
 //
 // \retval MDBX_EINVAL        An invalid parameter was specified.
-func (cur *Cursor) Delete(flags PutFlags) Error {
+func (cur *Cursor) Delete(flags PutFlags) error {
 	args := struct {
 		cursor uintptr
 		flags  PutFlags
@@ -5115,7 +5390,7 @@ func (cur *Cursor) Delete(flags PutFlags) Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_del), ptr, 0)
-	return args.result
+	return cur.opError("cursor_del", nil, args.result)
 }
 
 // Count Return count of duplicates for current key.
@@ -5138,7 +5413,7 @@ func (cur *Cursor) Delete(flags PutFlags) Error {
 // \retval MDBX_EINVAL   Cursor is not initialized, or an invalid parameter
 //
 //	was specified.
-func (cur *Cursor) Count() (int, Error) {
+func (cur *Cursor) Count() (int, error) {
 	var count uintptr
 	args := struct {
 		cursor uintptr
@@ -5150,7 +5425,7 @@ func (cur *Cursor) Count() (int, Error) {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_count), ptr, 0)
-	return int(count), args.result
+	return int(count), cur.opError("cursor_count", nil, args.result)
 }
 
 // EOF Determines whether the cursor is pointed to a key-value pair or not,
@@ -5169,7 +5444,7 @@ func (cur *Cursor) Count() (int, Error) {
 //
 // \retval MDBX_RESULT_FALSE   A data is available
 // \retval Otherwise the error code
-func (cur *Cursor) EOF() Error {
+func (cur *Cursor) EOF() (bool, error) {
 	args := struct {
 		cursor uintptr
 		result Error
@@ -5178,7 +5453,10 @@ func (cur *Cursor) EOF() Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_eof), ptr, 0)
-	return args.result
+	if args.result == ErrResultTrue {
+		return true, nil
+	}
+	return false, cur.opError("cursor_eof", nil, args.result)
 }
 
 // OnFirst Determines whether the cursor is pointed to the first key-value pair
@@ -5193,7 +5471,7 @@ func (cur *Cursor) EOF() Error {
 // \retval MDBX_RESULT_TRUE   Cursor positioned to the first key-value pair
 // \retval MDBX_RESULT_FALSE  Cursor NOT positioned to the first key-value
 // pair \retval Otherwise the error code
-func (cur *Cursor) OnFirst() Error {
+func (cur *Cursor) OnFirst() (bool, error) {
 	args := struct {
 		cursor uintptr
 		result Error
@@ -5202,7 +5480,10 @@ func (cur *Cursor) OnFirst() Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_on_first), ptr, 0)
-	return args.result
+	if args.result == ErrResultTrue {
+		return true, nil
+	}
+	return false, cur.opError("cursor_on_first", nil, args.result)
 }
 
 // OnLast Determines whether the cursor is pointed to the last key-value pair
@@ -5217,7 +5498,7 @@ func (cur *Cursor) OnFirst() Error {
 // \retval MDBX_RESULT_TRUE   Cursor positioned to the last key-value pair
 // \retval MDBX_RESULT_FALSE  Cursor NOT positioned to the last key-value pair
 // \retval Otherwise the error code
-func (cur *Cursor) OnLast() Error {
+func (cur *Cursor) OnLast() (bool, error) {
 	args := struct {
 		cursor uintptr
 		result Error
@@ -5226,7 +5507,10 @@ func (cur *Cursor) OnLast() Error {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_cursor_on_last), ptr, 0)
-	return args.result
+	if args.result == ErrResultTrue {
+		return true, nil
+	}
+	return false, cur.opError("cursor_on_last", nil, args.result)
 }
 
 // EstimateDistance
@@ -5274,7 +5558,7 @@ func (cur *Cursor) OnLast() Error {
 //	i.e. `*distance_items = distance(first, last)`.
 //
 // \returns A non-zero error value on failure and 0 on success.
-func EstimateDistance(first, last *Cursor) (int64, Error) {
+func EstimateDistance(first, last *Cursor) (int64, error) {
 	var distance int64
 	args := struct {
 		first    uintptr
@@ -5288,5 +5572,5 @@ func EstimateDistance(first, last *Cursor) (int64, Error) {
 	}
 	ptr := uintptr(unsafe.Pointer(&args))
 	unsafecgo.NonBlocking((*byte)(C.do_mdbx_estimate_distance), ptr, 0)
-	return distance, args.result
+	return distance, opError("estimate_distance", args.result)
 }
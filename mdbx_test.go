@@ -27,7 +27,7 @@ func TestStat(t *testing.T) {
 
 func TestEnv_Open(t *testing.T) {
 	env, err := NewEnv()
-	if err != ErrSuccess {
+	if err != nil {
 		t.Fatal(err)
 	}
 	if err = env.SetGeometry(Geometry{
@@ -37,10 +37,10 @@ func TestEnv_Open(t *testing.T) {
 		GrowthStep:      1024 * 1024 * 16,
 		ShrinkThreshold: 0,
 		PageSize:        4096,
-	}); err != ErrSuccess {
+	}); err != nil {
 		t.Fatal(err)
 	}
-	if err = env.SetMaxDBS(4); err != ErrSuccess {
+	if err = env.SetMaxDBS(4); err != nil {
 		t.Fatal(err)
 	}
 	err = env.Open(
@@ -48,17 +48,17 @@ func TestEnv_Open(t *testing.T) {
 		EnvNoTLS|EnvNoReadAhead|EnvCoalesce|EnvLIFOReclaim|EnvSafeNoSync,
 		0664,
 	)
-	if err != ErrSuccess {
+	if err != nil {
 		t.Fatal(err)
 	}
 
 	var txn Tx
-	if err = env.Begin(&txn, TxReadWrite); err != ErrSuccess {
+	if err = env.Begin(&txn, TxReadWrite); err != nil {
 		t.Fatal(err)
 	}
 
 	var dbi DBI
-	if dbi, err = txn.OpenDBI("", DBCreate); err != ErrSuccess {
+	if dbi, err = txn.OpenDBI("", DBCreate); err != nil {
 		t.Fatal(err)
 	}
 
@@ -69,7 +69,7 @@ func TestEnv_Open(t *testing.T) {
 	keyVal := Bytes(&key)
 	valueVal := Bytes(&value)
 
-	if err = txn.Put(dbi, &keyVal, &valueVal, 0); err != ErrSuccess {
+	if err = txn.Put(dbi, &keyVal, &valueVal, 0); err != nil {
 		t.Fatal(err)
 	}
 
@@ -77,7 +77,7 @@ func TestEnv_Open(t *testing.T) {
 	txn.CommitEx(&latency)
 
 	err = env.Close(false)
-	if err != ErrSuccess {
+	if err != nil {
 		t.Fatal(err)
 	}
 }
@@ -89,21 +89,21 @@ type Engine struct {
 	rd     Tx
 }
 
-func (engine *Engine) BeginWrite() (*Tx, Error) {
+func (engine *Engine) BeginWrite() (*Tx, error) {
 	engine.write.txn = nil
 	engine.write.env = engine.env
 	return &engine.write, engine.env.Begin(&engine.write, TxReadWrite)
 }
 
-func (engine *Engine) BeginRead() (*Tx, Error) {
+func (engine *Engine) BeginRead() (*Tx, error) {
 	engine.rd.env = engine.env
 	return &engine.rd, engine.rd.Renew()
 }
 
-func initDB(path string) (*Engine, Error) {
+func initDB(path string) (*Engine, error) {
 	engine := &Engine{}
 	env, err := NewEnv()
-	if err != ErrSuccess {
+	if err != nil {
 		return nil, err
 	}
 	engine.env = env
@@ -114,10 +114,10 @@ func initDB(path string) (*Engine, Error) {
 		GrowthStep:      1024 * 1024 * 16,
 		ShrinkThreshold: 0,
 		PageSize:        16384,
-	}); err != ErrSuccess {
+	}); err != nil {
 		return nil, err
 	}
-	if err = env.SetMaxDBS(1); err != ErrSuccess {
+	if err = env.SetMaxDBS(1); err != nil {
 		return nil, err
 	}
 
@@ -128,38 +128,38 @@ func initDB(path string) (*Engine, Error) {
 		EnvNoMemInit|EnvCoalesce|EnvLIFOReclaim|EnvSafeNoSync|EnvWriteMap,
 		0664,
 	)
-	if err != ErrSuccess {
+	if err != nil {
 		return nil, err
 	}
 
-	if err = env.Begin(&engine.write, TxReadWrite); err != ErrSuccess {
+	if err = env.Begin(&engine.write, TxReadWrite); err != nil {
 		return nil, err
 	}
 
-	if engine.rootDB, err = engine.write.OpenDBI("m", DBIntegerKey|DBCreate); err != ErrSuccess {
+	if engine.rootDB, err = engine.write.OpenDBI("m", DBIntegerKey|DBCreate); err != nil {
 		return nil, err
 	}
-	//if engine.rootDB, err = engine.write.OpenDBIEx("m", DBCreate, CmpU64, nil); err != ErrSuccess {
+	//if engine.rootDB, err = engine.write.OpenDBIEx("m", DBCreate, CmpU64, nil); err != nil {
 	//	return nil, err
 	//}
 
-	if err = engine.write.Commit(); err != ErrSuccess {
+	if err = engine.write.Commit(); err != nil {
 		return nil, err
 	}
 
-	//if err = env.Begin(&engine.rd, TxReadOnly); err != ErrSuccess {
+	//if err = env.Begin(&engine.rd, TxReadOnly); err != nil {
 	//	return nil, err
 	//}
-	//if err = engine.rd.Reset(); err != ErrSuccess {
+	//if err = engine.rd.Reset(); err != nil {
 	//	return nil, err
 	//}
 
-	return engine, ErrSuccess
+	return engine, nil
 }
 
 func BenchmarkTxn_Put(b *testing.B) {
 	engine, err := initDB("./testdata/" + strconv.Itoa(b.N))
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 	defer engine.env.Close(true)
@@ -174,7 +174,7 @@ func BenchmarkTxn_Put(b *testing.B) {
 	b.ReportAllocs()
 
 	txn, err := engine.BeginWrite()
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 
@@ -183,21 +183,21 @@ func BenchmarkTxn_Put(b *testing.B) {
 		//binary.LittleEndian.PutUint64(key, uint64(i))
 		*(*uint64)(unsafe.Pointer(keyVal.Base)) = uint64(i)
 		//keyVal = U64(uint64(i))
-		if err = txn.Put(engine.rootDB, &keyVal, &dataVal, PutAppend); err != ErrSuccess {
+		if err = txn.Put(engine.rootDB, &keyVal, &dataVal, PutAppend); err != nil {
 			txn.Abort()
 			b.Fatal(err)
 		}
 	}
 
 	//var envInfo EnvInfo
-	//if err = txn.EnvInfo(&envInfo); err != ErrSuccess {
+	//if err = txn.EnvInfo(&envInfo); err != nil {
 	//	b.Fatal(err)
 	//}
 	//var info TxInfo
-	//if err = txn.Info(&info); err != ErrSuccess {
+	//if err = txn.Info(&info); err != nil {
 	//	b.Fatal(err)
 	//}
-	if err = txn.Commit(); err != ErrSuccess {
+	if err = txn.Commit(); err != nil {
 		b.Fatal(err)
 	}
 	//engine.env.Sync(true, false)
@@ -206,7 +206,7 @@ func BenchmarkTxn_Put(b *testing.B) {
 
 func BenchmarkTxn_PutCursor(b *testing.B) {
 	engine, err := initDB("./testdata/" + strconv.Itoa(b.N))
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 	defer engine.env.Close(true)
@@ -222,28 +222,28 @@ func BenchmarkTxn_PutCursor(b *testing.B) {
 	{
 		insert := func(low, high uint64) {
 			txn, err := engine.BeginWrite()
-			if err != ErrSuccess {
+			if err != nil {
 				b.Fatal(err)
 			}
 
 			cursor, err := txn.OpenCursor(engine.rootDB)
-			if err != ErrSuccess {
+			if err != nil {
 				b.Fatal(err)
 			}
 
 			for i := low; i < high; i++ {
 				key = i
-				if err = cursor.Put(&keyVal, &dataVal, PutAppend); err != ErrSuccess {
+				if err = cursor.Put(&keyVal, &dataVal, PutAppend); err != nil {
 					cursor.Close()
 					txn.Abort()
 					b.Fatal(err)
 				}
 			}
 
-			if err = cursor.Close(); err != ErrSuccess {
+			if err = cursor.Close(); err != nil {
 				b.Fatal(err)
 			}
-			if err = txn.Commit(); err != ErrSuccess {
+			if err = txn.Commit(); err != nil {
 				b.Fatal(err)
 			}
 		}
@@ -261,7 +261,7 @@ func BenchmarkTxn_PutCursor(b *testing.B) {
 
 func BenchmarkTxn_Get(b *testing.B) {
 	engine, err := initDB("./testdata/" + strconv.Itoa(b.N))
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 	defer engine.env.Close(true)
@@ -275,28 +275,28 @@ func BenchmarkTxn_Get(b *testing.B) {
 	{
 		insert := func(low, high uint64) {
 			txn, err := engine.BeginWrite()
-			if err != ErrSuccess {
+			if err != nil {
 				b.Fatal(err)
 			}
 
 			cursor, err := txn.OpenCursor(engine.rootDB)
-			if err != ErrSuccess {
+			if err != nil {
 				b.Fatal(err)
 			}
 
 			for i := low; i < high; i++ {
 				key = i
-				if err = cursor.Put(&keyVal, &dataVal, PutAppend); err != ErrSuccess {
+				if err = cursor.Put(&keyVal, &dataVal, PutAppend); err != nil {
 					cursor.Close()
 					txn.Abort()
 					b.Fatal(err)
 				}
 			}
 
-			if err = cursor.Close(); err != ErrSuccess {
+			if err = cursor.Close(); err != nil {
 				b.Fatal(err)
 			}
-			if err = txn.Commit(); err != ErrSuccess {
+			if err = txn.Commit(); err != nil {
 				b.Fatal(err)
 			}
 		}
@@ -316,7 +316,7 @@ func BenchmarkTxn_Get(b *testing.B) {
 	//engine.env.Sync(true, false)
 	//engine.env.Sync(true, false)
 
-	if err = engine.env.Begin(txn, TxReadOnly); err != ErrSuccess {
+	if err = engine.env.Begin(txn, TxReadOnly); err != nil {
 		b.Fatal(err)
 	}
 	b.ResetTimer()
@@ -333,14 +333,14 @@ func BenchmarkTxn_Get(b *testing.B) {
 		keyVal = U64(&key)
 		//binary.BigEndian.PutUint64(key, uint64(20))
 		//binary.BigEndian.PutUint64(key[8:], uint64(i))
-		if err = txn.Get(engine.rootDB, &keyVal, &dataVal); err != ErrSuccess && err != ErrNotFound {
+		if err = txn.Get(engine.rootDB, &keyVal, &dataVal); err != nil && err != ErrNotFound {
 			txn.Reset()
 			b.Fatal(err)
 		}
 		count++
 	}
 
-	if err = txn.Reset(); err != ErrSuccess {
+	if err = txn.Reset(); err != nil {
 		b.Fatal(err)
 	}
 
@@ -349,11 +349,11 @@ func BenchmarkTxn_Get(b *testing.B) {
 	fmt.Println("count", count)
 
 	//var envInfo EnvInfo
-	//if err = txn.EnvInfo(&envInfo); err != ErrSuccess {
+	//if err = txn.EnvInfo(&envInfo); err != nil {
 	//	b.Fatal(err)
 	//}
 	//var info TxInfo
-	//if err = txn.Info(&info); err != ErrSuccess {
+	//if err = txn.Info(&info); err != nil {
 	//	b.Fatal(err)
 	//}
 
@@ -363,7 +363,7 @@ func BenchmarkTxn_Get(b *testing.B) {
 
 func BenchmarkTxn_GetCursor(b *testing.B) {
 	engine, err := initDB("./testdata/" + strconv.Itoa(b.N))
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 	defer engine.env.Close(true)
@@ -377,28 +377,28 @@ func BenchmarkTxn_GetCursor(b *testing.B) {
 	{
 		insert := func(low, high uint64) {
 			txn, err := engine.BeginWrite()
-			if err != ErrSuccess {
+			if err != nil {
 				b.Fatal(err)
 			}
 
 			cursor, err := txn.OpenCursor(engine.rootDB)
-			if err != ErrSuccess {
+			if err != nil {
 				b.Fatal(err)
 			}
 
 			for i := low; i < high; i++ {
 				key = i
-				if err = cursor.Put(&keyVal, &dataVal, PutAppend); err != ErrSuccess {
+				if err = cursor.Put(&keyVal, &dataVal, PutAppend); err != nil {
 					cursor.Close()
 					txn.Abort()
 					b.Fatal(err)
 				}
 			}
 
-			if err = cursor.Close(); err != ErrSuccess {
+			if err = cursor.Close(); err != nil {
 				b.Fatal(err)
 			}
-			if err = txn.Commit(); err != ErrSuccess {
+			if err = txn.Commit(); err != nil {
 				b.Fatal(err)
 			}
 		}
@@ -418,11 +418,11 @@ func BenchmarkTxn_GetCursor(b *testing.B) {
 	//engine.env.Sync(true, false)
 	//engine.env.Sync(true, false)
 
-	if err = engine.env.Begin(txn, TxReadOnly); err != ErrSuccess {
+	if err = engine.env.Begin(txn, TxReadOnly); err != nil {
 		b.Fatal(err)
 	}
 	//txn, err = engine.BeginRead()
-	//if err != ErrSuccess {
+	//if err != nil {
 	//	b.Fatal(err)
 	//}
 
@@ -430,19 +430,19 @@ func BenchmarkTxn_GetCursor(b *testing.B) {
 	b.ReportAllocs()
 
 	cursor, err := txn.OpenCursor(engine.rootDB)
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 
 	//binary.LittleEndian.PutUint64(key, uint64(b.N))
 
-	//if err = txn.Get(engine.rootDB, &keyVal, &dataVal); err != ErrSuccess {
+	//if err = txn.Get(engine.rootDB, &keyVal, &dataVal); err != nil {
 	//	b.Fatal(err)
 	//}
 
 	//keyInt := binary.LittleEndian.Uint64(key)
 
-	//if err = cursor.Get(&keyVal, &dataVal, CursorSet); err != ErrSuccess {
+	//if err = cursor.Get(&keyVal, &dataVal, CursorSet); err != nil {
 	//	b.Fatal(err)
 	//}
 
@@ -456,7 +456,7 @@ func BenchmarkTxn_GetCursor(b *testing.B) {
 	count := 0
 	//
 	for {
-		if err = cursor.Get(&keyVal, &dataVal, CursorNextNoDup); err != ErrSuccess {
+		if err = cursor.Get(&keyVal, &dataVal, CursorNextNoDup); err != nil {
 			break
 		}
 		//if keyVal.Base == nil {
@@ -483,16 +483,16 @@ func BenchmarkTxn_GetCursor(b *testing.B) {
 	//	//binary.BigEndian.PutUint64(key, uint64(20))
 	//	//binary.BigEndian.PutUint64(key[8:], uint64(i))
 	//	//keyVal = U64(uint64(i))
-	//	if err = txn.Get(engine.rootDB, &keyVal, &dataVal); err != ErrSuccess && err != ErrNotFound {
+	//	if err = txn.Get(engine.rootDB, &keyVal, &dataVal); err != nil && err != ErrNotFound {
 	//		txn.Reset()
 	//		b.Fatal(err)
 	//	}
 	//}
 
-	if err = cursor.Close(); err != ErrSuccess {
+	if err = cursor.Close(); err != nil {
 		b.Fatal(err)
 	}
-	if err = txn.Reset(); err != ErrSuccess {
+	if err = txn.Reset(); err != nil {
 		b.Fatal(err)
 	}
 
@@ -501,11 +501,11 @@ func BenchmarkTxn_GetCursor(b *testing.B) {
 	fmt.Println("count", count)
 
 	//var envInfo EnvInfo
-	//if err = txn.EnvInfo(&envInfo); err != ErrSuccess {
+	//if err = txn.EnvInfo(&envInfo); err != nil {
 	//	b.Fatal(err)
 	//}
 	//var info TxInfo
-	//if err = txn.Info(&info); err != ErrSuccess {
+	//if err = txn.Info(&info); err != nil {
 	//	b.Fatal(err)
 	//}
 
@@ -516,7 +516,7 @@ func BenchmarkTxn_GetCursor(b *testing.B) {
 func TestTxn_Cursor(b *testing.T) {
 	iterations := 100
 	engine, err := initDB("./testdata/" + strconv.Itoa(iterations))
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 
@@ -527,7 +527,7 @@ func TestTxn_Cursor(b *testing.T) {
 	dataVal := Bytes(&data)
 
 	txn, err := engine.BeginWrite()
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 
@@ -536,7 +536,7 @@ func TestTxn_Cursor(b *testing.T) {
 		//*(*uint64)(unsafe.Pointer(&key[0])) = uint64(i)
 		binary.LittleEndian.PutUint64(key, uint64(i))
 		//keyVal = U64(uint64(i))
-		if err = txn.Put(engine.rootDB, &keyVal, &dataVal, 0); err != ErrSuccess {
+		if err = txn.Put(engine.rootDB, &keyVal, &dataVal, 0); err != nil {
 			txn.Abort()
 			b.Fatal(err)
 		}
@@ -544,7 +544,7 @@ func TestTxn_Cursor(b *testing.T) {
 
 	//*(*uint64)(unsafe.Pointer(&key[0])) = 0
 
-	if err = txn.Commit(); err != ErrSuccess {
+	if err = txn.Commit(); err != nil {
 		b.Fatal(err)
 	}
 
@@ -553,16 +553,16 @@ func TestTxn_Cursor(b *testing.T) {
 	//engine.env.Sync(true, false)
 	//engine.env.Sync(true, false)
 
-	if err = engine.env.Begin(txn, TxReadOnly); err != ErrSuccess {
+	if err = engine.env.Begin(txn, TxReadOnly); err != nil {
 		b.Fatal(err)
 	}
 	//txn, err = engine.BeginRead()
-	//if err != ErrSuccess {
+	//if err != nil {
 	//	b.Fatal(err)
 	//}
 
 	cursor, err := txn.OpenCursor(engine.rootDB)
-	if err != ErrSuccess {
+	if err != nil {
 		b.Fatal(err)
 	}
 
@@ -574,7 +574,7 @@ func TestTxn_Cursor(b *testing.T) {
 	count := 0
 	//
 	for {
-		if err = cursor.Get(&keyVal, &dataVal, CursorNextNoDup); err != ErrSuccess {
+		if err = cursor.Get(&keyVal, &dataVal, CursorNextNoDup); err != nil {
 			break
 		}
 		//if keyVal.Base == nil {
@@ -598,27 +598,27 @@ func TestTxn_Cursor(b *testing.T) {
 	//	//binary.BigEndian.PutUint64(key, uint64(20))
 	//	//binary.BigEndian.PutUint64(key[8:], uint64(i))
 	//	//keyVal = U64(uint64(i))
-	//	if err = txn.Get(engine.rootDB, &keyVal, &dataVal); err != ErrSuccess && err != ErrNotFound {
+	//	if err = txn.Get(engine.rootDB, &keyVal, &dataVal); err != nil && err != ErrNotFound {
 	//		txn.Reset()
 	//		b.Fatal(err)
 	//	}
 	//}
 
-	if err = cursor.Close(); err != ErrSuccess {
+	if err = cursor.Close(); err != nil {
 		b.Fatal(err)
 	}
-	if err = txn.Reset(); err != ErrSuccess {
+	if err = txn.Reset(); err != nil {
 		b.Fatal(err)
 	}
 
 	fmt.Println("count", count)
 
 	//var envInfo EnvInfo
-	//if err = txn.EnvInfo(&envInfo); err != ErrSuccess {
+	//if err = txn.EnvInfo(&envInfo); err != nil {
 	//	b.Fatal(err)
 	//}
 	//var info TxInfo
-	//if err = txn.Info(&info); err != ErrSuccess {
+	//if err = txn.Info(&info); err != nil {
 	//	b.Fatal(err)
 	//}
 
@@ -0,0 +1,234 @@
+package mdbx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MigrationsDBIName is the reserved database recording applied migrations.
+// Keys are big-endian versions; values hold the time the migration was
+// applied in Unix nanoseconds followed by its name.
+const MigrationsDBIName = "_migrations"
+
+// Migration changes the database layout from Version-1 or earlier to Version.
+// Up runs inside the write transaction that records the migration, so a
+// migration is either applied and recorded or not at all.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      func(tx *Tx) error
+}
+
+// MigrationRecord describes an applied migration.
+type MigrationRecord struct {
+	Version   uint64
+	Name      string
+	AppliedAt time.Time
+}
+
+var (
+	ErrInvalidMigration     = errors.New("mdbx: invalid migration")
+	ErrMigrationOutOfOrder  = errors.New("mdbx: migration is older than the schema version but was never applied")
+	ErrSchemaTooNew         = errors.New("mdbx: schema version is newer than the known migrations")
+	errMigrationDryRunAbort = errors.New("mdbx: migration dry run")
+)
+
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version == 0 || m.Up == nil {
+			return nil, fmt.Errorf("%w: version %d %q needs a version above zero and Up", ErrInvalidMigration, m.Version, m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("%w: version %d is registered twice", ErrInvalidMigration, m.Version)
+		}
+	}
+	return sorted, nil
+}
+
+func readMigrations(tx *Tx, dbi DBI) ([]MigrationRecord, error) {
+	cursor, err := tx.OpenCursor(dbi)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+	var records []MigrationRecord
+	key, data := Val{}, Val{}
+	for {
+		if err = cursor.Get(&key, &data, CursorNext); err != nil {
+			if err == ErrNotFound {
+				return records, nil
+			}
+			return nil, err
+		}
+		k, v := key.UnsafeBytes(), data.UnsafeBytes()
+		if len(k) != 8 || len(v) < 8 {
+			return nil, fmt.Errorf("%w: corrupt record in %s", ErrInvalidMigration, MigrationsDBIName)
+		}
+		records = append(records, MigrationRecord{
+			Version:   binary.BigEndian.Uint64(k),
+			Name:      string(v[8:]),
+			AppliedAt: time.Unix(0, int64(binary.BigEndian.Uint64(v))).UTC(),
+		})
+	}
+}
+
+// pendingMigrations returns the migrations above the recorded schema version.
+func pendingMigrations(sorted []Migration, applied []MigrationRecord) ([]Migration, error) {
+	done := make(map[uint64]bool, len(applied))
+	var version uint64
+	for _, r := range applied {
+		done[r.Version] = true
+		version = r.Version
+	}
+	if len(sorted) > 0 && version > sorted[len(sorted)-1].Version {
+		return nil, fmt.Errorf("%w: database is at version %d, latest known migration is %d",
+			ErrSchemaTooNew, version, sorted[len(sorted)-1].Version)
+	} else if len(sorted) == 0 && version > 0 {
+		return nil, fmt.Errorf("%w: database is at version %d", ErrSchemaTooNew, version)
+	}
+	var pending []Migration
+	for _, m := range sorted {
+		switch {
+		case done[m.Version]:
+		case m.Version < version:
+			return nil, fmt.Errorf("%w: version %d %q, schema version %d", ErrMigrationOutOfOrder, m.Version, m.Name, version)
+		default:
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func openMigrationsDBI(tx *Tx) (DBI, error) {
+	dbi, err := tx.OpenDBI(MigrationsDBIName, DBCreate)
+	if err != nil {
+		return 0, fmt.Errorf("mdbx: open %s: %w", MigrationsDBIName, err)
+	}
+	return dbi, nil
+}
+
+func applyMigration(tx *Tx, dbi DBI, m Migration, at time.Time) error {
+	if err := m.Up(tx); err != nil {
+		return fmt.Errorf("mdbx: migration %d %q: %w", m.Version, m.Name, err)
+	}
+	var key [8]byte
+	binary.BigEndian.PutUint64(key[:], m.Version)
+	value := make([]byte, 8+len(m.Name))
+	binary.BigEndian.PutUint64(value, uint64(at.UnixNano()))
+	copy(value[8:], m.Name)
+	k, v := sliceVal(key[:]), sliceVal(value)
+	if err := tx.Put(dbi, &k, &v, 0); err != nil {
+		return fmt.Errorf("mdbx: record migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// Migrate applies the migrations above the current schema version in order of
+// Version, each in its own write transaction, and returns the records of the
+// migrations it applied. It fails without applying anything if the database
+// was migrated by a newer release or if a migration below the schema version
+// was never applied.
+func (s *Store) Migrate(migrations ...Migration) ([]MigrationRecord, error) {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	if err = s.Update(func(tx *Tx) error {
+		dbi, err := openMigrationsDBI(tx)
+		if err != nil {
+			return err
+		}
+		applied, err := readMigrations(tx, dbi)
+		if err != nil {
+			return err
+		}
+		pending, err = pendingMigrations(sorted, applied)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	records := make([]MigrationRecord, 0, len(pending))
+	for _, m := range pending {
+		at := time.Now().UTC()
+		if err = s.Update(func(tx *Tx) error {
+			dbi, err := openMigrationsDBI(tx)
+			if err != nil {
+				return err
+			}
+			return applyMigration(tx, dbi, m, at)
+		}); err != nil {
+			return records, err
+		}
+		records = append(records, MigrationRecord{Version: m.Version, Name: m.Name, AppliedAt: at})
+	}
+	return records, nil
+}
+
+// MigrateDryRun runs the pending migrations in a single write transaction that
+// is always aborted and returns the migrations Migrate would apply. An error
+// from a migration is returned as it would be by Migrate.
+func (s *Store) MigrateDryRun(migrations ...Migration) ([]Migration, error) {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	err = s.Update(func(tx *Tx) error {
+		dbi, err := openMigrationsDBI(tx)
+		if err != nil {
+			return err
+		}
+		applied, err := readMigrations(tx, dbi)
+		if err != nil {
+			return err
+		}
+		if pending, err = pendingMigrations(sorted, applied); err != nil {
+			return err
+		}
+		at := time.Now().UTC()
+		for _, m := range pending {
+			if err = applyMigration(tx, dbi, m, at); err != nil {
+				return err
+			}
+		}
+		return errMigrationDryRunAbort
+	})
+	if err != errMigrationDryRunAbort {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// MigrationHistory returns the applied migrations in order of version.
+func (s *Store) MigrationHistory() ([]MigrationRecord, error) {
+	var records []MigrationRecord
+	err := s.View(func(tx *Tx) error {
+		dbi, e := tx.OpenDBI(MigrationsDBIName, 0)
+		if e == ErrNotFound {
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+		var err error
+		records, err = readMigrations(tx, dbi)
+		return err
+	})
+	return records, err
+}
+
+// SchemaVersion returns the version of the latest applied migration, zero if none.
+func (s *Store) SchemaVersion() (uint64, error) {
+	records, err := s.MigrationHistory()
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+	return records[len(records)-1].Version, nil
+}
@@ -0,0 +1,93 @@
+package mdbx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStore_Migrate(t *testing.T) {
+	path := t.TempDir()
+	store := openTestStore(t, path, EnvSafeNoSync)
+
+	var ran []uint64
+	putMarker := func(version uint64, name string) Migration {
+		return Migration{Version: version, Name: name, Up: func(tx *Tx) error {
+			ran = append(ran, version)
+			dbi, err := tx.OpenDBI(name, DBCreate)
+			if err != nil {
+				return err
+			}
+			k, v := StringConst("created"), StringConst(name)
+			return tx.Put(dbi, &k, &v, 0)
+		}}
+	}
+	v1, v2, v3 := putMarker(1, "users"), putMarker(2, "orders"), putMarker(3, "invoices")
+
+	pending, err := store.MigrateDryRun(v2, v1)
+	if err != nil || len(pending) != 2 || pending[0].Version != 1 {
+		t.Fatalf("dry run: %v, %v", pending, err)
+	}
+	if version, err := store.SchemaVersion(); err != nil || version != 0 {
+		t.Fatalf("dry run changed version to %d, %v", version, err)
+	}
+
+	ran = nil
+	records, err := store.Migrate(v2, v1)
+	if err != nil || len(records) != 2 || len(ran) != 2 || ran[0] != 1 || ran[1] != 2 {
+		t.Fatalf("migrate: %v, ran %v, %v", records, ran, err)
+	}
+	history, err := store.MigrationHistory()
+	if err != nil || len(history) != 2 || history[1].Name != "orders" || history[1].AppliedAt.IsZero() {
+		t.Fatalf("history: %+v, %v", history, err)
+	}
+
+	// Applied migrations are skipped.
+	ran = nil
+	if records, err = store.Migrate(v1, v2, v3); err != nil || len(records) != 1 || len(ran) != 1 || ran[0] != 3 {
+		t.Fatalf("migrate: %v, ran %v, %v", records, ran, err)
+	}
+	if version, err := store.SchemaVersion(); err != nil || version != 3 {
+		t.Fatalf("version %d, %v", version, err)
+	}
+
+	// A failing migration is not recorded and leaves no changes behind.
+	failing := Migration{Version: 4, Name: "failing", Up: func(tx *Tx) error {
+		if err := putMarker(4, "partial").Up(tx); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}}
+	if _, err = store.Migrate(v1, v2, v3, failing); err == nil {
+		t.Fatal("expected error")
+	}
+	if version, _ := store.SchemaVersion(); version != 3 {
+		t.Fatalf("failed migration recorded, version %d", version)
+	}
+	if err = store.View(func(tx *Tx) error {
+		if _, err := tx.OpenDBI("partial", 0); err != ErrNotFound {
+			t.Fatalf("changes of failed migration committed: %v", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Migrate(v1, v2); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+	if _, err = store.Migrate(v1, v3, putMarker(0, "zero")); !errors.Is(err, ErrInvalidMigration) {
+		t.Fatalf("expected ErrInvalidMigration, got %v", err)
+	}
+}
+
+func TestStore_MigrateOutOfOrder(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	noop := func(tx *Tx) error { return nil }
+	if _, err := store.Migrate(Migration{Version: 1, Up: noop}, Migration{Version: 3, Up: noop}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := store.Migrate(Migration{Version: 1, Up: noop}, Migration{Version: 2, Up: noop}, Migration{Version: 3, Up: noop})
+	if !errors.Is(err, ErrMigrationOutOfOrder) {
+		t.Fatalf("expected ErrMigrationOutOfOrder, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package mdbx
+
+import "unsafe"
+
+// PutMulti stores values, a sequence of values of elemSize bytes each, as
+// values of key in a DBDupFixed database with PutMultiple, building the pair
+// of Val it takes. In a Store.Update with a change feed the values are
+// written with Tx.Put and recorded.
+func (cur *Cursor) PutMulti(key, values []byte, elemSize int) error {
+	k := sliceVal(key)
+	if elemSize <= 0 || len(values)%elemSize != 0 {
+		return cur.opError("cursor_put_multi", &k, ErrBadValSize)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	put := cur.Put
+	if tx := changeTx(unsafe.Pointer(cur.Tx())); tx != nil {
+		dbi := cur.DBI()
+		put = func(key, data *Val, flags PutFlags) error {
+			return tx.Put(dbi, key, data, flags)
+		}
+	}
+	count := func() (int, error) {
+		var data Val
+		if err := cur.Get(&k, &data, CursorSet); err != nil {
+			if err == ErrNotFound {
+				return 0, nil
+			}
+			return 0, err
+		}
+		return cur.Count()
+	}
+
+	// mdbx fails PutMultiple for keys with less than two values, those are
+	// put one at a time until there are two.
+	n, err := count()
+	if err != nil {
+		return err
+	}
+	for len(values) > 0 {
+		written := len(values) / elemSize
+		if written < 2 || n < 2 {
+			v := sliceVal(values[:elemSize])
+			if err = put(&k, &v, 0); err != nil {
+				return err
+			}
+			if n, err = count(); err != nil {
+				return err
+			}
+			written = 1
+		} else {
+			// The first Val holds the size of one value, the second the count
+			// and on return the number of values written.
+			multi := [2]Val{{Base: &values[0], Len: uint64(elemSize)}, {Len: uint64(written)}}
+			if err = put(&k, &multi[0], PutMultiple); err != nil {
+				return err
+			}
+			if written = int(multi[1].Len); written == 0 {
+				return cur.opError("cursor_put_multi", &k, ErrEINVAL)
+			}
+			n += written
+		}
+		values = values[written*elemSize:]
+	}
+	return nil
+}
+
+// MultiIter reads the values of a DBDupFixed database a page at a time with
+// CursorGetMultiple and CursorNextMultiple. It is returned by
+// Cursor.GetMulti.
+//
+//	it := cursor.GetMulti(key)
+//	for it.Next() {
+//		page := it.Page() // values of key, a multiple of the value size
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+//
+// Pages point into the memory map like the slices returned by
+// Val.UnsafeBytes. They are valid until the transaction ends or the database
+// is modified and must be copied to be retained.
+type MultiIter struct {
+	cur     *Cursor
+	key     Val
+	data    Val
+	all     bool
+	started bool
+	done    bool
+	err     error
+}
+
+// GetMulti returns an iterator over the values of key a page at a time. A
+// nil key iterates over the values of all keys in key order.
+func (cur *Cursor) GetMulti(key []byte) *MultiIter {
+	return &MultiIter{cur: cur, key: Bytes(&key), all: key == nil}
+}
+
+// Next moves to the next page and reports whether there is one.
+func (it *MultiIter) Next() bool {
+	if it.done {
+		return false
+	}
+	var err error
+	switch {
+	case !it.started:
+		it.started = true
+		if it.all {
+			err = it.cur.Get(&it.key, &it.data, CursorFirst)
+		} else {
+			err = it.cur.Get(&it.key, &it.data, CursorSetKey)
+		}
+		if err == nil {
+			// A key with one value has no page, the value read above is left
+			// in data.
+			err = it.cur.Get(&it.key, &it.data, CursorGetMultiple)
+		}
+	default:
+		err = it.cur.Get(&it.key, &it.data, CursorNextMultiple)
+		if err == ErrNotFound && it.all {
+			if err = it.cur.Get(&it.key, &it.data, CursorNextNoDup); err == nil {
+				err = it.cur.Get(&it.key, &it.data, CursorGetMultiple)
+			}
+		}
+	}
+	if err != nil {
+		it.done = true
+		if err != ErrNotFound {
+			it.err = err
+		}
+		return false
+	}
+	return true
+}
+
+// Key returns the key of the current page.
+func (it *MultiIter) Key() []byte {
+	return it.key.UnsafeBytes()
+}
+
+// Page returns the values of the current page.
+func (it *MultiIter) Page() []byte {
+	return it.data.UnsafeBytes()
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *MultiIter) Err() error {
+	return it.err
+}
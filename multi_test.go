@@ -0,0 +1,132 @@
+package mdbx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCursor_PutMulti(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "series", DBDupSort|DBDupFixed)
+
+	series := func(from, n int) []byte {
+		b := make([]byte, 0, n*8)
+		for i := from; i < from+n; i++ {
+			b = appendUint64(b, uint64(i))
+		}
+		return b
+	}
+	if err := store.Update(func(tx *Tx) error {
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		for _, p := range []struct {
+			key     string
+			from, n int
+		}{{"a", 0, 5000}, {"a", 5000, 5000}, {"b", 7, 1}, {"c", 0, 1}, {"c", 1, 2}} {
+			if err = cursor.PutMulti([]byte(p.key), series(p.from, p.n), 8); err != nil {
+				return err
+			}
+		}
+		if err = cursor.PutMulti([]byte("d"), make([]byte, 12), 8); !errors.Is(err, ErrBadValSize) {
+			t.Fatalf("got %v, want ErrBadValSize", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+
+		var values []byte
+		pages := 0
+		it := cursor.GetMulti([]byte("a"))
+		for it.Next() {
+			if string(it.Key()) != "a" || len(it.Page())%8 != 0 {
+				t.Fatalf("page of %q with %d bytes", it.Key(), len(it.Page()))
+			}
+			values = append(values, it.Page()...)
+			pages++
+		}
+		if err = it.Err(); err != nil {
+			return err
+		}
+		if pages < 2 || !bytes.Equal(values, series(0, 10000)) {
+			t.Fatalf("%d pages with %d values", pages, len(values)/8)
+		}
+
+		got := ""
+		it = cursor.GetMulti(nil)
+		for it.Next() {
+			got += fmt.Sprintf("%s:%d ", it.Key(), len(it.Page())/8)
+		}
+		if err = it.Err(); err != nil {
+			return err
+		}
+		if got = got[len(got)-8:]; got != "b:1 c:3 " {
+			t.Fatalf("got %q", got)
+		}
+
+		if it = cursor.GetMulti([]byte("z")); it.Next() || it.Err() != nil {
+			t.Fatalf("missing key: %v", it.Err())
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCursor_PutMultiChangeFeed(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "series", DBDupSort|DBDupFixed)
+	if err := store.EnableChangeFeed("cdc"); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := store.Subscribe(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	var values []byte
+	for i := 0; i < 1000; i++ {
+		values = appendUint64(values, uint64(i))
+	}
+	if err = store.Update(func(tx *Tx) error {
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		return cursor.PutMulti([]byte("k"), values, 8)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var cs ChangeSet
+	select {
+	case cs = <-sub.C:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for change set")
+	}
+	var recorded []byte
+	for _, c := range cs.Changes {
+		if c.Op != ChangePut || c.DBI != dbi || string(c.Key) != "k" {
+			t.Fatalf("unexpected change %+v", c)
+		}
+		recorded = append(recorded, c.New...)
+	}
+	if !bytes.Equal(recorded, values) {
+		t.Fatalf("recorded %d bytes of values, want %d", len(recorded), len(values))
+	}
+}
@@ -0,0 +1,195 @@
+package mdbx
+
+// Multimap maps keys to ordered sets of values in a DBDupSort database. The
+// values of a key are distinct and ordered by DataComparator of the flags of
+// the database. Values are limited to the maximum key size.
+//
+// The values passed to the callback of Range point into the memory map like
+// the slices returned by Val.UnsafeBytes. They are valid until the
+// transaction ends or the database is modified and must be copied to be
+// retained. Members returns copies.
+type Multimap struct {
+	DBI DBI
+}
+
+// OpenMultimap opens the named database as a Multimap, creating it if
+// necessary. DBDupSort is added to flags.
+func OpenMultimap(tx *Tx, name string, flags DBFlags) (Multimap, error) {
+	dbi, err := tx.OpenDBI(name, flags|DBDupSort|DBCreate)
+	if err != nil {
+		return Multimap{}, err
+	}
+	return Multimap{DBI: dbi}, nil
+}
+
+// Set returns the set of values of key.
+func (m Multimap) Set(key []byte) Set {
+	return Set{Multimap: m, Key: key}
+}
+
+// Add adds value to the values of key with PutNoDupData. It reports whether
+// value was added, false if it was present already.
+func (m Multimap) Add(tx *Tx, key, value []byte) (bool, error) {
+	k, v := sliceVal(key), sliceVal(value)
+	err := tx.Put(m.DBI, &k, &v, PutNoDupData)
+	if err == ErrKeyExist {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Remove removes value from the values of key. It reports whether value was
+// removed, false if it was not present.
+func (m Multimap) Remove(tx *Tx, key, value []byte) (bool, error) {
+	k, v := sliceVal(key), sliceVal(value)
+	err := tx.Delete(m.DBI, &k, &v)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RemoveAll removes key with all its values and returns how many values
+// were removed.
+func (m Multimap) RemoveAll(tx *Tx, key []byte) (int, error) {
+	n, err := m.Count(tx, key)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	k := sliceVal(key)
+	if err = tx.Delete(m.DBI, &k, nil); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Contains reports whether value is one of the values of key, positioning a
+// cursor with CursorGetBoth.
+func (m Multimap) Contains(tx *Tx, key, value []byte) (bool, error) {
+	cursor, err := tx.OpenCursor(m.DBI)
+	if err != nil {
+		return false, err
+	}
+	defer cursor.Close()
+	k, v := sliceVal(key), sliceVal(value)
+	err = cursor.Get(&k, &v, CursorGetBoth)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Count returns the number of values of key with Cursor.Count, zero if key
+// is not present.
+func (m Multimap) Count(tx *Tx, key []byte) (int, error) {
+	cursor, err := tx.OpenCursor(m.DBI)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close()
+	k, v := sliceVal(key), Val{}
+	if err = cursor.Get(&k, &v, CursorSet); err != nil {
+		if err == ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return cursor.Count()
+}
+
+// Members returns copies of the values of key in order.
+func (m Multimap) Members(tx *Tx, key []byte) ([][]byte, error) {
+	var members [][]byte
+	err := m.Range(tx, key, nil, nil, func(value []byte) bool {
+		members = append(members, append([]byte{}, value...))
+		return true
+	})
+	return members, err
+}
+
+// Range calls fn for the values of key >= from and < to in order, until fn
+// returns false. A nil from or to leaves the range open at that end. The
+// first value is found with CursorGetBothRange, the others with
+// CursorNextDup.
+func (m Multimap) Range(tx *Tx, key, from, to []byte, fn func(value []byte) bool) error {
+	var cmp func(a, b []byte) int
+	if to != nil {
+		flags, _, err := tx.DBIFlags(m.DBI)
+		if err != nil {
+			return err
+		}
+		cmp = DataComparator(flags)
+	}
+
+	cursor, err := tx.OpenCursor(m.DBI)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	k, v := sliceVal(key), Val{}
+	if from != nil {
+		v = sliceVal(from)
+		err = cursor.Get(&k, &v, CursorGetBothRange)
+	} else {
+		err = cursor.Get(&k, &v, CursorSetKey)
+	}
+	for ; err == nil; err = cursor.Get(&k, &v, CursorNextDup) {
+		value := v.UnsafeBytes()
+		if cmp != nil && cmp(value, to) >= 0 {
+			return nil
+		}
+		if !fn(value) {
+			return nil
+		}
+	}
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Set is the ordered set of values of one key in a Multimap. Sets of
+// different keys share the database, an empty set is not stored.
+type Set struct {
+	Multimap
+	Key []byte
+}
+
+// Add adds member to the set. It reports whether member was added, false if
+// it was present already.
+func (s Set) Add(tx *Tx, member []byte) (bool, error) {
+	return s.Multimap.Add(tx, s.Key, member)
+}
+
+// Remove removes member from the set. It reports whether member was removed,
+// false if it was not present.
+func (s Set) Remove(tx *Tx, member []byte) (bool, error) {
+	return s.Multimap.Remove(tx, s.Key, member)
+}
+
+// RemoveAll removes all members and returns how many were removed.
+func (s Set) RemoveAll(tx *Tx) (int, error) {
+	return s.Multimap.RemoveAll(tx, s.Key)
+}
+
+// Contains reports whether member is in the set.
+func (s Set) Contains(tx *Tx, member []byte) (bool, error) {
+	return s.Multimap.Contains(tx, s.Key, member)
+}
+
+// Count returns the number of members.
+func (s Set) Count(tx *Tx) (int, error) {
+	return s.Multimap.Count(tx, s.Key)
+}
+
+// Members returns copies of the members in order.
+func (s Set) Members(tx *Tx) ([][]byte, error) {
+	return s.Multimap.Members(tx, s.Key)
+}
+
+// Range calls fn for the members >= from and < to in order, until fn returns
+// false. A nil from or to leaves the range open at that end.
+func (s Set) Range(tx *Tx, from, to []byte, fn func(member []byte) bool) error {
+	return s.Multimap.Range(tx, s.Key, from, to, fn)
+}
@@ -0,0 +1,99 @@
+package mdbx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultimap(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	var tags Multimap
+	if err := store.Update(func(tx *Tx) error {
+		var err error
+		if tags, err = OpenMultimap(tx, "tags", DBDefaults); err != nil {
+			return err
+		}
+		for i, p := range [][2]string{{"go", "db"}, {"go", "lang"}, {"go", "cgo"}, {"c", "lang"}, {"go", "lang"}} {
+			added, err := tags.Add(tx, []byte(p[0]), []byte(p[1]))
+			if err != nil {
+				return err
+			}
+			if want := i < 4; added != want {
+				t.Fatalf("add %v: got %v, want %v", p, added, want)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	join := func(members [][]byte) string {
+		s := make([]string, len(members))
+		for i, m := range members {
+			s[i] = string(m)
+		}
+		return strings.Join(s, " ")
+	}
+	if err := store.View(func(tx *Tx) error {
+		members, err := tags.Members(tx, []byte("go"))
+		if err != nil {
+			return err
+		}
+		if got := join(members); got != "cgo db lang" {
+			t.Fatalf("members: got %q", got)
+		}
+		for _, c := range []struct {
+			key, value string
+			want       bool
+		}{{"go", "db", true}, {"go", "dc", false}, {"c", "lang", true}, {"c", "db", false}, {"rust", "lang", false}} {
+			if ok, err := tags.Contains(tx, []byte(c.key), []byte(c.value)); err != nil || ok != c.want {
+				t.Fatalf("contains %s %s: %v, %v", c.key, c.value, ok, err)
+			}
+		}
+		for key, want := range map[string]int{"go": 3, "c": 1, "rust": 0} {
+			if n, err := tags.Count(tx, []byte(key)); err != nil || n != want {
+				t.Fatalf("count %s: %d, %v", key, n, err)
+			}
+		}
+
+		var got []string
+		if err = tags.Range(tx, []byte("go"), []byte("d"), []byte("lang"), func(value []byte) bool {
+			got = append(got, string(value))
+			return true
+		}); err != nil {
+			return err
+		}
+		if strings.Join(got, " ") != "db" {
+			t.Fatalf("range: got %q", got)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Update(func(tx *Tx) error {
+		set := tags.Set([]byte("go"))
+		if removed, err := set.Remove(tx, []byte("db")); err != nil || !removed {
+			t.Fatalf("remove: %v, %v", removed, err)
+		}
+		if removed, err := set.Remove(tx, []byte("db")); err != nil || removed {
+			t.Fatalf("remove missing: %v, %v", removed, err)
+		}
+		if n, err := set.RemoveAll(tx); err != nil || n != 2 {
+			t.Fatalf("remove all: %d, %v", n, err)
+		}
+		if n, err := set.Count(tx); err != nil || n != 0 {
+			t.Fatalf("count after remove all: %d, %v", n, err)
+		}
+		members, err := tags.Set([]byte("c")).Members(tx)
+		if err != nil {
+			return err
+		}
+		if got := join(members); got != "lang" {
+			t.Fatalf("other set: got %q", got)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
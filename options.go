@@ -0,0 +1,568 @@
+package mdbx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options declares how a Store environment is configured so the setup of every
+// service can be reviewed and reproduced from a file. Zero values keep the
+// MDBX default unless noted otherwise.
+type Options struct {
+	Path       string          `json:"path"`
+	Flags      EnvFlags        `json:"flags,omitempty"`
+	Mode       os.FileMode     `json:"mode,omitempty"` // defaults to 0664
+	Geometry   GeometryOptions `json:"geometry"`
+	MaxDBs     uint16          `json:"max_dbs,omitempty"` // defaults to 64
+	MaxReaders uint64          `json:"max_readers,omitempty"`
+	SyncBytes  Size            `json:"sync_bytes,omitempty"`  // requires a no-sync mode
+	SyncPeriod Duration        `json:"sync_period,omitempty"` // requires a no-sync mode
+	Tunables   Tunables        `json:"tunables"`
+	DBIs       []DBIOptions    `json:"dbis,omitempty"`
+	MapGrowth  *MapGrowth      `json:"map_growth,omitempty"`
+	Retry      *RetryPolicy    `json:"retry,omitempty"`
+	Migrations []Migration     `json:"-"` // applied by OpenWithOptions
+}
+
+// GeometryOptions mirrors Geometry with sizes that can be written as "64MB".
+// Zero keeps the current or default value.
+type GeometryOptions struct {
+	SizeLower       Size `json:"size_lower,omitempty"`
+	SizeNow         Size `json:"size_now,omitempty"`
+	SizeUpper       Size `json:"size_upper,omitempty"`
+	GrowthStep      Size `json:"growth_step,omitempty"`
+	ShrinkThreshold Size `json:"shrink_threshold,omitempty"`
+	PageSize        Size `json:"page_size,omitempty"`
+}
+
+// Tunables holds the runtime Opt values. Zero keeps the MDBX default.
+type Tunables struct {
+	RPAugmentLimit               uint64 `json:"rp_augment_limit,omitempty"`
+	LooseLimit                   uint64 `json:"loose_limit,omitempty"`
+	DPReserveLimit               uint64 `json:"dp_reserve_limit,omitempty"`
+	TxnDPLimit                   uint64 `json:"txn_dp_limit,omitempty"`
+	TxnDPInitial                 uint64 `json:"txn_dp_initial,omitempty"`
+	SpillMaxDenominator          uint64 `json:"spill_max_denominator,omitempty"`
+	SpillMinDenominator          uint64 `json:"spill_min_denominator,omitempty"`
+	SpillParent4ChildDenominator uint64 `json:"spill_parent4child_denominator,omitempty"`
+	MergeThreshold16Dot16Percent uint64 `json:"merge_threshold_16dot16_percent,omitempty"`
+}
+
+// DBIOptions declares a database opened, and created if missing, when the
+// Store is opened. KeyCmp and DataCmp name one of the built-in comparators,
+// e.g. "u64" for CmpU64.
+type DBIOptions struct {
+	Name    string  `json:"name"`
+	Flags   DBFlags `json:"flags,omitempty"`
+	KeyCmp  string  `json:"key_cmp,omitempty"`
+	DataCmp string  `json:"data_cmp,omitempty"`
+}
+
+var ErrInvalidOptions = errors.New("mdbx: invalid options")
+
+const defaultMaxDBs = 64
+
+// dbiFlagsMask holds the persistent database flags a DBI can be declared with.
+const dbiFlagsMask = DBReverseKey | DBDupSort | DBIntegerKey | DBDupFixed | DBIntegerGroup | DBReverseDup
+
+var comparators = map[string]*Cmp{
+	"u16":                        CmpU16,
+	"u32":                        CmpU32,
+	"u64":                        CmpU64,
+	"u16_prefix_lexical":         CmpU16PrefixLexical,
+	"u16_prefix_u64":             CmpU16PrefixU64,
+	"u32_prefix_lexical":         CmpU32PrefixLexical,
+	"u32_prefix_u64":             CmpU32PrefixU64,
+	"u64_prefix_lexical":         CmpU64PrefixLexical,
+	"u64_prefix_u64":             CmpU64PrefixU64,
+	"u32_prefix_u64_dup_lexical": CmpU32PrefixU64DupLexical,
+	"u32_prefix_u64_dup_u64":     CmpU32PrefixU64DupU64,
+	"u64_prefix_u64_dup_lexical": CmpU64PrefixU64DupLexical,
+	"u64_prefix_u64_dup_u64":     CmpU64PrefixU64DupU64,
+}
+
+// lookupCmp returns the comparator registered under name, nil for "".
+func lookupCmp(name string) (*Cmp, bool) {
+	if name == "" {
+		return nil, true
+	}
+	cmp, ok := comparators[name]
+	return cmp, ok
+}
+
+// DefaultOptions returns the options Open uses for path when nothing else is given.
+func DefaultOptions(path string) Options {
+	return Options{
+		Path:   path,
+		Mode:   0664,
+		MaxDBs: defaultMaxDBs,
+	}
+}
+
+// ParseOptions decodes JSON options, fills in defaults and validates them.
+// Unknown fields are rejected so typos don't silently fall back to defaults.
+func ParseOptions(data []byte) (Options, error) {
+	var opts Options
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&opts); err != nil {
+		return Options{}, fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+	}
+	opts.setDefaults()
+	return opts, opts.Validate()
+}
+
+// ReadOptionsFile reads options from a JSON file. A relative Path in the file
+// is resolved against the directory of the file.
+func ReadOptionsFile(file string) (Options, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Options{}, err
+	}
+	opts, err := ParseOptions(data)
+	if err != nil {
+		return Options{}, fmt.Errorf("%s: %w", file, err)
+	}
+	if !filepath.IsAbs(opts.Path) {
+		opts.Path = filepath.Join(filepath.Dir(file), opts.Path)
+	}
+	return opts, nil
+}
+
+func (o *Options) setDefaults() {
+	if o.Mode == 0 {
+		o.Mode = 0664
+	}
+	if o.MaxDBs == 0 {
+		o.MaxDBs = defaultMaxDBs
+	}
+}
+
+// Validate reports the first inconsistency in o.
+func (o *Options) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidOptions, fmt.Sprintf(format, args...))
+	}
+	if o.Path == "" {
+		return invalid("path is required")
+	}
+
+	g := o.Geometry
+	for _, size := range []struct {
+		name  string
+		value Size
+	}{
+		{"size_lower", g.SizeLower},
+		{"size_now", g.SizeNow},
+		{"size_upper", g.SizeUpper},
+		{"growth_step", g.GrowthStep},
+		{"shrink_threshold", g.ShrinkThreshold},
+		{"page_size", g.PageSize},
+	} {
+		if size.value < 0 {
+			return invalid("geometry %s is negative", size.name)
+		}
+	}
+	if g.SizeLower > 0 && g.SizeUpper > 0 && g.SizeLower > g.SizeUpper {
+		return invalid("geometry size_lower %d is above size_upper %d", g.SizeLower, g.SizeUpper)
+	}
+	if g.SizeNow > 0 && ((g.SizeLower > 0 && g.SizeNow < g.SizeLower) || (g.SizeUpper > 0 && g.SizeNow > g.SizeUpper)) {
+		return invalid("geometry size_now %d is outside of size_lower..size_upper", g.SizeNow)
+	}
+	if p := int(g.PageSize); p != 0 && (p < MinPageSize || p > MaxPageSize || p&(p-1) != 0) {
+		return invalid("geometry page_size %d must be a power of two between %d and %d", p, MinPageSize, MaxPageSize)
+	}
+
+	if o.SyncBytes < 0 || o.SyncPeriod < 0 {
+		return invalid("sync_bytes and sync_period must not be negative")
+	}
+	if (o.SyncBytes > 0 || o.SyncPeriod > 0) && o.Flags&(EnvSafeNoSync|EnvNoMetaSync) == 0 {
+		return invalid("sync_bytes and sync_period require safe_nosync, nometasync or utterly_nosync")
+	}
+
+	named := 0
+	seen := make(map[string]bool, len(o.DBIs))
+	for _, d := range o.DBIs {
+		if seen[d.Name] {
+			return invalid("dbi %q is declared twice", d.Name)
+		}
+		seen[d.Name] = true
+		if d.Name != "" {
+			named++
+		}
+		if err := validateDBIFlags(d.Flags); err != "" {
+			return invalid("dbi %q: %s", d.Name, err)
+		}
+		if _, ok := lookupCmp(d.KeyCmp); !ok {
+			return invalid("dbi %q: unknown key_cmp %q", d.Name, d.KeyCmp)
+		}
+		if _, ok := lookupCmp(d.DataCmp); !ok {
+			return invalid("dbi %q: unknown data_cmp %q", d.Name, d.DataCmp)
+		}
+		if d.DataCmp != "" && d.Flags&DBDupSort == 0 {
+			return invalid("dbi %q: data_cmp requires dupsort", d.Name)
+		}
+	}
+	if named > int(o.MaxDBs) {
+		return invalid("%d named dbis declared but max_dbs is %d", named, o.MaxDBs)
+	}
+	if o.MapGrowth != nil {
+		if err := o.MapGrowth.validate(); err != nil {
+			return err
+		}
+		if g.SizeUpper > 0 && o.MapGrowth.Ceiling < g.SizeUpper {
+			return invalid("map_growth ceiling %d is below geometry size_upper %d", o.MapGrowth.Ceiling, g.SizeUpper)
+		}
+	}
+	if o.Retry != nil {
+		if err := o.Retry.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateDBIFlags(flags DBFlags) string {
+	if flags&^dbiFlagsMask != 0 {
+		return fmt.Sprintf("unsupported flags %#x", uint32(flags&^dbiFlagsMask))
+	}
+	if flags&(DBDupFixed|DBIntegerGroup|DBReverseDup) != 0 && flags&DBDupSort == 0 {
+		return "dupfixed, integerdup and reversedup require dupsort"
+	}
+	if flags&DBIntegerGroup != 0 && flags&DBDupFixed == 0 {
+		return "integerdup requires dupfixed"
+	}
+	return ""
+}
+
+func sizeArg(s Size) uintptr {
+	if s == 0 {
+		return ^uintptr(0)
+	}
+	return uintptr(s)
+}
+
+// apply configures env before it is opened.
+func (o *Options) apply(env *Env) error {
+	if err := env.SetMaxDBS(o.MaxDBs); err != nil {
+		return fmt.Errorf("mdbx: set max_dbs: %w", err)
+	}
+	if o.MaxReaders > 0 {
+		if err := env.SetMaxReaders(o.MaxReaders); err != nil {
+			return fmt.Errorf("mdbx: set max_readers: %w", err)
+		}
+	}
+	g := o.Geometry
+	if g != (GeometryOptions{}) {
+		if err := env.SetGeometry(Geometry{
+			SizeLower:       sizeArg(g.SizeLower),
+			SizeNow:         sizeArg(g.SizeNow),
+			SizeUpper:       sizeArg(g.SizeUpper),
+			GrowthStep:      sizeArg(g.GrowthStep),
+			ShrinkThreshold: sizeArg(g.ShrinkThreshold),
+			PageSize:        sizeArg(g.PageSize),
+		}); err != nil {
+			return fmt.Errorf("mdbx: set geometry: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyOptions sets the runtime Opt values, which MDBX accepts only once env is open.
+func (o *Options) applyOptions(env *Env) error {
+	t := o.Tunables
+	for _, opt := range []struct {
+		name  string
+		opt   Opt
+		value uint64
+	}{
+		{"sync_bytes", OptSyncBytes, uint64(o.SyncBytes)},
+		{"sync_period", OptSyncPeriod, o.SyncPeriod.seconds16dot16()},
+		{"rp_augment_limit", OptRpAugmentLimit, t.RPAugmentLimit},
+		{"loose_limit", OptLooseLimit, t.LooseLimit},
+		{"dp_reserve_limit", OptDpReserveLimit, t.DPReserveLimit},
+		{"txn_dp_limit", OptTxnDpLimit, t.TxnDPLimit},
+		{"txn_dp_initial", OptTxnDpInitial, t.TxnDPInitial},
+		{"spill_max_denominator", OptSpillMaxDenomiator, t.SpillMaxDenominator},
+		{"spill_min_denominator", OptSpillMinDenomiator, t.SpillMinDenominator},
+		{"spill_parent4child_denominator", OptSpillParent4ChildDenominator, t.SpillParent4ChildDenominator},
+		{"merge_threshold_16dot16_percent", OptMergeThreshold16Dot16Percent, t.MergeThreshold16Dot16Percent},
+	} {
+		if opt.value == 0 {
+			continue
+		}
+		if err := env.SetOption(opt.opt, opt.value); err != nil {
+			return fmt.Errorf("mdbx: set %s: %w", opt.name, err)
+		}
+	}
+	return nil
+}
+
+// tables resolves the declared DBIs for the Store catalog.
+func (o *Options) tables() []Table {
+	tables := make([]Table, 0, len(o.DBIs))
+	for _, d := range o.DBIs {
+		keyCmp, _ := lookupCmp(d.KeyCmp)
+		dataCmp, _ := lookupCmp(d.DataCmp)
+		tables = append(tables, Table{Name: d.Name, Flags: d.Flags, KeyCmp: keyCmp, DataCmp: dataCmp})
+	}
+	return tables
+}
+
+// OpenWithOptions validates opts and opens a Store configured by them. The
+// declared DBIs are registered in the Store catalog and the migrations are
+// applied before init is called.
+func OpenWithOptions(opts Options, init func(store *Store, create bool) error) (*Store, error) {
+	opts.setDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return Open(opts.Path, opts.Flags, opts.Mode,
+		func(env *Env, create bool) error {
+			return opts.apply(env)
+		},
+		func(store *Store, create bool) error {
+			if err := opts.applyOptions(store.env); err != nil {
+				return err
+			}
+			if opts.MapGrowth != nil {
+				if err := store.SetMapGrowth(opts.MapGrowth); err != nil {
+					return err
+				}
+			}
+			if opts.Retry != nil {
+				if err := store.SetRetryPolicy(opts.Retry); err != nil {
+					return err
+				}
+			}
+			if len(opts.DBIs) > 0 {
+				if err := store.Register(opts.tables()...); err != nil {
+					return err
+				}
+			}
+			if len(opts.Migrations) > 0 {
+				if _, err := store.Migrate(opts.Migrations...); err != nil {
+					return err
+				}
+			}
+			if init != nil {
+				return init(store, create)
+			}
+			return nil
+		})
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////
+// JSON
+//////////////////////////////////////////////////////////////////////////////////////////
+
+// Size is a byte count that is written in JSON either as a number or as a
+// string with a K, M, G or T suffix (powers of 1024), e.g. "256MB".
+type Size int64
+
+func (s Size) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(s), 10)), nil
+}
+
+func (s *Size) UnmarshalJSON(b []byte) error {
+	if len(b) == 0 || b[0] != '"' {
+		v, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size %s", b)
+		}
+		*s = Size(v)
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	v, err := ParseSize(str)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// ParseSize parses a byte count with an optional K, M, G or T suffix,
+// optionally followed by B or iB.
+func ParseSize(str string) (Size, error) {
+	s := strings.ToUpper(strings.TrimSpace(str))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	mult := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			s = strings.TrimSpace(s[:n-1])
+		}
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 || v > (1<<62)/mult {
+		return 0, fmt.Errorf("invalid size %q", str)
+	}
+	return Size(v * mult), nil
+}
+
+// Duration is a time.Duration written in JSON as a string such as "250ms".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return fmt.Errorf("invalid duration %s", b)
+	}
+	v, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// seconds16dot16 converts d to the fixed point seconds used by OptSyncPeriod.
+func (d Duration) seconds16dot16() uint64 {
+	return uint64(time.Duration(d) * 65536 / time.Second)
+}
+
+var envFlagNames = []struct {
+	flag EnvFlags
+	name string
+}{
+	// utterly_nosync includes the bits of safe_nosync and nometasync.
+	{EnvUtterlyNoSync, "utterly_nosync"},
+	{EnvSafeNoSync, "safe_nosync"},
+	{EnvNoMetaSync, "nometasync"},
+	{EnvNoSubDir, "nosubdir"},
+	{EnvReadOnly, "rdonly"},
+	{EnvExclusive, "exclusive"},
+	{EnvAccede, "accede"},
+	{EnvWriteMap, "writemap"},
+	{EnvNoTLS, "notls"},
+	{EnvNoReadAhead, "nordahead"},
+	{EnvNoMemInit, "nomeminit"},
+	{EnvCoalesce, "coalesce"},
+	{EnvLIFOReclaim, "liforeclaim"},
+	{EnvPagePerTurb, "pageperturb"},
+}
+
+// MarshalJSON writes the flags as a list of names such as ["nosubdir","safe_nosync"].
+func (f EnvFlags) MarshalJSON() ([]byte, error) {
+	names := []string{}
+	for _, n := range envFlagNames {
+		if n.flag != 0 && f&n.flag == n.flag {
+			names = append(names, n.name)
+			f &^= n.flag
+		}
+	}
+	if f != 0 {
+		return nil, fmt.Errorf("mdbx: unknown env flags %#x", uint32(f))
+	}
+	return json.Marshal(names)
+}
+
+// UnmarshalJSON accepts a list of flag names or a number.
+func (f *EnvFlags) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		var v uint32
+		if json.Unmarshal(b, &v) != nil {
+			return fmt.Errorf("invalid env flags %s", b)
+		}
+		*f = EnvFlags(v)
+		return nil
+	}
+	*f = 0
+NAMES:
+	for _, name := range names {
+		for _, n := range envFlagNames {
+			if n.name == name {
+				*f |= n.flag
+				continue NAMES
+			}
+		}
+		if name == "sync_durable" {
+			continue
+		}
+		return fmt.Errorf("unknown env flag %q", name)
+	}
+	return nil
+}
+
+// String returns the persistent flags as names separated by "|".
+func (f DBFlags) String() string {
+	var names []string
+	for _, n := range dbFlagNames {
+		if f&n.flag != 0 {
+			names = append(names, n.name)
+			f &^= n.flag
+		}
+	}
+	if f != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint32(f)))
+	}
+	if len(names) == 0 {
+		return "defaults"
+	}
+	return strings.Join(names, "|")
+}
+
+// MarshalJSON writes the flags as a list of names such as ["dupsort","dupfixed"].
+func (f DBFlags) MarshalJSON() ([]byte, error) {
+	names := []string{}
+	for _, n := range dbFlagNames {
+		if f&n.flag != 0 {
+			names = append(names, n.name)
+			f &^= n.flag
+		}
+	}
+	if f != 0 {
+		return nil, fmt.Errorf("mdbx: unknown db flags %#x", uint32(f))
+	}
+	return json.Marshal(names)
+}
+
+// UnmarshalJSON accepts a list of flag names or a number.
+func (f *DBFlags) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		var v uint32
+		if json.Unmarshal(b, &v) != nil {
+			return fmt.Errorf("invalid db flags %s", b)
+		}
+		*f = DBFlags(v)
+		return nil
+	}
+	*f = 0
+NAMES:
+	for _, name := range names {
+		for _, n := range dbFlagNames {
+			if n.name == name {
+				*f |= n.flag
+				continue NAMES
+			}
+		}
+		return fmt.Errorf("unknown db flag %q", name)
+	}
+	return nil
+}
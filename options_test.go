@@ -0,0 +1,126 @@
+package mdbx
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadOptionsFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "store.json")
+	if err := os.WriteFile(file, []byte(`{
+		"path": "data",
+		"flags": ["safe_nosync", "nordahead"],
+		"geometry": {"size_lower": "1MB", "size_upper": "64MB", "growth_step": "1M", "page_size": 4096},
+		"max_dbs": 8,
+		"sync_bytes": "16MB",
+		"sync_period": "250ms",
+		"tunables": {"txn_dp_limit": 4096},
+		"dbis": [
+			{"name": "events", "flags": ["dupsort"]},
+			{"name": "by_id", "key_cmp": "u64"}
+		]
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := ReadOptionsFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Path != filepath.Join(dir, "data") || opts.Mode != 0664 ||
+		opts.Flags != EnvSafeNoSync|EnvNoReadAhead || opts.Geometry.SizeUpper != 64<<20 ||
+		opts.SyncPeriod != Duration(250*time.Millisecond) || opts.DBIs[0].Flags != DBDupSort {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	store, err := OpenWithOptions(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if limit, err := store.Env().GetTxDPLimit(); err != nil || limit != 4096 {
+		t.Fatalf("txn_dp_limit %d, %v", limit, err)
+	}
+	if period, err := store.Env().GetSyncPeriod(); err != nil || period != 65536/4 {
+		t.Fatalf("sync_period %d, %v", period, err)
+	}
+
+	if err = store.Update(func(tx *Tx) error {
+		dbi, err := tx.OpenDBI("by_id", 0)
+		if err != nil {
+			return err
+		}
+		var key [8]byte
+		for _, id := range []uint64{256, 1, 65536} {
+			binary.LittleEndian.PutUint64(key[:], id)
+			k, v := sliceVal(key[:]), StringConst("x")
+			if err = tx.Put(dbi, &k, &v, 0); err != nil {
+				return err
+			}
+		}
+		cursor, err := tx.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		k, v := Val{}, Val{}
+		var ids []uint64
+		for cursor.Get(&k, &v, CursorNext) == nil {
+			ids = append(ids, binary.LittleEndian.Uint64(k.UnsafeBytes()))
+		}
+		if len(ids) != 3 || ids[0] != 1 || ids[1] != 256 || ids[2] != 65536 {
+			t.Fatalf("keys not ordered by u64 comparator: %v", ids)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"no path":        {},
+		"lower > upper":  {Path: "x", Geometry: GeometryOptions{SizeLower: 2 << 20, SizeUpper: 1 << 20}},
+		"page size":      {Path: "x", Geometry: GeometryOptions{PageSize: 5000}},
+		"sync no flags":  {Path: "x", SyncBytes: 1 << 20},
+		"duplicate dbi":  {Path: "x", DBIs: []DBIOptions{{Name: "a"}, {Name: "a"}}},
+		"dupfixed":       {Path: "x", DBIs: []DBIOptions{{Name: "a", Flags: DBDupFixed}}},
+		"unknown cmp":    {Path: "x", DBIs: []DBIOptions{{Name: "a", KeyCmp: "nope"}}},
+		"data cmp":       {Path: "x", DBIs: []DBIOptions{{Name: "a", DataCmp: "u64"}}},
+		"too many dbis":  {Path: "x", MaxDBs: 1, DBIs: []DBIOptions{{Name: "a"}, {Name: "b"}}},
+		"create flag":    {Path: "x", DBIs: []DBIOptions{{Name: "a", Flags: DBCreate}}},
+		"negative size":  {Path: "x", Geometry: GeometryOptions{GrowthStep: -1}},
+		"negative bytes": {Path: "x", Flags: EnvSafeNoSync, SyncBytes: -1},
+	} {
+		if err := opts.Validate(); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: expected ErrInvalidOptions, got %v", name, err)
+		}
+	}
+	opts := DefaultOptions("x")
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseOptions([]byte(`{"path": "x", "max_dbz": 1}`)); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("unknown field accepted: %v", err)
+	}
+}
+
+func TestEnvFlags_JSON(t *testing.T) {
+	b, err := json.Marshal(EnvUtterlyNoSync | EnvNoSubDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `["utterly_nosync","nosubdir"]` {
+		t.Fatalf("got %s", b)
+	}
+	var flags EnvFlags
+	if err = json.Unmarshal(b, &flags); err != nil || flags != EnvUtterlyNoSync|EnvNoSubDir {
+		t.Fatalf("got %#x, %v", flags, err)
+	}
+}
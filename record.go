@@ -0,0 +1,177 @@
+//go:build go1.18
+
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ErrRecordLayout is returned by NewRecords for types that cannot be mapped
+// onto values.
+var ErrRecordLayout = errors.New("mdbx: type is not a fixed-layout record")
+
+// Records maps the values of a database onto the fixed-layout type T, giving
+// allocation-free access to records like
+//
+//	type Tick struct {
+//		Time  int64
+//		Price float64
+//		Size  uint32
+//		Side  uint8
+//		_     [3]byte
+//	}
+//
+// A fixed-layout type is made of sized integers, floats, bools and arrays of
+// them, with explicit padding fields where the fields would not be aligned
+// otherwise, so that it has the same little-endian layout on every supported
+// platform. NewRecords checks the layout described by the caller, there is
+// no reflection or code generation:
+//
+//	var t Tick
+//	ticks, err := NewRecords[Tick](dbi,
+//		Field[int64](unsafe.Offsetof(t.Time)),
+//		Field[float64](unsafe.Offsetof(t.Price)),
+//		Field[uint32](unsafe.Offsetof(t.Size)),
+//		Field[uint8](unsafe.Offsetof(t.Side)),
+//		Pad(3))
+//
+// The pointers returned by View, Get and CursorGet point into the memory map
+// like Val.UnsafeBytes: they are read-only and valid until the transaction
+// ends or the database is modified. The pointer returned by Put is valid
+// until the next modification. Values are not necessarily aligned, T must
+// not be accessed with sync/atomic.
+type Records[T any] struct {
+	DBI DBI
+}
+
+// RecordScalar constrains the types of the fields of a fixed-layout record.
+type RecordScalar interface {
+	~bool | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+// RecordField describes a field of a fixed-layout record for NewRecords. It
+// is built with Field, ArrayField or Pad.
+type RecordField struct {
+	offset uintptr
+	size   uintptr // Size of the scalar type, the alignment of the field
+	len    uintptr // Number of scalars
+	pad    bool
+}
+
+// Field describes a field of type F at offset, as returned by
+// unsafe.Offsetof.
+func Field[F RecordScalar](offset uintptr) RecordField {
+	return RecordField{offset: offset, size: unsafe.Sizeof(*new(F)), len: 1}
+}
+
+// ArrayField describes an array of n elements of type F at offset. Fields
+// of nested structs and arrays of them are described by their scalars.
+func ArrayField[F RecordScalar](offset uintptr, n int) RecordField {
+	return RecordField{offset: offset, size: unsafe.Sizeof(*new(F)), len: uintptr(n)}
+}
+
+// Pad describes an explicit padding field of n bytes following the previous
+// field, like _ [n]byte.
+func Pad(n int) RecordField {
+	return RecordField{size: 1, len: uintptr(n), pad: true}
+}
+
+// NewRecords checks that fields describe every byte of T in order, each
+// field aligned to the size of its scalar type, and returns Records for
+// dbi. Records used without NewRecords skip the check.
+func NewRecords[T any](dbi DBI, fields ...RecordField) (Records[T], error) {
+	if err := checkRecordLayout(unsafe.Sizeof(*new(T)), fields); err != nil {
+		return Records[T]{}, err
+	}
+	return Records[T]{DBI: dbi}, nil
+}
+
+// View returns the value v as a *T. It returns ErrBadValSize if v does not
+// have the size of T.
+func (r Records[T]) View(v *Val) (*T, error) {
+	if uintptr(v.Len) != unsafe.Sizeof(*new(T)) {
+		return nil, opError("record_view", ErrBadValSize)
+	}
+	return (*T)(unsafe.Pointer(v.Base)), nil
+}
+
+// Get returns the record of key.
+func (r Records[T]) Get(tx *Tx, key *Val) (*T, error) {
+	var data Val
+	if err := tx.Get(r.DBI, key, &data); err != nil {
+		return nil, err
+	}
+	if uintptr(data.Len) != unsafe.Sizeof(*new(T)) {
+		return nil, dbiError("get", tx.env, r.DBI, key, ErrBadValSize)
+	}
+	return (*T)(unsafe.Pointer(data.Base)), nil
+}
+
+// Put reserves a zeroed record for key with PutReserve and flags and returns
+// it for the caller to fill in place.
+func (r Records[T]) Put(tx *Tx, key *Val, flags PutFlags) (*T, error) {
+	data := Val{Len: uint64(unsafe.Sizeof(*new(T)))}
+	if err := tx.Put(r.DBI, key, &data, flags|PutReserve); err != nil {
+		return nil, err
+	}
+	record := (*T)(unsafe.Pointer(data.Base))
+	*record = *new(T)
+	return record, nil
+}
+
+// CursorGet moves cur with op like Cursor.Get and returns the record it
+// moved to.
+func (r Records[T]) CursorGet(cur *Cursor, key *Val, op CursorOp) (*T, error) {
+	var data Val
+	if err := cur.Get(key, &data, op); err != nil {
+		return nil, err
+	}
+	if uintptr(data.Len) != unsafe.Sizeof(*new(T)) {
+		return nil, cur.opError("cursor_get", key, ErrBadValSize)
+	}
+	return (*T)(unsafe.Pointer(data.Base)), nil
+}
+
+// hostLittleEndian reports whether values map onto records unchanged.
+var hostLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+func checkRecordLayout(size uintptr, fields []RecordField) error {
+	if !hostLittleEndian {
+		return fmt.Errorf("%w: host is big-endian", ErrRecordLayout)
+	}
+	if size == 0 {
+		return fmt.Errorf("%w: size 0", ErrRecordLayout)
+	}
+	var offset, align uintptr = 0, 1
+	for i, f := range fields {
+		if f.pad {
+			f.offset = offset
+		}
+		if f.offset != offset {
+			return fmt.Errorf("%w: field %d at offset %d, want %d", ErrRecordLayout, i, f.offset, offset)
+		}
+		if f.len == 0 {
+			return fmt.Errorf("%w: field %d is empty", ErrRecordLayout, i)
+		}
+		if offset%f.size != 0 {
+			return fmt.Errorf("%w: field %d at offset %d is not aligned to %d bytes", ErrRecordLayout, i, offset, f.size)
+		}
+		if f.size > align {
+			align = f.size
+		}
+		offset += f.size * f.len
+	}
+	if offset != size {
+		return fmt.Errorf("%w: fields cover %d of %d bytes", ErrRecordLayout, offset, size)
+	}
+	if size%align != 0 {
+		return fmt.Errorf("%w: size %d is not a multiple of %d bytes", ErrRecordLayout, size, align)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+//go:build go1.18
+
+package mdbx
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"unsafe"
+)
+
+type testTick struct {
+	Time  int64
+	Price float64
+	Size  uint32
+	Side  uint8
+	Flags [3]bool
+}
+
+func TestRecords(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	var tt testTick
+	ticks, err := NewRecords[testTick](openTestDBI(t, store, "ticks", DBIntegerKey),
+		Field[int64](unsafe.Offsetof(tt.Time)),
+		Field[float64](unsafe.Offsetof(tt.Price)),
+		Field[uint32](unsafe.Offsetof(tt.Size)),
+		Field[uint8](unsafe.Offsetof(tt.Side)),
+		ArrayField[bool](unsafe.Offsetof(tt.Flags), 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Update(func(tx *Tx) error {
+		for i := uint64(1); i <= 3; i++ {
+			key := U64(&i)
+			tick, err := ticks.Put(tx, &key, 0)
+			if err != nil {
+				return err
+			}
+			tick.Time, tick.Price, tick.Size, tick.Side = int64(i)*100, float64(i)/2, uint32(i), 'b'
+			tick.Flags[1] = true
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		i := uint64(2)
+		key, data := U64(&i), Val{}
+		tick, err := ticks.Get(tx, &key)
+		if err != nil {
+			return err
+		}
+		if *tick != (testTick{Time: 200, Price: 1, Size: 2, Side: 'b', Flags: [3]bool{false, true, false}}) {
+			t.Fatalf("got %+v", *tick)
+		}
+
+		// The layout is little-endian with the fields at their offsets.
+		if err := tx.Get(ticks.DBI, &key, &data); err != nil {
+			return err
+		}
+		b := data.Bytes()
+		if len(b) != 24 || binary.LittleEndian.Uint64(b) != 200 ||
+			math.Float64frombits(binary.LittleEndian.Uint64(b[8:])) != 1 ||
+			binary.LittleEndian.Uint32(b[16:]) != 2 || b[20] != 'b' || b[22] != 1 {
+			t.Fatalf("unexpected layout %x", b)
+		}
+
+		cursor, err := tx.OpenCursor(ticks.DBI)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+		var times []int64
+		key = Val{}
+		for tick, err = ticks.CursorGet(cursor, &key, CursorFirst); err == nil; tick, err = ticks.CursorGet(cursor, &key, CursorNext) {
+			times = append(times, tick.Time)
+		}
+		if err != ErrNotFound {
+			return err
+		}
+		if len(times) != 3 || times[0] != 100 || times[2] != 300 {
+			t.Fatalf("got %v", times)
+		}
+
+		short := StringConst("short")
+		if _, err := ticks.View(&short); !errors.Is(err, ErrBadValSize) {
+			t.Fatalf("got %v, want ErrBadValSize", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewRecords_Layout(t *testing.T) {
+	check := func(name string, err error, ok bool) {
+		t.Helper()
+		if ok != (err == nil) || (err != nil && !errors.Is(err, ErrRecordLayout)) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+	var padded struct {
+		A uint32
+		_ [4]byte
+		B [2]struct{ X, Y int64 }
+	}
+	_, err := NewRecords[struct {
+		A uint32
+		_ [4]byte
+		B [2]struct{ X, Y int64 }
+	}](1, Field[uint32](unsafe.Offsetof(padded.A)), Pad(4), ArrayField[int64](unsafe.Offsetof(padded.B), 4))
+	check("padded", err, true)
+
+	var implicit struct {
+		A uint32
+		B int64
+	}
+	_, err = NewRecords[struct {
+		A uint32
+		B int64
+	}](1, Field[uint32](unsafe.Offsetof(implicit.A)), Field[int64](unsafe.Offsetof(implicit.B)))
+	check("implicit padding", err, false)
+
+	var trailing struct {
+		A int64
+		B uint8
+	}
+	_, err = NewRecords[struct {
+		A int64
+		B uint8
+	}](1, Field[int64](unsafe.Offsetof(trailing.A)), Field[uint8](unsafe.Offsetof(trailing.B)))
+	check("trailing padding", err, false)
+
+	var pair struct{ A, B uint32 }
+	_, err = NewRecords[struct{ A, B uint32 }](1, Field[uint32](unsafe.Offsetof(pair.A)))
+	check("missing field", err, false)
+	_, err = NewRecords[struct{ A, B uint32 }](1, Field[uint32](unsafe.Offsetof(pair.B)), Field[uint32](unsafe.Offsetof(pair.A)))
+	check("out of order", err, false)
+	_, err = NewRecords[struct{ A, B uint32 }](1, Field[uint64](unsafe.Offsetof(pair.A)))
+	check("combined fields", err, true)
+	_, err = NewRecords[[3]byte](1, Pad(1), Field[uint16](1))
+	check("misaligned", err, false)
+	_, err = NewRecords[struct{}](1)
+	check("empty", err, false)
+	_, err = NewRecords[uint64](1, Field[uint64](0))
+	check("uint64", err, true)
+}
@@ -0,0 +1,345 @@
+package mdbx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync/atomic"
+)
+
+// Replication ships the change feed of a leader Store to followers over any
+// io.ReadWriter. The follower keeps its position in the environment Canary:
+// X holds the epoch of the leader's change feed and Y the ID of the last
+// leader transaction applied. Both are written in the same transaction as the
+// replicated changes, so a follower resumes exactly where it stopped.
+
+var (
+	ErrReplicaDiverged     = errors.New("mdbx: replica follows a different leader")
+	ErrReplicaTooOld       = errors.New("mdbx: replica position is no longer in the change feed")
+	ErrReplicationChecksum = errors.New("mdbx: replication frame checksum mismatch")
+	ErrReplicationProtocol = errors.New("mdbx: replication protocol error")
+)
+
+const (
+	frameHello byte = iota + 1
+	frameWelcome
+	frameChangeSet
+	frameAck
+	frameError
+
+	maxFrameSize = 1 << 30
+)
+
+const (
+	replicaErrDiverged byte = iota + 1
+	replicaErrTooOld
+	replicaErrInternal
+)
+
+// writeFrame writes type, big-endian length, payload and a CRC-32 of type and payload.
+func writeFrame(w io.Writer, typ byte, payload []byte) error {
+	header := [5]byte{typ}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write(header[:1])
+	_, _ = crc.Write(payload)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+
+	buf := make([]byte, 0, len(header)+len(payload)+len(sum))
+	buf = append(buf, header[:]...)
+	buf = append(buf, payload...)
+	buf = append(buf, sum[:]...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxFrameSize {
+		return 0, nil, ErrReplicationProtocol
+	}
+	buf := make([]byte, int(size)+4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	payload := buf[:size]
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write(header[:1])
+	_, _ = crc.Write(payload)
+	if crc.Sum32() != binary.BigEndian.Uint32(buf[size:]) {
+		return 0, nil, ErrReplicationChecksum
+	}
+	return header[0], payload, nil
+}
+
+func writeReplicaError(w io.Writer, code byte, err error) error {
+	_ = writeFrame(w, frameError, append([]byte{code}, err.Error()...))
+	return err
+}
+
+func decodeReplicaError(payload []byte) error {
+	if len(payload) == 0 {
+		return ErrReplicationProtocol
+	}
+	switch payload[0] {
+	case replicaErrDiverged:
+		return ErrReplicaDiverged
+	case replicaErrTooOld:
+		return ErrReplicaTooOld
+	}
+	return fmt.Errorf("mdbx: leader error: %s", payload[1:])
+}
+
+func uint64Pair(a, b uint64) []byte {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:], a)
+	binary.BigEndian.PutUint64(buf[8:], b)
+	return buf[:]
+}
+
+// ServeReplica streams the change feed to a single follower connected on rw
+// until the follower disconnects or the store is closed. The follower
+// announces its position first; the leader rejects it with ErrReplicaDiverged
+// if it was following another leader and with ErrReplicaTooOld if the
+// requested changes were already truncated, in which case the follower must be
+// bootstrapped again. onAck, if not nil, is called with the ID of every
+// transaction the follower has durably applied.
+//
+// Acknowledgements are read on another goroutine. If rw is an io.Closer,
+// ServeReplica closes it and waits for that goroutine before returning, so
+// onAck is not called afterwards. Otherwise the caller must make the pending
+// Read on rw fail to stop the goroutine.
+func (s *Store) ServeReplica(rw io.ReadWriter, onAck func(txID uint64)) error {
+	var acks chan struct{} // closed when the acknowledgement reader exits
+	defer func() {
+		if c, ok := rw.(io.Closer); ok {
+			_ = c.Close()
+			if acks != nil {
+				<-acks
+			}
+		}
+	}()
+
+	feed := s.changeFeed()
+	if feed == nil {
+		return ErrChangeFeedDisabled
+	}
+	typ, payload, err := readFrame(rw)
+	if err != nil {
+		return err
+	}
+	if typ != frameHello || len(payload) != 16 {
+		return writeReplicaError(rw, replicaErrInternal, ErrReplicationProtocol)
+	}
+	epoch := binary.BigEndian.Uint64(payload)
+	next := binary.BigEndian.Uint64(payload[8:])
+	if next == 0 {
+		next = 1
+	}
+
+	meta, err := feed.meta()
+	if err != nil {
+		return writeReplicaError(rw, replicaErrInternal, err)
+	}
+	if epoch != 0 && epoch != meta.Epoch {
+		return writeReplicaError(rw, replicaErrDiverged, ErrReplicaDiverged)
+	}
+	if next < meta.Truncated {
+		return writeReplicaError(rw, replicaErrTooOld, ErrReplicaTooOld)
+	}
+	if err = writeFrame(rw, frameWelcome, uint64Pair(meta.Epoch, next)); err != nil {
+		return err
+	}
+
+	sub, err := s.Subscribe(next)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	// The follower only sends acknowledgements from now on. A read error means
+	// it went away, which also has to interrupt a subscription waiting for commits.
+	var readErr atomic.Value
+	acks = make(chan struct{})
+	go func() {
+		defer close(acks)
+		for {
+			typ, payload, err := readFrame(rw)
+			if err == nil && (typ != frameAck || len(payload) != 8) {
+				err = ErrReplicationProtocol
+			}
+			if err != nil {
+				readErr.Store(err)
+				_ = sub.Close()
+				return
+			}
+			if onAck != nil {
+				onAck(binary.BigEndian.Uint64(payload))
+			}
+		}
+	}()
+
+	var buf []byte
+	for cs := range sub.C {
+		var id [8]byte
+		binary.BigEndian.PutUint64(id[:], cs.TxID)
+		buf = appendChanges(append(buf[:0], id[:]...), cs.DBIs, cs.Changes)
+		if err = writeFrame(rw, frameChangeSet, buf); err != nil {
+			return err
+		}
+	}
+	if err = sub.Err(); err != nil {
+		return err
+	}
+	if err, ok := readErr.Load().(error); ok && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// ReplicationPosition returns the leader epoch and the ID of the last leader
+// transaction applied to this store. Both are zero for a fresh follower.
+func (s *Store) ReplicationPosition() (epoch, txID uint64, err error) {
+	err = s.View(func(tx *Tx) error {
+		var canary Canary
+		if e := tx.GetCanary(&canary); e != nil {
+			return e
+		}
+		epoch, txID = canary.X, canary.Y
+		return nil
+	})
+	return
+}
+
+// Follow connects to a leader on rw and applies its change feed in order, one
+// Store.Update per leader transaction, until the connection is closed. It
+// returns nil if the leader closed the connection cleanly.
+func (s *Store) Follow(rw io.ReadWriter) error {
+	epoch, position, err := s.ReplicationPosition()
+	if err != nil {
+		return err
+	}
+	next := uint64(0)
+	if epoch != 0 {
+		next = position + 1
+	}
+	if err = writeFrame(rw, frameHello, uint64Pair(epoch, next)); err != nil {
+		return err
+	}
+
+	typ, payload, err := readFrame(rw)
+	if err != nil {
+		return err
+	}
+	switch {
+	case typ == frameError:
+		return decodeReplicaError(payload)
+	case typ != frameWelcome || len(payload) != 16:
+		return ErrReplicationProtocol
+	}
+	leaderEpoch := binary.BigEndian.Uint64(payload)
+	if epoch != 0 && leaderEpoch != epoch {
+		return ErrReplicaDiverged
+	}
+
+	dbis := make(map[string]DBI)
+	for {
+		typ, payload, err = readFrame(rw)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch {
+		case typ == frameError:
+			return decodeReplicaError(payload)
+		case typ != frameChangeSet || len(payload) < 8:
+			return ErrReplicationProtocol
+		}
+		cs, err := decodeChanges(binary.BigEndian.Uint64(payload), payload[8:])
+		if err != nil {
+			return err
+		}
+		if epoch == leaderEpoch && cs.TxID <= position {
+			continue
+		}
+		if err = s.applyChangeSet(leaderEpoch, &cs, dbis); err != nil {
+			return err
+		}
+		epoch, position = leaderEpoch, cs.TxID
+		var id [8]byte
+		binary.BigEndian.PutUint64(id[:], cs.TxID)
+		if err = writeFrame(rw, frameAck, id[:]); err != nil {
+			return err
+		}
+	}
+}
+
+// applyChangeSet applies a leader change set and records the new position.
+// dbis caches local handles by name across calls.
+func (s *Store) applyChangeSet(epoch uint64, cs *ChangeSet, dbis map[string]DBI) error {
+	err := s.Update(func(tx *Tx) error {
+		handles := make(map[DBI]ChangeDBI, len(cs.DBIs))
+		for _, d := range cs.DBIs {
+			local, ok := dbis[d.Name]
+			if !ok {
+				var err error
+				if local, err = tx.OpenDBI(d.Name, d.Flags|DBCreate); err != nil {
+					return err
+				}
+				dbis[d.Name] = local
+			}
+			handles[d.DBI] = ChangeDBI{DBI: local, Name: d.Name, Flags: d.Flags}
+		}
+
+		for _, c := range cs.Changes {
+			d, ok := handles[c.DBI]
+			if !ok {
+				return ErrCorruptChangeSet
+			}
+			key := sliceVal(c.Key)
+			var err error
+			switch c.Op {
+			case ChangePut:
+				data := sliceVal(c.New)
+				err = tx.Put(d.DBI, &key, &data, 0)
+			case ChangeDelete:
+				if d.Flags&DBDupSort != 0 && c.Old != nil {
+					data := sliceVal(c.Old)
+					err = tx.Delete(d.DBI, &key, &data)
+				} else {
+					err = tx.Delete(d.DBI, &key, nil)
+				}
+			case ChangeDrop:
+				err = tx.Drop(d.DBI, false)
+			case ChangeDeleteDBI:
+				err = tx.Drop(d.DBI, true)
+				delete(dbis, d.Name)
+			default:
+				return ErrCorruptChangeSet
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return tx.PutCanary(&Canary{X: epoch, Y: cs.TxID})
+	})
+	if err != nil {
+		// Handles opened by an aborted transaction are no longer valid.
+		for name := range dbis {
+			delete(dbis, name)
+		}
+	}
+	return err
+}
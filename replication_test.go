@@ -0,0 +1,184 @@
+package mdbx
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReplication_Follow(t *testing.T) {
+	leader := openTestStore(t, "", EnvSafeNoSync)
+	if err := leader.EnableChangeFeed("cdc"); err != nil {
+		t.Fatal(err)
+	}
+	dbi := openTestDBI(t, leader, "kv", 0)
+	put := func(key, value string) {
+		if err := leader.Update(func(tx *Tx) error {
+			k, v := StringConst(key), StringConst(value)
+			return tx.Put(dbi, &k, &v, 0)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a", "1")
+	put("b", "2")
+
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "replica.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	acks := make(chan uint64, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = leader.ServeReplica(conn, func(txID uint64) {
+					acks <- txID
+				})
+			}()
+		}
+	}()
+
+	followerPath := t.TempDir()
+	follow := func(until uint64) {
+		follower := openTestStore(t, followerPath, EnvSafeNoSync)
+		defer follower.Close()
+		conn, err := net.Dial("unix", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- follower.Follow(conn)
+		}()
+		for {
+			select {
+			case id := <-acks:
+				if id >= until {
+					_ = conn.Close()
+					<-done
+					return
+				}
+			case err := <-done:
+				t.Fatalf("follow stopped: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for replication")
+			}
+		}
+	}
+
+	lastTxID := func() uint64 {
+		var id uint64
+		_ = leader.View(func(tx *Tx) error {
+			id = tx.ID()
+			return nil
+		})
+		return id
+	}
+
+	follow(lastTxID())
+	put("a", "3")
+	if err = leader.Update(func(tx *Tx) error {
+		k := StringConst("b")
+		return tx.Delete(dbi, &k, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	follow(lastTxID())
+
+	follower := openTestStore(t, followerPath, EnvSafeNoSync)
+	_, position, err := follower.ReplicationPosition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position != lastTxID() {
+		t.Fatalf("follower position %d, leader at %d", position, lastTxID())
+	}
+	local := openTestDBI(t, follower, "kv", 0)
+	if err = follower.View(func(tx *Tx) error {
+		k, v := StringConst("a"), Val{}
+		if err := tx.Get(local, &k, &v); err != nil {
+			return err
+		}
+		if v.String() != "3" {
+			t.Fatalf("expected 3, got %q", v.String())
+		}
+		k = StringConst("b")
+		if err := tx.Get(local, &k, &v); err != ErrNotFound {
+			t.Fatalf("expected b to be deleted, got %v", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplication_TooOld(t *testing.T) {
+	leader := openTestStore(t, "", EnvSafeNoSync)
+	if err := leader.EnableChangeFeed("cdc"); err != nil {
+		t.Fatal(err)
+	}
+	dbi := openTestDBI(t, leader, "kv", 0)
+	var txID uint64
+	for i := 0; i < 3; i++ {
+		if err := leader.Update(func(tx *Tx) error {
+			txID = tx.ID()
+			k, v := StringConst("k"), StringConst("v")
+			return tx.Put(dbi, &k, &v, 0)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := leader.TruncateChangeFeed(txID); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	go func() {
+		_ = leader.ServeReplica(a, nil)
+	}()
+	follower := openTestStore(t, "", EnvSafeNoSync)
+	if err := follower.Follow(b); err != ErrReplicaTooOld {
+		t.Fatalf("expected ErrReplicaTooOld, got %v", err)
+	}
+}
+
+func TestReplication_ServeCloses(t *testing.T) {
+	leader := openTestStore(t, "", EnvSafeNoSync)
+	if err := leader.EnableChangeFeed("cdc"); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := net.Pipe()
+	defer b.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- leader.ServeReplica(a, func(uint64) {
+			t.Error("acknowledgement after ServeReplica returned")
+		})
+	}()
+	if err := writeFrame(b, frameHello, uint64Pair(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if typ, _, err := readFrame(b); err != nil || typ != frameWelcome {
+		t.Fatalf("expected welcome, got %d, %v", typ, err)
+	}
+
+	_ = leader.Close()
+	if err := <-done; err != os.ErrClosed {
+		t.Fatalf("expected os.ErrClosed, got %v", err)
+	}
+	// The connection is closed, the acknowledgement reader has exited.
+	if err := writeFrame(b, frameAck, make([]byte, 8)); err == nil {
+		t.Fatal("expected write to closed connection to fail")
+	}
+}
@@ -0,0 +1,71 @@
+package mdbx
+
+import (
+	"io"
+)
+
+// PutWriter reserves size bytes for the value of key with PutReserve and
+// returns a writer filling them in place, avoiding an intermediate buffer
+// for large values. The writer implements io.ReaderFrom, so io.Copy reads
+// straight into the reserved space.
+//
+// The caller must write exactly size bytes before the database is modified
+// again. Writes past size fail with io.ErrShortWrite, io.Copy stops once
+// size bytes were copied. Bytes that are not written are undefined.
+func (tx *Tx) PutWriter(dbi DBI, key *Val, size int) (io.Writer, error) {
+	data := Val{Len: uint64(size)}
+	if err := tx.Put(dbi, key, &data, PutReserve); err != nil {
+		return nil, err
+	}
+	return &reserveWriter{buf: data.UnsafeBytes()}, nil
+}
+
+// PutFrom stores size bytes read from r as the value of key, reading them
+// directly into the space reserved with PutReserve. If r ends early it
+// returns io.ErrUnexpectedEOF, the value is then incomplete and the
+// transaction should be aborted.
+func (tx *Tx) PutFrom(dbi DBI, key *Val, r io.Reader, size int) error {
+	data := Val{Len: uint64(size)}
+	if err := tx.Put(dbi, key, &data, PutReserve); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, data.UnsafeBytes()); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+type reserveWriter struct {
+	buf []byte
+	n   int
+}
+
+func (w *reserveWriter) Write(p []byte) (int, error) {
+	n := copy(w.buf[w.n:], p)
+	w.n += n
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// ReadFrom reads from r until the reserved space is full or r ends. It does
+// not read past the reserved space, so that a larger r is not detected.
+func (w *reserveWriter) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for w.n < len(w.buf) {
+		n, err := r.Read(w.buf[w.n:])
+		w.n += n
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
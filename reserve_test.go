@@ -0,0 +1,65 @@
+package mdbx
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestTx_PutWriter(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "blobs", DBDefaults)
+	blob := make([]byte, 3<<20)
+	rand.New(rand.NewSource(1)).Read(blob)
+
+	if err := store.Update(func(tx *Tx) error {
+		key := StringConst("copy")
+		w, err := tx.PutWriter(dbi, &key, len(blob))
+		if err != nil {
+			return err
+		}
+		if n, err := io.Copy(w, bytes.NewReader(blob)); err != nil || n != int64(len(blob)) {
+			t.Fatalf("io.Copy: %d, %v", n, err)
+		}
+
+		key = StringConst("write")
+		if w, err = tx.PutWriter(dbi, &key, 5); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte("abc")); err != nil {
+			return err
+		}
+		if n, err := w.Write([]byte("defg")); n != 2 || err != io.ErrShortWrite {
+			t.Fatalf("write past size: %d, %v", n, err)
+		}
+
+		key = StringConst("from")
+		return tx.PutFrom(dbi, &key, bytes.NewReader(blob), len(blob))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		for key, want := range map[string][]byte{"copy": blob, "write": []byte("abcde"), "from": blob} {
+			k, v := StringConst(key), Val{}
+			if err := tx.Get(dbi, &k, &v); err != nil {
+				return err
+			}
+			if !bytes.Equal(v.UnsafeBytes(), want) {
+				t.Errorf("%s: value differs", key)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.Update(func(tx *Tx) error {
+		key := StringConst("short")
+		return tx.PutFrom(dbi, &key, bytes.NewReader(blob[:10]), 20)
+	})
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,121 @@
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy makes Store.Update re-run its closure after transient errors
+// such as ErrBusy, with exponential backoff and jitter. The closure must be
+// idempotent. The write lock of the Store is released while waiting.
+type RetryPolicy struct {
+	// MaxAttempts bounds the number of runs, including the first. Defaults to 3.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+
+	// InitialBackoff is the delay before the second attempt. Defaults to 1ms.
+	InitialBackoff Duration `json:"initial_backoff,omitempty"`
+
+	// MaxBackoff caps the delay between attempts. Defaults to 100ms.
+	MaxBackoff Duration `json:"max_backoff,omitempty"`
+
+	// Multiplier grows the delay after every attempt. Defaults to 2.
+	Multiplier float64 `json:"multiplier,omitempty"`
+
+	// Jitter is the fraction of each delay that is randomized, from 0 to 1.
+	Jitter float64 `json:"jitter,omitempty"`
+
+	// Retryable classifies errors. Defaults to IsTemporary.
+	Retryable func(err error) bool `json:"-"`
+
+	// OnRetry is called before waiting for the next attempt.
+	OnRetry func(attempt int, err error, delay time.Duration) `json:"-"`
+}
+
+func (p *RetryPolicy) validate() error {
+	switch {
+	case p.MaxAttempts < 0:
+		return fmt.Errorf("%w: retry max_attempts is negative", ErrInvalidOptions)
+	case p.InitialBackoff < 0 || p.MaxBackoff < 0:
+		return fmt.Errorf("%w: retry backoff is negative", ErrInvalidOptions)
+	case p.Multiplier != 0 && p.Multiplier < 1:
+		return fmt.Errorf("%w: retry multiplier is below 1", ErrInvalidOptions)
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("%w: retry jitter must be between 0 and 1", ErrInvalidOptions)
+	}
+	return nil
+}
+
+func (p *RetryPolicy) setDefaults() {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = Duration(time.Millisecond)
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = Duration(100 * time.Millisecond)
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = 2
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsTemporary
+	}
+}
+
+// backoff returns the delay before the attempt following the failed one,
+// or false if err must be returned.
+func (p *RetryPolicy) backoff(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || !p.Retryable(err) {
+		return 0, false
+	}
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+		if delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+	return time.Duration(delay), true
+}
+
+// SetRetryPolicy installs the retry policy used by Update. A nil policy
+// disables retries.
+func (s *Store) SetRetryPolicy(policy *RetryPolicy) error {
+	if policy != nil {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		p := *policy
+		p.setDefaults()
+		policy = &p
+	}
+	s.writeMu.Lock()
+	s.retry = policy
+	s.writeMu.Unlock()
+	return nil
+}
+
+// retryDelay returns how long to wait before retrying a failed update.
+// Stale readers are cleared first when the reader table is full.
+func (s *Store) retryDelay(policy *RetryPolicy, attempt int, err error) (time.Duration, bool) {
+	delay, ok := policy.backoff(attempt, err)
+	if !ok {
+		return 0, false
+	}
+	if errors.Is(err, ErrReadersFull) {
+		_, _ = s.env.ReaderCheck()
+	}
+	if policy.OnRetry != nil {
+		policy.OnRetry(attempt, err, delay)
+	}
+	return delay, true
+}
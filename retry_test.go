@@ -0,0 +1,82 @@
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestError_Temporary(t *testing.T) {
+	for _, err := range []Error{ErrBusy, ErrEINTR, ErrReadersFull, ErrTXNFull} {
+		if !err.Temporary() || !errors.Is(err, ErrTemporary) || !IsTemporary(fmt.Errorf("wrapped: %w", err)) {
+			t.Errorf("%d should be temporary", int32(err))
+		}
+	}
+	for _, err := range []Error{ErrNotFound, ErrCorrupted, ErrMapFull, ErrSuccess} {
+		if err.Temporary() || errors.Is(err, ErrTemporary) {
+			t.Errorf("%d should not be temporary", int32(err))
+		}
+	}
+}
+
+func TestStore_RetryPolicy(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+
+	var delays []time.Duration
+	if err := store.SetRetryPolicy(&RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: Duration(time.Millisecond),
+		MaxBackoff:     Duration(3 * time.Millisecond),
+		Jitter:         0.5,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			delays = append(delays, delay)
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := 0
+	if err := store.Update(func(tx *Tx) error {
+		runs++
+		k, v := StringConst("key"), StringConst(fmt.Sprint(runs))
+		if err := tx.Put(dbi, &k, &v, 0); err != nil {
+			return err
+		}
+		if runs < 3 {
+			return fmt.Errorf("attempt %d: %w", runs, ErrBusy)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 3 || len(delays) != 2 {
+		t.Fatalf("runs %d, delays %v", runs, delays)
+	}
+	for i, max := range []time.Duration{time.Millisecond, 2 * time.Millisecond} {
+		if delays[i] < max/2 || delays[i] > max {
+			t.Fatalf("delay %d is %v, want %v..%v", i, delays[i], max/2, max)
+		}
+	}
+
+	// Attempts are bounded and fatal errors are returned at once.
+	runs = 0
+	if err := store.Update(func(tx *Tx) error {
+		runs++
+		return ErrReadersFull
+	}); err != ErrReadersFull || runs != 4 {
+		t.Fatalf("got %v after %d runs", err, runs)
+	}
+	runs = 0
+	if err := store.Update(func(tx *Tx) error {
+		runs++
+		return ErrCorrupted
+	}); err != ErrCorrupted || runs != 1 {
+		t.Fatalf("got %v after %d runs", err, runs)
+	}
+
+	if err := store.SetRetryPolicy(&RetryPolicy{Jitter: 2}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected invalid policy, got %v", err)
+	}
+}
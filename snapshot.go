@@ -0,0 +1,441 @@
+package mdbx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot format
+//
+// A snapshot is a logical image of every database in the environment as seen
+// by a single read transaction. It does not depend on page size or geometry.
+// All integers are big-endian unless noted as uvarint.
+//
+//	header   "MDBXSNAP" | version u32 | txID u64 | canary X,Y,Z,V u64 |
+//	         feed epoch u64 | number of databases u32
+//	database 'D' | flags u32 | uvarint name length | name
+//	record   'R' | uvarint key length | key | uvarint value length | value
+//	end      'E' | number of records u64
+//	trailer  'Z' | CRC-32 (IEEE) of every preceding byte including 'Z' u32
+//
+// Each database is a 'D' section followed by its records in key order (and
+// duplicate order for DBDupSort) and an 'E' marker. The main database comes
+// first with an empty name and contains only plain records. The feed epoch is
+// zero unless the change feed is enabled.
+
+var (
+	ErrSnapshotFormat   = errors.New("mdbx: invalid snapshot")
+	ErrSnapshotChecksum = errors.New("mdbx: snapshot checksum mismatch")
+)
+
+const (
+	snapshotMagic   = "MDBXSNAP"
+	snapshotVersion = 1
+
+	snapshotDBI    byte = 'D'
+	snapshotRecord byte = 'R'
+	snapshotEnd    byte = 'E'
+	snapshotDone   byte = 'Z'
+
+	restoreBatchBytes = 64 * 1024 * 1024
+)
+
+// DBINames returns the names of all named databases in the environment. It
+// works in read-only transactions and opens a handle for every database found.
+func (tx *Tx) DBINames() ([]string, error) {
+	main, err := tx.OpenDBI("", 0)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := tx.OpenCursor(main)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	var names []string
+	key, data := Val{}, Val{}
+	for {
+		if err = cursor.Get(&key, &data, CursorNextNoDup); err != nil {
+			if err == ErrNotFound {
+				return names, nil
+			}
+			return nil, err
+		}
+		name := key.String()
+		if len(name) == 0 || containsZero(key.UnsafeBytes()) {
+			continue
+		}
+		// Plain records of the main database fail with ErrIncompatible.
+		if _, err = tx.OpenDBI(name, DBAccede); err == nil {
+			names = append(names, name)
+		}
+	}
+}
+
+func containsZero(b []byte) bool {
+	for _, c := range b {
+		if c == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type snapshotWriter struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	buf []byte
+}
+
+func (sw *snapshotWriter) write(b []byte) error {
+	_, _ = sw.crc.Write(b)
+	_, err := sw.w.Write(b)
+	return err
+}
+
+func (sw *snapshotWriter) record(key, value []byte) error {
+	sw.buf = append(sw.buf[:0], snapshotRecord)
+	sw.buf = appendUvarint(sw.buf, uint64(len(key)))
+	sw.buf = append(sw.buf, key...)
+	sw.buf = appendUvarint(sw.buf, uint64(len(value)))
+	sw.buf = append(sw.buf, value...)
+	return sw.write(sw.buf)
+}
+
+func (sw *snapshotWriter) dbi(tx *Tx, name string, dbi DBI, skip map[string]bool) error {
+	flags, _, err := tx.DBIFlags(dbi)
+	if err != nil {
+		return err
+	}
+	sw.buf = append(sw.buf[:0], snapshotDBI)
+	sw.buf = appendUint32(sw.buf, uint32(flags))
+	sw.buf = appendUvarint(sw.buf, uint64(len(name)))
+	sw.buf = append(sw.buf, name...)
+	if e := sw.write(sw.buf); e != nil {
+		return e
+	}
+
+	cursor, err := tx.OpenCursor(dbi)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	count := uint64(0)
+	key, data := Val{}, Val{}
+	for {
+		if err = cursor.Get(&key, &data, CursorNext); err != nil {
+			if err == ErrNotFound {
+				break
+			}
+			return err
+		}
+		if skip != nil && skip[key.UnsafeString()] {
+			continue
+		}
+		if e := sw.record(key.UnsafeBytes(), data.UnsafeBytes()); e != nil {
+			return e
+		}
+		count++
+	}
+	var end [9]byte
+	end[0] = snapshotEnd
+	binary.BigEndian.PutUint64(end[1:], count)
+	return sw.write(end[:])
+}
+
+// Snapshot writes a consistent image of every database to w and returns the
+// ID of the read transaction it was taken at. The change feed DBI is not
+// included. The format is described at the top of this file.
+func (s *Store) Snapshot(w io.Writer) (txID uint64, err error) {
+	feed := s.changeFeed()
+	sw := &snapshotWriter{w: bufio.NewWriterSize(w, 1024*1024), crc: crc32.NewIEEE()}
+	err = s.View(func(tx *Tx) error {
+		txID = tx.ID()
+		names, err := tx.DBINames()
+		if err != nil {
+			return err
+		}
+		var canary Canary
+		if err = tx.GetCanary(&canary); err != nil {
+			return err
+		}
+
+		// Named databases are stored as records of the main database.
+		skip := make(map[string]bool, len(names))
+		for _, name := range names {
+			skip[name] = true
+		}
+		var epoch uint64
+		if feed != nil {
+			if name, ok := s.env.DBIName(feed.dbi); ok && skip[name] {
+				meta, err := readFeedMeta(tx, feed.dbi)
+				if err != nil {
+					return err
+				}
+				epoch = meta.Epoch
+				names = removeName(names, name)
+			}
+		}
+
+		header := make([]byte, 0, 64)
+		header = append(header, snapshotMagic...)
+		header = appendUint32(header, snapshotVersion)
+		header = appendUint64(header, txID)
+		header = appendUint64(header, canary.X)
+		header = appendUint64(header, canary.Y)
+		header = appendUint64(header, canary.Z)
+		header = appendUint64(header, canary.V)
+		header = appendUint64(header, epoch)
+		header = appendUint32(header, uint32(len(names)))
+		if e := sw.write(header); e != nil {
+			return e
+		}
+
+		main, err := tx.OpenDBI("", 0)
+		if err != nil {
+			return err
+		}
+		if e := sw.dbi(tx, "", main, skip); e != nil {
+			return e
+		}
+		for _, name := range names {
+			dbi, err := tx.OpenDBI(name, DBAccede)
+			if err != nil {
+				return err
+			}
+			if e := sw.dbi(tx, name, dbi, nil); e != nil {
+				return e
+			}
+		}
+
+		if e := sw.write([]byte{snapshotDone}); e != nil {
+			return e
+		}
+		var sum [4]byte
+		binary.BigEndian.PutUint32(sum[:], sw.crc.Sum32())
+		if _, e := sw.w.Write(sum[:]); e != nil {
+			return e
+		}
+		return sw.w.Flush()
+	})
+	return txID, err
+}
+
+func removeName(names []string, name string) []string {
+	for i, n := range names {
+		if n == name {
+			return append(names[:i], names[i+1:]...)
+		}
+	}
+	return names
+}
+
+type snapshotReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (sr *snapshotReader) ReadByte() (byte, error) {
+	b, err := sr.r.ReadByte()
+	if err == nil {
+		_, _ = sr.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+func (sr *snapshotReader) read(b []byte) error {
+	if _, err := io.ReadFull(sr.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	_, _ = sr.crc.Write(b)
+	return nil
+}
+
+func (sr *snapshotReader) bytes(buf []byte) ([]byte, error) {
+	n, err := binary.ReadUvarint(sr)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(MaxDataSize) {
+		return nil, ErrSnapshotFormat
+	}
+	if uint64(cap(buf)) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	return buf, sr.read(buf)
+}
+
+// Restore recreates an environment at path from a snapshot written by
+// Store.Snapshot and returns the opened store and the transaction ID the
+// snapshot was taken at. path must not contain an existing database. initEnv
+// may configure geometry and other settings; the maximum number of databases
+// is raised as needed.
+//
+// If the snapshot was taken from a store with the change feed enabled, the
+// Canary is set to the replication position of the snapshot so the new store
+// can Follow the leader from there. Otherwise the source Canary is restored.
+func Restore(
+	r io.Reader,
+	path string,
+	flags EnvFlags,
+	mode os.FileMode,
+	initEnv func(env *Env, create bool) error,
+) (*Store, uint64, error) {
+	if _, err := os.Stat(filepath.Join(path, DataFileName)); err == nil {
+		return nil, 0, os.ErrExist
+	}
+	sr := &snapshotReader{r: bufio.NewReaderSize(r, 1024*1024), crc: crc32.NewIEEE()}
+	header := make([]byte, len(snapshotMagic)+4+8*6+4)
+	if err := sr.read(header); err != nil {
+		return nil, 0, err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, 0, ErrSnapshotFormat
+	}
+	h := header[len(snapshotMagic):]
+	if binary.BigEndian.Uint32(h) != snapshotVersion {
+		return nil, 0, ErrSnapshotFormat
+	}
+	txID := binary.BigEndian.Uint64(h[4:])
+	canary := Canary{
+		X: binary.BigEndian.Uint64(h[12:]),
+		Y: binary.BigEndian.Uint64(h[20:]),
+		Z: binary.BigEndian.Uint64(h[28:]),
+	}
+	if epoch := binary.BigEndian.Uint64(h[44:]); epoch != 0 {
+		canary = Canary{X: epoch, Y: txID}
+	}
+	numDBIs := binary.BigEndian.Uint32(h[52:])
+
+	store, err := Open(path, flags, mode, func(env *Env, create bool) error {
+		if initEnv != nil {
+			if err := initEnv(env, create); err != nil {
+				return err
+			}
+		}
+		if max, err := env.GetMaxDBS(); err != nil {
+			return err
+		} else if max < uint64(numDBIs) {
+			return env.SetMaxDBS(uint16(numDBIs))
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = restoreDBIs(store, sr, canary); err != nil {
+		_ = store.Close()
+		return nil, 0, err
+	}
+	return store, txID, nil
+}
+
+func restoreDBIs(store *Store, sr *snapshotReader, canary Canary) error {
+	var (
+		key, value []byte
+		dbi        DBI
+		flags      DBFlags
+		count      uint64
+		open       bool
+	)
+	for {
+		// Each batch is a separate write transaction to bound its size.
+		done := false
+		err := store.Update(func(tx *Tx) error {
+			size := 0
+			for size < restoreBatchBytes {
+				typ, err := sr.ReadByte()
+				if err != nil {
+					return io.ErrUnexpectedEOF
+				}
+				switch typ {
+				case snapshotDBI:
+					if open {
+						return ErrSnapshotFormat
+					}
+					var f [4]byte
+					if err = sr.read(f[:]); err != nil {
+						return err
+					}
+					flags = DBFlags(binary.BigEndian.Uint32(f[:]))
+					name, err := sr.bytes(nil)
+					if err != nil {
+						return err
+					}
+					var e error
+					if dbi, e = tx.OpenDBI(string(name), flags|DBCreate); e != nil {
+						return e
+					}
+					open, count = true, 0
+
+				case snapshotRecord:
+					if !open {
+						return ErrSnapshotFormat
+					}
+					if key, err = sr.bytes(key); err != nil {
+						return err
+					}
+					if value, err = sr.bytes(value); err != nil {
+						return err
+					}
+					k, v := sliceVal(key), sliceVal(value)
+					put := PutAppend
+					if flags&DBDupSort != 0 {
+						put = PutAppendDup
+					}
+					if e := tx.Put(dbi, &k, &v, put); e != nil {
+						return e
+					}
+					size += len(key) + len(value)
+					count++
+
+				case snapshotEnd:
+					var n [8]byte
+					if err = sr.read(n[:]); err != nil {
+						return err
+					}
+					if !open || binary.BigEndian.Uint64(n[:]) != count {
+						return ErrSnapshotFormat
+					}
+					open = false
+
+				case snapshotDone:
+					sum := sr.crc.Sum32()
+					var n [4]byte
+					if _, err = io.ReadFull(sr.r, n[:]); err != nil {
+						return io.ErrUnexpectedEOF
+					}
+					if open {
+						return ErrSnapshotFormat
+					}
+					if binary.BigEndian.Uint32(n[:]) != sum {
+						return ErrSnapshotChecksum
+					}
+					done = true
+					return tx.PutCanary(&canary)
+
+				default:
+					return ErrSnapshotFormat
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
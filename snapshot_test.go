@@ -0,0 +1,90 @@
+package mdbx
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestStore_SnapshotRestore(t *testing.T) {
+	source := openTestStore(t, "", EnvSafeNoSync)
+	if err := source.EnableChangeFeed("cdc"); err != nil {
+		t.Fatal(err)
+	}
+	kv := openTestDBI(t, source, "kv", 0)
+	dups := openTestDBI(t, source, "dups", DBDupSort)
+	main := openTestDBI(t, source, "", 0)
+	if err := source.Update(func(tx *Tx) error {
+		for i := 0; i < 1000; i++ {
+			k, v := StringConst(fmt.Sprintf("key-%04d", i)), StringConst(fmt.Sprintf("value-%d", i))
+			if err := tx.Put(kv, &k, &v, 0); err != nil {
+				return err
+			}
+			d := StringConst(fmt.Sprintf("dup-%d", i%7))
+			if err := tx.Put(dups, &d, &k, 0); err != nil {
+				return err
+			}
+		}
+		k, v := StringConst("~plain"), StringConst("main")
+		return tx.Put(main, &k, &v, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	txID, err := source.Snapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore with a different page size.
+	restored, restoredTxID, err := Restore(bytes.NewReader(buf.Bytes()), t.TempDir(), EnvSafeNoSync, 0, func(env *Env, create bool) error {
+		return env.SetGeometry(Geometry{
+			SizeLower:  1024 * 1024,
+			SizeNow:    1024 * 1024,
+			SizeUpper:  1024 * 1024 * 64,
+			GrowthStep: 1024 * 1024,
+			PageSize:   16384,
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if restoredTxID != txID {
+		t.Fatalf("restored tx id %d, snapshot at %d", restoredTxID, txID)
+	}
+	if _, position, err := restored.ReplicationPosition(); err != nil || position != txID {
+		t.Fatalf("replication position %d, %v", position, err)
+	}
+
+	var again bytes.Buffer
+	if _, err = restored.Snapshot(&again); err != nil {
+		t.Fatal(err)
+	}
+	// Skip headers which include tx ids, canary and epoch.
+	const headerSize = 8 + 4 + 8*6 + 4
+	if !bytes.Equal(buf.Bytes()[headerSize:len(buf.Bytes())-4], again.Bytes()[headerSize:len(again.Bytes())-4]) {
+		t.Fatal("restored snapshot differs from source")
+	}
+}
+
+func TestRestore_Checksum(t *testing.T) {
+	source := openTestStore(t, "", EnvSafeNoSync)
+	kv := openTestDBI(t, source, "kv", 0)
+	if err := source.Update(func(tx *Tx) error {
+		k, v := StringConst("k"), StringConst("v")
+		return tx.Put(kv, &k, &v, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := source.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	b[len(b)-8] ^= 0xFF
+	if _, _, err := Restore(bytes.NewReader(b), t.TempDir(), EnvSafeNoSync, 0, nil); err == nil {
+		t.Fatal("expected restore of corrupted snapshot to fail")
+	}
+}
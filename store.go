@@ -1,12 +1,14 @@
 package mdbx
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
 const (
@@ -33,6 +35,11 @@ type Store struct {
 	synced           uint64
 	syncQueued       uint64
 	syncPeriod       time.Duration
+	feed             *changeFeed
+	catalog          map[string]Table
+	catalogMu        sync.RWMutex
+	mapGrowth        *MapGrowth
+	retry            *RetryPolicy
 	writeMu          sync.Mutex
 	syncMu           sync.Mutex
 	mu               sync.Mutex
@@ -50,7 +57,7 @@ func Open(
 	}
 
 	env, e := NewEnv()
-	if e != ErrSuccess {
+	if e != nil {
 		return nil, e
 	}
 	store.env = env
@@ -68,7 +75,7 @@ func Open(
 	_ = stat
 
 	if initEnv != nil {
-		if err = initEnv(env, create); err != nil && err != ErrSuccess {
+		if err = initEnv(env, create); err != nil {
 			store.Close()
 			return nil, err
 		}
@@ -78,18 +85,18 @@ func Open(
 		mode = 0664
 	}
 
-	if err = store.env.Open(path, flags, mode); err != ErrSuccess {
-		if err == ErrWannaRecovery {
+	if err = store.env.Open(path, flags, mode); err != nil {
+		if errors.Is(err, ErrWannaRecovery) {
 			start := time.Now()
 			_, output, err := Chk("-v", "-w", store.path)
-			if err != ErrSuccess {
+			if err != nil {
 				_ = store.Close()
 				return nil, err
 			}
 			store.recovery = string(output)
 			store.recoveryDuration = time.Now().Sub(start)
 
-			if err = store.env.Open(path, flags, mode); err != ErrSuccess {
+			if err = store.env.Open(path, flags, mode); err != nil {
 				_ = store.Close()
 				return nil, err
 			}
@@ -99,13 +106,13 @@ func Open(
 		}
 	}
 
-	if _, err = store.env.ReaderCheck(); err != nil && err != ErrSuccess {
+	if _, err = store.env.ReaderCheck(); err != nil {
 		_ = store.Close()
 		return nil, err
 	}
 
 	if init != nil {
-		if err = init(store, create); err != nil && err != ErrSuccess {
+		if err = init(store, create); err != nil {
 			_ = store.Close()
 			return nil, err
 		}
@@ -146,6 +153,9 @@ func (s *Store) Close() error {
 		return os.ErrClosed
 	}
 	s.closed = time.Now().UnixNano()
+	if s.feed != nil {
+		s.feed.close()
+	}
 	if s.env != nil {
 		_ = s.env.Close(false)
 		s.env = nil
@@ -160,21 +170,44 @@ func (s *Store) Update(fn func(tx *Tx) error) error {
 	return s.UpdateLock(true, fn)
 }
 
-func (s *Store) UpdateLock(lockThread bool, fn func(tx *Tx) error) (err error) {
+// UpdateLock runs fn in a write transaction and commits it if fn returns nil.
+// With a MapGrowth policy installed, a transaction failing because the map is
+// full is aborted and fn is run again after the datafile upper bound was raised.
+// With a RetryPolicy installed, fn is run again after transient errors.
+func (s *Store) UpdateLock(lockThread bool, fn func(tx *Tx) error) error {
 	if lockThread {
 		// Write transactions must be bound to a single thread.
 		runtime.LockOSThread()
 		defer runtime.UnlockOSThread()
 	}
 
-	// Get exclusive write lock.
-	s.writeMu.Lock()
-	defer s.writeMu.Unlock()
+	for attempt := 1; ; attempt++ {
+		// Get exclusive write lock.
+		s.writeMu.Lock()
+		err := s.update(fn)
+		for err != nil && s.mapGrowth != nil && isMapFull(err) && s.growMap(err) {
+			err = s.update(fn)
+		}
+		retry := s.retry
+		s.writeMu.Unlock()
+
+		if err == nil || retry == nil {
+			return err
+		}
+		delay, ok := s.retryDelay(retry, attempt, err)
+		if !ok {
+			return err
+		}
+		time.Sleep(delay)
+	}
+}
+
+func (s *Store) update(fn func(tx *Tx) error) (err error) {
 	tx := Tx{}
 	defer func() {
 		// Abort if panic
 		if !tx.IsCommitted() && !tx.IsAborted() && tx.txn != nil {
-			if e := tx.Abort(); e != ErrSuccess {
+			if e := tx.Abort(); e != nil {
 				// Ignore
 			}
 		}
@@ -188,20 +221,26 @@ func (s *Store) UpdateLock(lockThread bool, fn func(tx *Tx) error) (err error) {
 		}
 	}()
 
-	if err = s.env.Begin(&tx, TxReadWrite); err != ErrSuccess {
+	if err = s.env.Begin(&tx, TxReadWrite); err != nil {
 		return err
 	}
-	if err = fn(&tx); err != nil && err != ErrSuccess {
+	if s.feed != nil {
+		tx.changes = &changeLog{feed: s.feed}
+		txn := unsafe.Pointer(tx.txn)
+		changeTxs.Store(txn, &tx)
+		defer changeTxs.Delete(txn)
+	}
+	if err = fn(&tx); err != nil {
 		// Abort if necessary
 		if !tx.IsAborted() && !tx.IsCommitted() {
-			if e := tx.Abort(); e != ErrSuccess {
+			if e := tx.Abort(); e != nil {
 				// Ignore
 			}
 		}
 		return err
 	} else {
 		if !tx.IsCommitted() {
-			if err = tx.Commit(); err != ErrSuccess {
+			if err = tx.Commit(); err != nil {
 				return err
 			}
 		}
@@ -211,6 +250,10 @@ func (s *Store) UpdateLock(lockThread bool, fn func(tx *Tx) error) (err error) {
 }
 
 func (s *Store) View(fn func(tx *Tx) error) (err error) {
+	// Read transactions are bound to the thread that started them.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
 	tx := Tx{}
 	defer func() {
 		if !tx.IsAborted() {
@@ -224,7 +267,7 @@ func (s *Store) View(fn func(tx *Tx) error) (err error) {
 			}
 		}
 	}()
-	if err = s.env.Begin(&tx, TxReadOnly); err != ErrSuccess {
+	if err = s.env.Begin(&tx, TxReadOnly); err != nil {
 		return err
 	}
 	return fn(&tx)
@@ -234,6 +277,8 @@ func (s *Store) ViewRenew(tx *Tx, fn func(tx *Tx) error) (err error) {
 	if tx == nil {
 		return s.View(fn)
 	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
 	defer func() {
 		if !tx.IsReset() {
 			err = tx.Reset()
@@ -246,7 +291,7 @@ func (s *Store) ViewRenew(tx *Tx, fn func(tx *Tx) error) (err error) {
 			}
 		}
 	}()
-	if err = tx.Renew(); err != ErrSuccess {
+	if err = tx.Renew(); err != nil {
 		return err
 	}
 	return fn(tx)
@@ -254,7 +299,7 @@ func (s *Store) ViewRenew(tx *Tx, fn func(tx *Tx) error) (err error) {
 
 func (s *Store) Sync() error {
 	update := atomic.LoadUint64(&s.updates)
-	if err := s.env.Sync(true, false); err != ErrSuccess && err != ErrResultTrue {
+	if err := s.env.Sync(true, false); err != nil {
 		return err
 	}
 	atomic.StoreUint64(&s.synced, update)
@@ -0,0 +1,116 @@
+package mdbx
+
+import (
+	"runtime"
+	"testing"
+)
+
+func openTestStore(t testing.TB, path string, flags EnvFlags) *Store {
+	t.Helper()
+	if path == "" {
+		path = t.TempDir()
+	}
+	store, err := Open(path, flags, 0664, func(env *Env, create bool) error {
+		if err := env.SetGeometry(Geometry{
+			SizeLower:       1024 * 1024,
+			SizeNow:         1024 * 1024,
+			SizeUpper:       1024 * 1024 * 256,
+			GrowthStep:      1024 * 1024,
+			ShrinkThreshold: 0,
+			PageSize:        4096,
+		}); err != nil {
+			return err
+		}
+		return env.SetMaxDBS(16)
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+func openTestDBI(t testing.TB, store *Store, name string, flags DBFlags) DBI {
+	t.Helper()
+	var dbi DBI
+	if err := store.Update(func(tx *Tx) error {
+		var err error
+		dbi, err = tx.OpenDBI(name, flags|DBCreate)
+		if err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return dbi
+}
+
+func TestStore_UpdateView(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+
+	if err := store.Update(func(tx *Tx) error {
+		k, v := StringConst("hello"), StringConst("world")
+		return tx.Put(dbi, &k, &v, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.View(func(tx *Tx) error {
+		k, v := StringConst("hello"), Val{}
+		if err := tx.Get(dbi, &k, &v); err != nil {
+			return err
+		}
+		if v.String() != "world" {
+			t.Fatalf("got %q", v.String())
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_ViewThread(t *testing.T) {
+	store := openTestStore(t, "", EnvSafeNoSync)
+	dbi := openTestDBI(t, store, "kv", 0)
+	if err := store.Update(func(tx *Tx) error {
+		k, v := StringConst("key"), StringConst("value")
+		return tx.Put(dbi, &k, &v, 0)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Busy goroutines make the scheduler move an unlocked goroutine to
+	// other threads, where the read transaction fails with
+	// ErrThreadMismatch.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	stop := make(chan struct{})
+	defer close(stop)
+	for i := 0; i < 8; i++ {
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	if err := store.View(func(tx *Tx) error {
+		for i := 0; i < 10000; i++ {
+			runtime.Gosched()
+			k, v := StringConst("key"), Val{}
+			if err := tx.Get(dbi, &k, &v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}